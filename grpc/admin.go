@@ -0,0 +1,338 @@
+package grpc
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
+	grpc_base "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const AUTHORIZATION_HEADER = "authorization"
+
+// adminServer implements SamAdmin.
+type adminServer struct {
+	UnimplementedSamAdminServer
+	am *auth.AuthManager
+}
+
+// adminAuthInterceptor rejects SamAdmin calls without the admin token.
+// Other services pass through.
+func adminAuthInterceptor(token string) grpc_base.UnaryServerInterceptor {
+	expected := sha256.Sum256([]byte(token))
+	prefix := "/" + SamAdmin_ServiceDesc.ServiceName + "/"
+	return func(ctx context.Context, req any, info *grpc_base.UnaryServerInfo, handler grpc_base.UnaryHandler) (any, error) {
+		if !strings.HasPrefix(info.FullMethod, prefix) {
+			return handler(ctx, req)
+		}
+		var got string
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			if v := md.Get(AUTHORIZATION_HEADER); len(v) > 0 {
+				got, _ = strings.CutPrefix(v[0], "Bearer ")
+			}
+		}
+		sum := sha256.Sum256([]byte(got))
+		if got == "" || subtle.ConstantTimeCompare(sum[:], expected[:]) != 1 {
+			slog.WarnContext(ctx, "Rejected admin call", "method", info.FullMethod)
+			return nil, status.Error(codes.Unauthenticated, "admin token required")
+		}
+		return handler(ctx, req)
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+func toSigningKey(k auth.SigningKeyInfo) *SigningKey {
+	return &SigningKey{
+		Kid:         k.Kid,
+		Algorithm:   k.Algorithm,
+		State:       k.State,
+		CreatedAt:   k.CreatedAt.Format(time.RFC3339),
+		ActivatesAt: k.ActivatesAt.Format(time.RFC3339),
+		RetiresAt:   formatOptionalTime(k.RetiresAt),
+		RevokedAt:   formatOptionalTime(k.RevokedAt),
+	}
+}
+
+func (s *adminServer) ListSigningKeys(ctx context.Context, data *Blank) (*SigningKeyList, error) {
+	keys, err := s.am.ListSigningKeys(ctx)
+	logOutcome(ctx, "ListSigningKeys", err)
+	if err != nil {
+		return nil, err
+	}
+	res := &SigningKeyList{Keys: make([]*SigningKey, 0, len(keys))}
+	for _, k := range keys {
+		res.Keys = append(res.Keys, toSigningKey(k))
+	}
+	return res, nil
+}
+
+func (s *adminServer) RotateSigningKey(ctx context.Context, data *RotateSigningKeyRequest) (*SigningKey, error) {
+	key, err := s.am.RotateSigningKey(ctx, data.Immediate)
+	logOutcome(ctx, "RotateSigningKey", err, "kid", key.Kid, "immediate", data.Immediate)
+	if err != nil {
+		return nil, err
+	}
+	return toSigningKey(key), nil
+}
+
+func (s *adminServer) RevokeSigningKey(ctx context.Context, data *RevokeSigningKeyRequest) (*Blank, error) {
+	err := s.am.RevokeSigningKey(ctx, data.Kid)
+	logOutcome(ctx, "RevokeSigningKey", err, "kid", data.Kid)
+	return &Blank{}, err
+}
+
+func toClient(c auth.OAuthClient, secret string) *Client {
+	return &Client{
+		ClientId:     c.Id,
+		Name:         c.Name,
+		RedirectUris: c.RedirectUris,
+		Public:       c.Public(),
+		CreatedAt:    c.CreatedAt.Format(time.RFC3339),
+		ClientSecret: secret,
+	}
+}
+
+func (s *adminServer) CreateClient(ctx context.Context, data *CreateClientRequest) (*Client, error) {
+	client, secret, err := s.am.CreateClient(ctx, data.Name, data.RedirectUris, data.Public)
+	logOutcome(ctx, "CreateClient", err, "client_id", client.Id, "name", data.Name)
+	if err != nil {
+		return nil, err
+	}
+	return toClient(client, secret), nil
+}
+
+func (s *adminServer) ListClients(ctx context.Context, data *Blank) (*ClientList, error) {
+	clients, err := s.am.ListClients(ctx)
+	logOutcome(ctx, "ListClients", err)
+	if err != nil {
+		return nil, err
+	}
+	res := &ClientList{Clients: make([]*Client, 0, len(clients))}
+	for _, c := range clients {
+		res.Clients = append(res.Clients, toClient(c, ""))
+	}
+	return res, nil
+}
+
+func (s *adminServer) DeleteClient(ctx context.Context, data *DeleteClientRequest) (*Blank, error) {
+	err := s.am.DeleteClient(ctx, data.ClientId)
+	logOutcome(ctx, "DeleteClient", err, "client_id", data.ClientId)
+	return &Blank{}, err
+}
+
+func toServiceAccount(sa auth.ServiceAccount, secret string) *ServiceAccount {
+	return &ServiceAccount{
+		ClientId:     sa.Id,
+		Name:         sa.Name,
+		Scopes:       sa.Scopes,
+		CreatedAt:    sa.CreatedAt.Format(time.RFC3339),
+		ClientSecret: secret,
+	}
+}
+
+func (s *adminServer) CreateServiceAccount(ctx context.Context, data *CreateServiceAccountRequest) (*ServiceAccount, error) {
+	sa, secret, err := s.am.CreateServiceAccount(ctx, data.Name, data.Scopes)
+	logOutcome(ctx, "CreateServiceAccount", err, "client_id", sa.Id, "name", data.Name)
+	if err != nil {
+		return nil, err
+	}
+	return toServiceAccount(sa, secret), nil
+}
+
+func (s *adminServer) ListServiceAccounts(ctx context.Context, data *Blank) (*ServiceAccountList, error) {
+	accounts, err := s.am.ListServiceAccounts(ctx)
+	logOutcome(ctx, "ListServiceAccounts", err)
+	if err != nil {
+		return nil, err
+	}
+	res := &ServiceAccountList{ServiceAccounts: make([]*ServiceAccount, 0, len(accounts))}
+	for _, sa := range accounts {
+		res.ServiceAccounts = append(res.ServiceAccounts, toServiceAccount(sa, ""))
+	}
+	return res, nil
+}
+
+func (s *adminServer) DeleteServiceAccount(ctx context.Context, data *DeleteServiceAccountRequest) (*Blank, error) {
+	err := s.am.DeleteServiceAccount(ctx, data.ClientId)
+	logOutcome(ctx, "DeleteServiceAccount", err, "client_id", data.ClientId)
+	return &Blank{}, err
+}
+
+func toRole(r auth.Role) *Role {
+	res := &Role{
+		Name:        r.Name,
+		Description: r.Description,
+		Permissions: make([]*Permission, 0, len(r.Permissions)),
+		CreatedAt:   r.CreatedAt.Format(time.RFC3339),
+	}
+	for _, p := range r.Permissions {
+		res.Permissions = append(res.Permissions, &Permission{Permission: p.Name, Resource: p.Resource})
+	}
+	return res
+}
+
+func toRoleList(roles []auth.Role) *RoleList {
+	res := &RoleList{Roles: make([]*Role, 0, len(roles))}
+	for _, r := range roles {
+		res.Roles = append(res.Roles, toRole(r))
+	}
+	return res
+}
+
+func fromPermission(p *Permission) auth.Permission {
+	return auth.Permission{Name: p.GetPermission(), Resource: p.GetResource()}
+}
+
+func (s *adminServer) CreateRole(ctx context.Context, data *CreateRoleRequest) (*Role, error) {
+	r, err := s.am.CreateRole(ctx, data.Name, data.Description)
+	logOutcome(ctx, "CreateRole", err, "role", data.Name)
+	if err != nil {
+		return nil, err
+	}
+	return toRole(r), nil
+}
+
+func (s *adminServer) ListRoles(ctx context.Context, data *Blank) (*RoleList, error) {
+	roles, err := s.am.ListRoles(ctx)
+	logOutcome(ctx, "ListRoles", err)
+	if err != nil {
+		return nil, err
+	}
+	return toRoleList(roles), nil
+}
+
+func (s *adminServer) DeleteRole(ctx context.Context, data *DeleteRoleRequest) (*Blank, error) {
+	err := s.am.DeleteRole(ctx, data.Name)
+	logOutcome(ctx, "DeleteRole", err, "role", data.Name)
+	return &Blank{}, err
+}
+
+func (s *adminServer) AddRolePermission(ctx context.Context, data *RolePermissionRequest) (*Blank, error) {
+	p := fromPermission(data.Permission)
+	err := s.am.AddRolePermission(ctx, data.Role, p)
+	logOutcome(ctx, "AddRolePermission", err, "role", data.Role, "permission", p.Name, "resource", p.Resource)
+	return &Blank{}, err
+}
+
+func (s *adminServer) RemoveRolePermission(ctx context.Context, data *RolePermissionRequest) (*Blank, error) {
+	p := fromPermission(data.Permission)
+	err := s.am.RemoveRolePermission(ctx, data.Role, p)
+	logOutcome(ctx, "RemoveRolePermission", err, "role", data.Role, "permission", p.Name, "resource", p.Resource)
+	return &Blank{}, err
+}
+
+func (s *adminServer) GrantRole(ctx context.Context, data *UserRoleRequest) (*Blank, error) {
+	err := s.am.GrantRole(ctx, data.TenantId, data.Username, data.Role)
+	logOutcome(ctx, "GrantRole", err, "tenant_id", data.TenantId, "username", data.Username, "role", data.Role)
+	return &Blank{}, err
+}
+
+func (s *adminServer) RevokeRole(ctx context.Context, data *UserRoleRequest) (*Blank, error) {
+	err := s.am.RevokeRole(ctx, data.TenantId, data.Username, data.Role)
+	logOutcome(ctx, "RevokeRole", err, "tenant_id", data.TenantId, "username", data.Username, "role", data.Role)
+	return &Blank{}, err
+}
+
+func (s *adminServer) ListUserRoles(ctx context.Context, data *ListUserRolesRequest) (*RoleList, error) {
+	roles, err := s.am.GetUserRoles(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "ListUserRoles", err, "tenant_id", data.TenantId, "username", data.Username)
+	if err != nil {
+		return nil, err
+	}
+	return toRoleList(roles), nil
+}
+
+func toTenant(t auth.Tenant) *Tenant {
+	res := &Tenant{
+		Id:        t.Id,
+		Name:      t.Name,
+		CreatedAt: t.CreatedAt.Format(time.RFC3339),
+	}
+	if t.DisabledAt != nil {
+		res.DisabledAt = t.DisabledAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+func (s *adminServer) CreateTenant(ctx context.Context, data *CreateTenantRequest) (*Tenant, error) {
+	t, err := s.am.CreateTenant(ctx, data.Id, data.Name)
+	logOutcome(ctx, "CreateTenant", err, "tenant_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toTenant(t), nil
+}
+
+func (s *adminServer) ListTenants(ctx context.Context, data *Blank) (*TenantList, error) {
+	tenants, err := s.am.ListTenants(ctx)
+	logOutcome(ctx, "ListTenants", err)
+	if err != nil {
+		return nil, err
+	}
+	res := &TenantList{Tenants: make([]*Tenant, 0, len(tenants))}
+	for _, t := range tenants {
+		res.Tenants = append(res.Tenants, toTenant(t))
+	}
+	return res, nil
+}
+
+// DisableTenant expires the sessions of the tenant and blocks its users.
+func (s *adminServer) DisableTenant(ctx context.Context, data *DisableTenantRequest) (*Blank, error) {
+	err := s.am.DisableTenant(ctx, data.Id)
+	logOutcome(ctx, "DisableTenant", err, "tenant_id", data.Id)
+	return &Blank{}, err
+}
+
+func (s *adminServer) ListUsers(ctx context.Context, data *ListUsersRequest) (*UserList, error) {
+	users, next, err := s.am.ListUsers(ctx, data.TenantId, data.Prefix, int(data.PageSize), data.PageToken)
+	logOutcome(ctx, "ListUsers", err, "tenant_id", data.TenantId, "prefix", data.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	res := &UserList{Users: make([]*User, 0, len(users)), NextPageToken: next}
+	for _, u := range users {
+		res.Users = append(res.Users, toUser(u))
+	}
+	return res, nil
+}
+
+func (s *adminServer) GetUser(ctx context.Context, data *UserRequest) (*User, error) {
+	u, err := s.am.GetUser(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "GetUser", err, "tenant_id", data.TenantId, "username", data.Username)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(u), nil
+}
+
+// DisableUser revokes the sessions of the user and keeps them from signing in.
+func (s *adminServer) DisableUser(ctx context.Context, data *UserRequest) (*Blank, error) {
+	err := s.am.DisableUser(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "DisableUser", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
+
+func (s *adminServer) EnableUser(ctx context.Context, data *UserRequest) (*Blank, error) {
+	err := s.am.EnableUser(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "EnableUser", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
+
+func (s *adminServer) DeleteUser(ctx context.Context, data *UserRequest) (*Blank, error) {
+	err := s.am.DeleteUser(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "DeleteUser", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
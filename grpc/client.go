@@ -0,0 +1,36 @@
+package grpc
+
+import (
+	"context"
+
+	"github.com/JustDean/sam/pkg/auth"
+	"github.com/JustDean/sam/pkg/utils"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// clientInfo describes the caller of the current request. Forwarded
+// metadata set by a proxy is only honoured when trustProxy is set.
+func (s *Server) clientInfo(ctx context.Context, deviceName string) auth.ClientInfo {
+	c := auth.ClientInfo{DeviceName: deviceName}
+	md, _ := metadata.FromIncomingContext(ctx)
+	if ua := md.Get("user-agent"); len(ua) > 0 {
+		c.UserAgent = ua[0]
+	}
+	if s.trustProxy {
+		c.Ip = utils.ForwardedIp(first(md.Get("x-forwarded-for")), first(md.Get("x-real-ip")))
+	}
+	if c.Ip == "" {
+		if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+			c.Ip = utils.HostIp(p.Addr.String())
+		}
+	}
+	return c
+}
+
+func first(values []string) string {
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
@@ -5,6 +5,11 @@ import "fmt"
 type Config struct {
 	Host string
 	Port string
+	// TrustProxy makes client addresses be read from x-forwarded-for
+	// and x-real-ip metadata. Enable only behind a trusted proxy.
+	TrustProxy bool
+	// AdminToken enables the SamAdmin service for callers presenting it.
+	AdminToken string
 }
 
 func (c *Config) url() string {
@@ -0,0 +1,168 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpc_base "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/protoadapt"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// RETRY_AFTER_HEADER carries the whole seconds to wait before retrying
+// a rate limited call.
+const RETRY_AFTER_HEADER = "retry-after"
+
+// ERROR_DOMAIN is set on every google.rpc.ErrorInfo returned by the service.
+const ERROR_DOMAIN = "sam"
+
+// Stable reasons carried by google.rpc.ErrorInfo. Clients may switch on them.
+const (
+	REASON_INVALID_ARGUMENT              = "INVALID_ARGUMENT"
+	REASON_USER_EXISTS                   = "USER_EXISTS"
+	REASON_INVALID_CREDENTIALS           = "INVALID_CREDENTIALS"
+	REASON_SESSION_NOT_FOUND             = "SESSION_NOT_FOUND"
+	REASON_SESSION_EXPIRED               = "SESSION_EXPIRED"
+	REASON_RATE_LIMITED                  = "RATE_LIMITED"
+	REASON_MFA_NOT_ENABLED               = "MFA_NOT_ENABLED"
+	REASON_MFA_ALREADY_ENABLED           = "MFA_ALREADY_ENABLED"
+	REASON_INVALID_MFA_CODE              = "INVALID_MFA_CODE"
+	REASON_MFA_CHALLENGE_NOT_FOUND       = "MFA_CHALLENGE_NOT_FOUND"
+	REASON_INVALID_RESET_TOKEN           = "INVALID_RESET_TOKEN"
+	REASON_ACCESS_TOKENS_DISABLED        = "ACCESS_TOKENS_DISABLED"
+	REASON_SIGNING_KEY_NOT_FOUND         = "SIGNING_KEY_NOT_FOUND"
+	REASON_CLIENT_NOT_FOUND              = "CLIENT_NOT_FOUND"
+	REASON_SERVICE_ACCOUNT_NOT_FOUND     = "SERVICE_ACCOUNT_NOT_FOUND"
+	REASON_USER_NOT_FOUND                = "USER_NOT_FOUND"
+	REASON_ROLE_NOT_FOUND                = "ROLE_NOT_FOUND"
+	REASON_ROLE_EXISTS                   = "ROLE_EXISTS"
+	REASON_PERMISSION_NOT_FOUND          = "PERMISSION_NOT_FOUND"
+	REASON_ROLE_NOT_GRANTED              = "ROLE_NOT_GRANTED"
+	REASON_TENANT_NOT_FOUND              = "TENANT_NOT_FOUND"
+	REASON_TENANT_EXISTS                 = "TENANT_EXISTS"
+	REASON_TENANT_DISABLED               = "TENANT_DISABLED"
+	REASON_USER_DISABLED                 = "USER_DISABLED"
+	REASON_EMAIL_VERIFICATION_DISABLED   = "EMAIL_VERIFICATION_DISABLED"
+	REASON_INVALID_VERIFICATION_TOKEN    = "INVALID_VERIFICATION_TOKEN"
+	REASON_EMAIL_NOT_VERIFIED            = "EMAIL_NOT_VERIFIED"
+	REASON_PASSWORDLESS_DISABLED         = "PASSWORDLESS_DISABLED"
+	REASON_INVALID_PASSWORDLESS_CODE     = "INVALID_PASSWORDLESS_CODE"
+	REASON_WEBAUTHN_DISABLED             = "WEBAUTHN_DISABLED"
+	REASON_WEBAUTHN_CHALLENGE_NOT_FOUND  = "WEBAUTHN_CHALLENGE_NOT_FOUND"
+	REASON_INVALID_WEBAUTHN_RESPONSE     = "INVALID_WEBAUTHN_RESPONSE"
+	REASON_WEBAUTHN_CREDENTIAL_NOT_FOUND = "WEBAUTHN_CREDENTIAL_NOT_FOUND"
+	REASON_WEBAUTHN_CREDENTIAL_EXISTS    = "WEBAUTHN_CREDENTIAL_EXISTS"
+	REASON_BACKEND_UNAVAILABLE           = "BACKEND_UNAVAILABLE"
+	REASON_DEADLINE_EXCEEDED             = "DEADLINE_EXCEEDED"
+	REASON_CANCELED                      = "CANCELED"
+	REASON_INTERNAL                      = "INTERNAL"
+)
+
+type errorMapping struct {
+	err    error
+	code   codes.Code
+	reason string
+}
+
+// errorMappings is checked in order; the first match wins.
+var errorMappings = []errorMapping{
+	{auth.ErrInvalidArgument, codes.InvalidArgument, REASON_INVALID_ARGUMENT},
+	{auth.ErrUserExists, codes.AlreadyExists, REASON_USER_EXISTS},
+	{auth.ErrInvalidCredentials, codes.Unauthenticated, REASON_INVALID_CREDENTIALS},
+	{auth.ErrSessionNotFound, codes.NotFound, REASON_SESSION_NOT_FOUND},
+	{auth.ErrSessionExpired, codes.Unauthenticated, REASON_SESSION_EXPIRED},
+	{auth.ErrMfaNotEnabled, codes.FailedPrecondition, REASON_MFA_NOT_ENABLED},
+	{auth.ErrMfaAlreadyEnabled, codes.FailedPrecondition, REASON_MFA_ALREADY_ENABLED},
+	{auth.ErrInvalidMfaCode, codes.Unauthenticated, REASON_INVALID_MFA_CODE},
+	{auth.ErrMfaChallengeNotFound, codes.Unauthenticated, REASON_MFA_CHALLENGE_NOT_FOUND},
+	{auth.ErrInvalidResetToken, codes.Unauthenticated, REASON_INVALID_RESET_TOKEN},
+	{auth.ErrAccessTokensDisabled, codes.FailedPrecondition, REASON_ACCESS_TOKENS_DISABLED},
+	{auth.ErrSigningKeyNotFound, codes.NotFound, REASON_SIGNING_KEY_NOT_FOUND},
+	{auth.ErrClientNotFound, codes.NotFound, REASON_CLIENT_NOT_FOUND},
+	{auth.ErrServiceAccountNotFound, codes.NotFound, REASON_SERVICE_ACCOUNT_NOT_FOUND},
+	{auth.ErrUserNotFound, codes.NotFound, REASON_USER_NOT_FOUND},
+	{auth.ErrRoleNotFound, codes.NotFound, REASON_ROLE_NOT_FOUND},
+	{auth.ErrRoleExists, codes.AlreadyExists, REASON_ROLE_EXISTS},
+	{auth.ErrPermissionNotFound, codes.NotFound, REASON_PERMISSION_NOT_FOUND},
+	{auth.ErrRoleNotGranted, codes.NotFound, REASON_ROLE_NOT_GRANTED},
+	{auth.ErrTenantNotFound, codes.NotFound, REASON_TENANT_NOT_FOUND},
+	{auth.ErrTenantExists, codes.AlreadyExists, REASON_TENANT_EXISTS},
+	{auth.ErrTenantDisabled, codes.FailedPrecondition, REASON_TENANT_DISABLED},
+	{auth.ErrUserDisabled, codes.FailedPrecondition, REASON_USER_DISABLED},
+	{auth.ErrEmailVerificationDisabled, codes.FailedPrecondition, REASON_EMAIL_VERIFICATION_DISABLED},
+	{auth.ErrInvalidVerificationToken, codes.Unauthenticated, REASON_INVALID_VERIFICATION_TOKEN},
+	{auth.ErrEmailNotVerified, codes.FailedPrecondition, REASON_EMAIL_NOT_VERIFIED},
+	{auth.ErrPasswordlessDisabled, codes.FailedPrecondition, REASON_PASSWORDLESS_DISABLED},
+	{auth.ErrInvalidPasswordlessCode, codes.Unauthenticated, REASON_INVALID_PASSWORDLESS_CODE},
+	{auth.ErrWebauthnDisabled, codes.FailedPrecondition, REASON_WEBAUTHN_DISABLED},
+	{auth.ErrWebauthnChallengeNotFound, codes.Unauthenticated, REASON_WEBAUTHN_CHALLENGE_NOT_FOUND},
+	{auth.ErrInvalidWebauthnResponse, codes.Unauthenticated, REASON_INVALID_WEBAUTHN_RESPONSE},
+	{auth.ErrWebauthnCredentialNotFound, codes.NotFound, REASON_WEBAUTHN_CREDENTIAL_NOT_FOUND},
+	{auth.ErrWebauthnCredentialExists, codes.AlreadyExists, REASON_WEBAUTHN_CREDENTIAL_EXISTS},
+	{auth.ErrRateLimited, codes.ResourceExhausted, REASON_RATE_LIMITED},
+	{auth.ErrBackendUnavailable, codes.Unavailable, REASON_BACKEND_UNAVAILABLE},
+	{context.DeadlineExceeded, codes.DeadlineExceeded, REASON_DEADLINE_EXCEEDED},
+	{context.Canceled, codes.Canceled, REASON_CANCELED},
+}
+
+// toStatusError translates a domain error into a gRPC status carrying
+// a google.rpc.ErrorInfo, plus a google.rpc.RetryInfo for rate limited
+// calls. Unknown errors become Internal without exposing their message.
+func toStatusError(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	code, reason, message := codes.Internal, REASON_INTERNAL, "internal error"
+	for _, m := range errorMappings {
+		if errors.Is(err, m.err) {
+			code, reason, message = m.code, m.reason, m.err.Error()
+			if m.code == codes.InvalidArgument {
+				message = err.Error()
+			}
+			break
+		}
+	}
+	details := []protoadapt.MessageV1{&errdetails.ErrorInfo{
+		Reason: reason,
+		Domain: ERROR_DOMAIN,
+	}}
+	if retryAfter, ok := retryAfter(err); ok {
+		details = append(details, &errdetails.RetryInfo{RetryDelay: durationpb.New(retryAfter)})
+	}
+	st, detailsErr := status.New(code, message).WithDetails(details...)
+	if detailsErr != nil {
+		return status.Error(code, message)
+	}
+	return st.Err()
+}
+
+func retryAfter(err error) (time.Duration, bool) {
+	var rl *auth.RateLimitedError
+	if !errors.As(err, &rl) {
+		return 0, false
+	}
+	return rl.RetryAfter, true
+}
+
+// retryAfterSeconds rounds up so that clients never retry too early.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+func errorInterceptor(ctx context.Context, req any, info *grpc_base.UnaryServerInfo, handler grpc_base.UnaryHandler) (any, error) {
+	res, err := handler(ctx, req)
+	if d, ok := retryAfter(err); ok {
+		grpc_base.SetTrailer(ctx, metadata.Pairs(RETRY_AFTER_HEADER, retryAfterSeconds(d)))
+	}
+	return res, toStatusError(err)
+}
@@ -2,42 +2,70 @@ package grpc
 
 import (
 	context "context"
-	"log"
+	"log/slog"
 	"net"
 
 	"github.com/JustDean/sam/pkg/auth"
+	"github.com/JustDean/sam/pkg/health"
 	grpc_base "google.golang.org/grpc"
+	grpc_health "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func SetServer(c Config, am *auth.AuthManager) (*Server, error) {
+func SetServer(c Config, am *auth.AuthManager, checker *health.Checker) (*Server, error) {
 	lis, err := net.Listen("tcp", c.url())
 	if err != nil {
 		return nil, err
 	}
-	s := grpc_base.NewServer()
+	interceptors := []grpc_base.UnaryServerInterceptor{
+		requestIdInterceptor,
+		metricsInterceptor,
+		errorInterceptor,
+	}
+	if c.AdminToken != "" {
+		interceptors = append(interceptors, adminAuthInterceptor(c.AdminToken))
+	}
+	s := grpc_base.NewServer(grpc_base.ChainUnaryInterceptor(interceptors...))
 	server := &Server{
-		l:  lis,
-		s:  s,
-		am: am,
+		l:          lis,
+		s:          s,
+		am:         am,
+		health:     grpc_health.NewServer(),
+		trustProxy: c.TrustProxy,
 	}
 	RegisterSamServer(s, server)
+	if c.AdminToken != "" {
+		RegisterSamAdminServer(s, &adminServer{am: am})
+	}
+	healthpb.RegisterHealthServer(s, server.health)
+	checker.OnChange(func(ready bool) {
+		st := healthpb.HealthCheckResponse_NOT_SERVING
+		if ready {
+			st = healthpb.HealthCheckResponse_SERVING
+		}
+		server.health.SetServingStatus("", st)
+		server.health.SetServingStatus(Sam_ServiceDesc.ServiceName, st)
+	})
 	return server, nil
 }
 
 type Server struct {
 	UnimplementedSamServer
-	l  net.Listener
-	s  *grpc_base.Server
-	am *auth.AuthManager
+	l          net.Listener
+	s          *grpc_base.Server
+	am         *auth.AuthManager
+	health     *grpc_health.Server
+	trustProxy bool
 }
 
 func (s *Server) Run(ctx context.Context) {
-	log.Printf("Starting gRPC Server on %s", s.l.Addr())
+	slog.Info("Starting gRPC Server", "addr", s.l.Addr().String())
 	go func() {
 		s.s.Serve(s.l)
 	}()
 	<-ctx.Done()
-	log.Println("Stopping gRPC Server")
+	slog.Info("Stopping gRPC Server")
+	s.health.Shutdown()
 	s.s.GracefulStop()
-	log.Println("gRPC Server is stopped")
+	slog.Info("gRPC Server is stopped")
 }
@@ -2,61 +2,375 @@ package grpc
 
 import (
 	"context"
-	"log"
 	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
 )
 
+func toSession(s auth.Session) *Session {
+	return &Session{
+		Id:           s.Id,
+		Handle:       s.Handle(),
+		TenantId:     s.TenantId,
+		ValidThrough: s.ValidThrough.Format(time.RFC3339),
+		Username:     s.Username,
+		CreatedAt:    s.CreatedAt.Format(time.RFC3339),
+		ClientIp:     s.Ip,
+		UserAgent:    s.UserAgent,
+		DeviceName:   s.DeviceName,
+		LastSeenAt:   s.LastSeenAt.Format(time.RFC3339),
+		ExpiresAt:    s.ExpiresAt.Format(time.RFC3339),
+		Restricted:   s.Restricted,
+	}
+}
+
+// toUser converts the profile of a user, leaving out the password hash.
+func toUser(u auth.User) *User {
+	res := &User{
+		TenantId:      u.TenantId,
+		Username:      u.Username,
+		Email:         u.Email,
+		DisplayName:   u.DisplayName,
+		Attributes:    u.Attributes,
+		CreatedAt:     u.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     u.UpdatedAt.Format(time.RFC3339),
+		EmailVerified: u.EmailVerified(),
+	}
+	if u.DisabledAt != nil {
+		res.DisabledAt = u.DisabledAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+func toAccessToken(t auth.AccessToken) *AccessToken {
+	return &AccessToken{
+		Token:     t.Token,
+		ExpiresAt: t.ExpiresAt.Format(time.RFC3339),
+	}
+}
+
+// toOpenedSession converts a session just opened, adding an access token
+// if they are enabled.
+func (s *Server) toOpenedSession(ctx context.Context, session auth.Session) (*Session, error) {
+	res := toSession(session)
+	token, err := s.am.IssueAccessToken(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	if token != nil {
+		res.AccessToken = toAccessToken(*token)
+	}
+	return res, nil
+}
+
 func (s *Server) Signup(ctx context.Context, data *CredentialsRequest) (*User, error) {
-	user, err := s.am.CreateUser(ctx, data.Username, data.Password)
+	user, err := s.am.CreateUser(ctx, data.TenantId, data.Username, data.Password, data.Email)
+	logOutcome(ctx, "Signup", err, "tenant_id", data.TenantId, "username", data.Username)
 	if err != nil {
-		log.Printf("Error Signup - %v: %v", data, err)
 		return nil, err
 	}
-	log.Printf("Success Signup - %v", user)
-	return &User{Username: user.Username}, nil
+	return toUser(user), nil
 }
 
-func (s *Server) Login(ctx context.Context, data *CredentialsRequest) (*Session, error) {
-	session, err := s.am.LoginUser(ctx, data.Username, data.Password)
+func (s *Server) Login(ctx context.Context, data *CredentialsRequest) (*LoginResponse, error) {
+	session, challenge, err := s.am.LoginUser(ctx, data.TenantId, data.Username, data.Password, s.clientInfo(ctx, data.DeviceName))
+	logOutcome(ctx, "Login", err, "tenant_id", data.TenantId, "username", data.Username, "session_id", session.Id, "mfa_required", challenge != nil)
 	if err != nil {
-		log.Printf("Error Login - %v: %v", data, err)
 		return nil, err
 	}
-	log.Printf("Success Login - for user %s", session.Username)
-	return &Session{Id: session.Id, ValidThrough: session.ValidThrough.Format(time.RFC3339), Username: session.Username}, nil
+	return s.toLoginResponse(ctx, session, challenge)
+}
+
+// toLoginResponse converts the outcome of a first factor, either a session
+// or an MFA challenge.
+func (s *Server) toLoginResponse(ctx context.Context, session auth.Session, challenge *auth.MfaChallenge) (*LoginResponse, error) {
+	if challenge != nil {
+		return &LoginResponse{Result: &LoginResponse_MfaChallenge{MfaChallenge: &MfaChallenge{
+			Token:     challenge.Token,
+			ExpiresAt: challenge.ExpiresAt.Format(time.RFC3339),
+		}}}, nil
+	}
+	res, err := s.toOpenedSession(ctx, session)
+	if err != nil {
+		return nil, err
+	}
+	return &LoginResponse{Result: &LoginResponse_Session{Session: res}}, nil
 }
 
 func (s *Server) SignupAndLogin(ctx context.Context, data *CredentialsRequest) (*Session, error) {
-	s.Signup(ctx, data)
-	return s.Login(ctx, data)
+	session, err := s.am.SignupAndLogin(ctx, data.TenantId, data.Username, data.Password, data.Email, s.clientInfo(ctx, data.DeviceName))
+	logOutcome(ctx, "SignupAndLogin", err, "tenant_id", data.TenantId, "username", data.Username, "session_id", session.Id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toOpenedSession(ctx, session)
 }
 
 func (s *Server) Logout(ctx context.Context, data *SessionId) (*Blank, error) {
 	err := s.am.InvalidateSession(ctx, data.Id)
-	if err != nil {
-		log.Printf("Error Logout - %v: %v", data, err)
-	} else {
-		log.Printf("Success Logout - %v", data)
-	}
+	logOutcome(ctx, "Logout", err, "session_id", data.Id)
 	return &Blank{}, err
 }
 
+// Authenticate accepts session ids as well as service tokens.
 func (s *Server) Authenticate(ctx context.Context, data *SessionId) (*User, error) {
-	user, err := s.am.GetUserBySessionId(ctx, data.Id)
+	p, err := s.am.Authenticate(ctx, data.Id)
+	if p.Kind == auth.PRINCIPAL_SERVICE {
+		logOutcome(ctx, "Authenticate", err, "service_id", p.Service.ClientId)
+		return &User{
+			Principal: p.Kind,
+			Service: &ServicePrincipal{
+				ClientId:  p.Service.ClientId,
+				Name:      p.Service.Name,
+				Scopes:    p.Service.Scopes,
+				IssuedAt:  p.Service.IssuedAt.Format(time.RFC3339),
+				ExpiresAt: p.Service.ExpiresAt.Format(time.RFC3339),
+			},
+		}, nil
+	}
+	logOutcome(ctx, "Authenticate", err, "tenant_id", p.User.TenantId, "username", p.User.Username, "session_id", p.Session.Id)
 	if err != nil {
-		log.Printf("Error Authenticate - %v: %v", data, err)
 		return &User{}, err
 	}
-	log.Printf("Success Authenticate - %v", user)
-	return &User{Username: user.Username}, nil
+	res := toUser(p.User)
+	res.Session = toSession(p.Session)
+	res.Principal = p.Kind
+	return res, nil
 }
 
 func (s *Server) ChangePassword(ctx context.Context, data *ChangePasswordRequest) (*Blank, error) {
-	user, err := s.am.ChangePassword(ctx, data.Username, data.CurrentPassword, data.NewPassword)
+	_, err := s.am.ChangePassword(ctx, data.TenantId, data.Username, data.CurrentPassword, data.NewPassword)
+	logOutcome(ctx, "ChangePassword", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
+
+// ListSessions leaves out session ids, which are credentials, and marks
+// the session of the caller.
+func (s *Server) ListSessions(ctx context.Context, data *SessionId) (*SessionList, error) {
+	sessions, err := s.am.ListSessions(ctx, data.Id)
+	logOutcome(ctx, "ListSessions", err, "session_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	res := &SessionList{Sessions: make([]*Session, 0, len(sessions))}
+	for _, session := range sessions {
+		listed := toSession(session)
+		listed.Id = ""
+		listed.Current = session.Id == data.Id
+		res.Sessions = append(res.Sessions, listed)
+	}
+	return res, nil
+}
+
+func (s *Server) RevokeAllSessions(ctx context.Context, data *RevokeAllSessionsRequest) (*Blank, error) {
+	err := s.am.RevokeAllSessions(ctx, data.SessionId, data.KeepCurrent)
+	logOutcome(ctx, "RevokeAllSessions", err, "session_id", data.SessionId, "keep_current", data.KeepCurrent)
+	return &Blank{}, err
+}
+
+func (s *Server) VerifyMfa(ctx context.Context, data *VerifyMfaRequest) (*Session, error) {
+	session, err := s.am.VerifyMfa(ctx, data.Challenge, data.Code)
+	logOutcome(ctx, "VerifyMfa", err, "tenant_id", session.TenantId, "username", session.Username, "session_id", session.Id)
+	if err != nil {
+		return nil, err
+	}
+	return s.toOpenedSession(ctx, session)
+}
+
+func (s *Server) EnrollMfa(ctx context.Context, data *SessionId) (*MfaEnrollment, error) {
+	enrollment, err := s.am.EnrollMfa(ctx, data.Id)
+	logOutcome(ctx, "EnrollMfa", err, "session_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	return &MfaEnrollment{Secret: enrollment.Secret, Uri: enrollment.Uri}, nil
+}
+
+func (s *Server) ConfirmMfa(ctx context.Context, data *MfaCodeRequest) (*RecoveryCodes, error) {
+	codes, err := s.am.ConfirmMfa(ctx, data.SessionId, data.Code)
+	logOutcome(ctx, "ConfirmMfa", err, "session_id", data.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+func (s *Server) DisableMfa(ctx context.Context, data *MfaCodeRequest) (*Blank, error) {
+	err := s.am.DisableMfa(ctx, data.SessionId, data.Code)
+	logOutcome(ctx, "DisableMfa", err, "session_id", data.SessionId)
+	return &Blank{}, err
+}
+
+func (s *Server) RegenerateRecoveryCodes(ctx context.Context, data *MfaCodeRequest) (*RecoveryCodes, error) {
+	codes, err := s.am.RegenerateRecoveryCodes(ctx, data.SessionId, data.Code)
+	logOutcome(ctx, "RegenerateRecoveryCodes", err, "session_id", data.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return &RecoveryCodes{Codes: codes}, nil
+}
+
+func (s *Server) RequestPasswordReset(ctx context.Context, data *PasswordResetRequest) (*Blank, error) {
+	err := s.am.RequestPasswordReset(ctx, data.TenantId, data.Username, s.clientInfo(ctx, ""))
+	logOutcome(ctx, "RequestPasswordReset", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
+
+func (s *Server) ResetPassword(ctx context.Context, data *ResetPasswordRequest) (*Blank, error) {
+	err := s.am.ResetPassword(ctx, data.Token, data.NewPassword)
+	logOutcome(ctx, "ResetPassword", err)
+	return &Blank{}, err
+}
+
+func (s *Server) RefreshAccessToken(ctx context.Context, data *SessionId) (*AccessToken, error) {
+	token, err := s.am.RefreshAccessToken(ctx, data.Id)
+	logOutcome(ctx, "RefreshAccessToken", err, "session_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toAccessToken(token), nil
+}
+
+// Authorize answers a denial with allowed set to false rather than an error.
+func (s *Server) Authorize(ctx context.Context, data *AuthorizeRequest) (*AuthorizeResponse, error) {
+	res, err := s.am.Authorize(ctx, data.SessionId, data.Permission, data.Resource)
+	logOutcome(ctx, "Authorize", err, "permission", data.Permission, "resource", data.Resource, "allowed", res.Allowed, "role", res.Role)
+	if err != nil {
+		return nil, err
+	}
+	return &AuthorizeResponse{Allowed: res.Allowed, Role: res.Role}, nil
+}
+
+func (s *Server) GetProfile(ctx context.Context, data *SessionId) (*User, error) {
+	user, err := s.am.GetProfile(ctx, data.Id)
+	logOutcome(ctx, "GetProfile", err, "session_id", data.Id)
 	if err != nil {
-		log.Printf("Error ChangePassword - %s", data.Username)
-	} else {
-		log.Printf("Success ChangePassword - for user %s", user.Username)
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *Server) UpdateProfile(ctx context.Context, data *UpdateProfileRequest) (*User, error) {
+	user, err := s.am.UpdateProfile(ctx, data.SessionId, auth.ProfileUpdate{
+		Email:       data.Email,
+		DisplayName: data.DisplayName,
+		Attributes:  data.Attributes,
+	})
+	logOutcome(ctx, "UpdateProfile", err, "session_id", data.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *Server) RequestEmailVerification(ctx context.Context, data *EmailVerificationRequest) (*Blank, error) {
+	err := s.am.RequestEmailVerification(ctx, data.TenantId, data.Username, s.clientInfo(ctx, ""))
+	logOutcome(ctx, "RequestEmailVerification", err, "tenant_id", data.TenantId, "username", data.Username)
+	return &Blank{}, err
+}
+
+func (s *Server) VerifyEmail(ctx context.Context, data *VerifyEmailRequest) (*User, error) {
+	user, err := s.am.VerifyEmail(ctx, data.Token)
+	logOutcome(ctx, "VerifyEmail", err, "tenant_id", user.TenantId, "username", user.Username)
+	if err != nil {
+		return nil, err
+	}
+	return toUser(user), nil
+}
+
+func (s *Server) StartPasswordlessLogin(ctx context.Context, data *StartPasswordlessLoginRequest) (*PasswordlessChallenge, error) {
+	c, err := s.am.StartPasswordlessLogin(ctx, data.TenantId, data.Username, data.Channel, s.clientInfo(ctx, ""))
+	logOutcome(ctx, "StartPasswordlessLogin", err, "tenant_id", data.TenantId, "username", data.Username, "channel", data.Channel)
+	if err != nil {
+		return nil, err
+	}
+	return &PasswordlessChallenge{Token: c.Token, ExpiresAt: c.ExpiresAt.Format(time.RFC3339)}, nil
+}
+
+func (s *Server) CompletePasswordlessLogin(ctx context.Context, data *CompletePasswordlessLoginRequest) (*LoginResponse, error) {
+	session, challenge, err := s.am.CompletePasswordlessLogin(ctx, data.Token, data.Code, s.clientInfo(ctx, data.DeviceName))
+	logOutcome(ctx, "CompletePasswordlessLogin", err, "tenant_id", session.TenantId, "username", session.Username, "session_id", session.Id, "mfa_required", challenge != nil)
+	if err != nil {
+		return nil, err
 	}
+	return s.toLoginResponse(ctx, session, challenge)
+}
+
+func toWebauthnChallenge(c auth.WebauthnChallenge) *WebauthnChallenge {
+	return &WebauthnChallenge{OptionsJson: string(c.Options), ExpiresAt: c.ExpiresAt.Format(time.RFC3339)}
+}
+
+func toWebauthnCredential(c auth.WebauthnCredential) *WebauthnCredential {
+	res := &WebauthnCredential{
+		Id:                c.Id,
+		Name:              c.Name,
+		AttestationFormat: c.AttestationFormat,
+		SignCount:         c.SignCount,
+		CreatedAt:         c.CreatedAt.Format(time.RFC3339),
+	}
+	if c.LastUsedAt != nil {
+		res.LastUsedAt = c.LastUsedAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+func (s *Server) BeginWebauthnRegistration(ctx context.Context, data *SessionId) (*WebauthnChallenge, error) {
+	c, err := s.am.BeginWebauthnRegistration(ctx, data.Id)
+	logOutcome(ctx, "BeginWebauthnRegistration", err, "session_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	return toWebauthnChallenge(c), nil
+}
+
+func (s *Server) FinishWebauthnRegistration(ctx context.Context, data *FinishWebauthnRegistrationRequest) (*WebauthnCredential, error) {
+	c, err := s.am.FinishWebauthnRegistration(ctx, data.SessionId, data.Name, data.ClientDataJson, data.AttestationObject)
+	logOutcome(ctx, "FinishWebauthnRegistration", err, "session_id", data.SessionId)
+	if err != nil {
+		return nil, err
+	}
+	return toWebauthnCredential(c), nil
+}
+
+func (s *Server) BeginWebauthnLogin(ctx context.Context, data *BeginWebauthnLoginRequest) (*WebauthnChallenge, error) {
+	c, err := s.am.BeginWebauthnLogin(ctx, data.TenantId, data.Username)
+	logOutcome(ctx, "BeginWebauthnLogin", err, "tenant_id", data.TenantId, "username", data.Username)
+	if err != nil {
+		return nil, err
+	}
+	return toWebauthnChallenge(c), nil
+}
+
+func (s *Server) FinishWebauthnLogin(ctx context.Context, data *FinishWebauthnLoginRequest) (*LoginResponse, error) {
+	session, challenge, err := s.am.FinishWebauthnLogin(ctx, auth.WebauthnAssertion{
+		CredentialId:      data.CredentialId,
+		ClientDataJson:    data.ClientDataJson,
+		AuthenticatorData: data.AuthenticatorData,
+		Signature:         data.Signature,
+		UserHandle:        data.UserHandle,
+	}, s.clientInfo(ctx, data.DeviceName))
+	logOutcome(ctx, "FinishWebauthnLogin", err, "tenant_id", session.TenantId, "username", session.Username, "session_id", session.Id, "mfa_required", challenge != nil)
+	if err != nil {
+		return nil, err
+	}
+	return s.toLoginResponse(ctx, session, challenge)
+}
+
+func (s *Server) ListWebauthnCredentials(ctx context.Context, data *SessionId) (*WebauthnCredentialList, error) {
+	credentials, err := s.am.ListWebauthnCredentials(ctx, data.Id)
+	logOutcome(ctx, "ListWebauthnCredentials", err, "session_id", data.Id)
+	if err != nil {
+		return nil, err
+	}
+	res := &WebauthnCredentialList{Credentials: make([]*WebauthnCredential, 0, len(credentials))}
+	for _, c := range credentials {
+		res.Credentials = append(res.Credentials, toWebauthnCredential(c))
+	}
+	return res, nil
+}
+
+func (s *Server) DeleteWebauthnCredential(ctx context.Context, data *DeleteWebauthnCredentialRequest) (*Blank, error) {
+	err := s.am.DeleteWebauthnCredential(ctx, data.SessionId, data.Id)
+	logOutcome(ctx, "DeleteWebauthnCredential", err, "session_id", data.SessionId)
 	return &Blank{}, err
 }
@@ -0,0 +1,48 @@
+package grpc
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/JustDean/sam/pkg/logging"
+	grpc_base "google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	REQUEST_ID_HEADER        = "x-request-id"
+	maxRequestIdHeaderLength = 64
+)
+
+// requestIdInterceptor attaches the caller's x-request-id, or a fresh one,
+// to the context of the request and echoes it in the response headers.
+func requestIdInterceptor(ctx context.Context, req any, info *grpc_base.UnaryServerInfo, handler grpc_base.UnaryHandler) (any, error) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if v := md.Get(REQUEST_ID_HEADER); len(v) > 0 && len(v[0]) <= maxRequestIdHeaderLength {
+			id = v[0]
+		}
+	}
+	if id == "" {
+		id = logging.NewRequestId()
+	}
+	grpc_base.SetHeader(ctx, metadata.Pairs(REQUEST_ID_HEADER, id))
+	return handler(logging.WithRequestId(ctx, id), req)
+}
+
+// logOutcome logs the result of an RPC. Failures caused by the caller are
+// logged as warnings, failures of the service itself as errors.
+func logOutcome(ctx context.Context, rpc string, err error, args ...any) {
+	if err == nil {
+		slog.InfoContext(ctx, "Success "+rpc, args...)
+		return
+	}
+	level := slog.LevelWarn
+	switch status.Code(toStatusError(err)) {
+	case codes.Internal, codes.Unavailable, codes.Unknown:
+		level = slog.LevelError
+	}
+	slog.Log(ctx, level, "Error "+rpc, append(args, "error", err)...)
+}
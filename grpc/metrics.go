@@ -0,0 +1,20 @@
+package grpc
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	grpc_base "google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// metricsInterceptor records the count and latency of every RPC by method
+// and resulting status code. It expects errors already translated to statuses.
+func metricsInterceptor(ctx context.Context, req any, info *grpc_base.UnaryServerInfo, handler grpc_base.UnaryHandler) (any, error) {
+	start := time.Now()
+	res, err := handler(ctx, req)
+	metrics.ObserveRpc(path.Base(info.FullMethod), status.Code(err).String(), time.Since(start))
+	return res, err
+}
@@ -19,12 +19,35 @@ import (
 const _ = grpc.SupportPackageIsVersion9
 
 const (
-	Sam_Signup_FullMethodName         = "/Sam/Signup"
-	Sam_Login_FullMethodName          = "/Sam/Login"
-	Sam_SignupAndLogin_FullMethodName = "/Sam/SignupAndLogin"
-	Sam_Logout_FullMethodName         = "/Sam/Logout"
-	Sam_Authenticate_FullMethodName   = "/Sam/Authenticate"
-	Sam_ChangePassword_FullMethodName = "/Sam/ChangePassword"
+	Sam_Signup_FullMethodName                     = "/Sam/Signup"
+	Sam_Login_FullMethodName                      = "/Sam/Login"
+	Sam_SignupAndLogin_FullMethodName             = "/Sam/SignupAndLogin"
+	Sam_Logout_FullMethodName                     = "/Sam/Logout"
+	Sam_Authenticate_FullMethodName               = "/Sam/Authenticate"
+	Sam_ChangePassword_FullMethodName             = "/Sam/ChangePassword"
+	Sam_ListSessions_FullMethodName               = "/Sam/ListSessions"
+	Sam_RevokeAllSessions_FullMethodName          = "/Sam/RevokeAllSessions"
+	Sam_VerifyMfa_FullMethodName                  = "/Sam/VerifyMfa"
+	Sam_EnrollMfa_FullMethodName                  = "/Sam/EnrollMfa"
+	Sam_ConfirmMfa_FullMethodName                 = "/Sam/ConfirmMfa"
+	Sam_DisableMfa_FullMethodName                 = "/Sam/DisableMfa"
+	Sam_RegenerateRecoveryCodes_FullMethodName    = "/Sam/RegenerateRecoveryCodes"
+	Sam_RequestPasswordReset_FullMethodName       = "/Sam/RequestPasswordReset"
+	Sam_ResetPassword_FullMethodName              = "/Sam/ResetPassword"
+	Sam_RefreshAccessToken_FullMethodName         = "/Sam/RefreshAccessToken"
+	Sam_Authorize_FullMethodName                  = "/Sam/Authorize"
+	Sam_GetProfile_FullMethodName                 = "/Sam/GetProfile"
+	Sam_UpdateProfile_FullMethodName              = "/Sam/UpdateProfile"
+	Sam_RequestEmailVerification_FullMethodName   = "/Sam/RequestEmailVerification"
+	Sam_VerifyEmail_FullMethodName                = "/Sam/VerifyEmail"
+	Sam_StartPasswordlessLogin_FullMethodName     = "/Sam/StartPasswordlessLogin"
+	Sam_CompletePasswordlessLogin_FullMethodName  = "/Sam/CompletePasswordlessLogin"
+	Sam_BeginWebauthnRegistration_FullMethodName  = "/Sam/BeginWebauthnRegistration"
+	Sam_FinishWebauthnRegistration_FullMethodName = "/Sam/FinishWebauthnRegistration"
+	Sam_BeginWebauthnLogin_FullMethodName         = "/Sam/BeginWebauthnLogin"
+	Sam_FinishWebauthnLogin_FullMethodName        = "/Sam/FinishWebauthnLogin"
+	Sam_ListWebauthnCredentials_FullMethodName    = "/Sam/ListWebauthnCredentials"
+	Sam_DeleteWebauthnCredential_FullMethodName   = "/Sam/DeleteWebauthnCredential"
 )
 
 // SamClient is the client API for Sam service.
@@ -32,11 +55,34 @@ const (
 // For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
 type SamClient interface {
 	Signup(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*User, error)
-	Login(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*Session, error)
+	Login(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*LoginResponse, error)
 	SignupAndLogin(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*Session, error)
 	Logout(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*Blank, error)
 	Authenticate(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*User, error)
 	ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...grpc.CallOption) (*Blank, error)
+	ListSessions(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*SessionList, error)
+	RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...grpc.CallOption) (*Blank, error)
+	VerifyMfa(ctx context.Context, in *VerifyMfaRequest, opts ...grpc.CallOption) (*Session, error)
+	EnrollMfa(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*MfaEnrollment, error)
+	ConfirmMfa(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*RecoveryCodes, error)
+	DisableMfa(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*Blank, error)
+	RegenerateRecoveryCodes(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*RecoveryCodes, error)
+	RequestPasswordReset(ctx context.Context, in *PasswordResetRequest, opts ...grpc.CallOption) (*Blank, error)
+	ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*Blank, error)
+	RefreshAccessToken(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*AccessToken, error)
+	Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error)
+	GetProfile(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*User, error)
+	UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*User, error)
+	RequestEmailVerification(ctx context.Context, in *EmailVerificationRequest, opts ...grpc.CallOption) (*Blank, error)
+	VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*User, error)
+	StartPasswordlessLogin(ctx context.Context, in *StartPasswordlessLoginRequest, opts ...grpc.CallOption) (*PasswordlessChallenge, error)
+	CompletePasswordlessLogin(ctx context.Context, in *CompletePasswordlessLoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	BeginWebauthnRegistration(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*WebauthnChallenge, error)
+	FinishWebauthnRegistration(ctx context.Context, in *FinishWebauthnRegistrationRequest, opts ...grpc.CallOption) (*WebauthnCredential, error)
+	BeginWebauthnLogin(ctx context.Context, in *BeginWebauthnLoginRequest, opts ...grpc.CallOption) (*WebauthnChallenge, error)
+	FinishWebauthnLogin(ctx context.Context, in *FinishWebauthnLoginRequest, opts ...grpc.CallOption) (*LoginResponse, error)
+	ListWebauthnCredentials(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*WebauthnCredentialList, error)
+	DeleteWebauthnCredential(ctx context.Context, in *DeleteWebauthnCredentialRequest, opts ...grpc.CallOption) (*Blank, error)
 }
 
 type samClient struct {
@@ -57,9 +103,9 @@ func (c *samClient) Signup(ctx context.Context, in *CredentialsRequest, opts ...
 	return out, nil
 }
 
-func (c *samClient) Login(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*Session, error) {
+func (c *samClient) Login(ctx context.Context, in *CredentialsRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
 	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
-	out := new(Session)
+	out := new(LoginResponse)
 	err := c.cc.Invoke(ctx, Sam_Login_FullMethodName, in, out, cOpts...)
 	if err != nil {
 		return nil, err
@@ -107,16 +153,269 @@ func (c *samClient) ChangePassword(ctx context.Context, in *ChangePasswordReques
 	return out, nil
 }
 
+func (c *samClient) ListSessions(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*SessionList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SessionList)
+	err := c.cc.Invoke(ctx, Sam_ListSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) RevokeAllSessions(ctx context.Context, in *RevokeAllSessionsRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_RevokeAllSessions_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) VerifyMfa(ctx context.Context, in *VerifyMfaRequest, opts ...grpc.CallOption) (*Session, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Session)
+	err := c.cc.Invoke(ctx, Sam_VerifyMfa_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) EnrollMfa(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*MfaEnrollment, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(MfaEnrollment)
+	err := c.cc.Invoke(ctx, Sam_EnrollMfa_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) ConfirmMfa(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*RecoveryCodes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecoveryCodes)
+	err := c.cc.Invoke(ctx, Sam_ConfirmMfa_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) DisableMfa(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_DisableMfa_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) RegenerateRecoveryCodes(ctx context.Context, in *MfaCodeRequest, opts ...grpc.CallOption) (*RecoveryCodes, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RecoveryCodes)
+	err := c.cc.Invoke(ctx, Sam_RegenerateRecoveryCodes_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) RequestPasswordReset(ctx context.Context, in *PasswordResetRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_RequestPasswordReset_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_ResetPassword_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) RefreshAccessToken(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*AccessToken, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AccessToken)
+	err := c.cc.Invoke(ctx, Sam_RefreshAccessToken_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) Authorize(ctx context.Context, in *AuthorizeRequest, opts ...grpc.CallOption) (*AuthorizeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(AuthorizeResponse)
+	err := c.cc.Invoke(ctx, Sam_Authorize_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) GetProfile(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, Sam_GetProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) UpdateProfile(ctx context.Context, in *UpdateProfileRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, Sam_UpdateProfile_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) RequestEmailVerification(ctx context.Context, in *EmailVerificationRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_RequestEmailVerification_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) VerifyEmail(ctx context.Context, in *VerifyEmailRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, Sam_VerifyEmail_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) StartPasswordlessLogin(ctx context.Context, in *StartPasswordlessLoginRequest, opts ...grpc.CallOption) (*PasswordlessChallenge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(PasswordlessChallenge)
+	err := c.cc.Invoke(ctx, Sam_StartPasswordlessLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) CompletePasswordlessLogin(ctx context.Context, in *CompletePasswordlessLoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, Sam_CompletePasswordlessLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) BeginWebauthnRegistration(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*WebauthnChallenge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WebauthnChallenge)
+	err := c.cc.Invoke(ctx, Sam_BeginWebauthnRegistration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) FinishWebauthnRegistration(ctx context.Context, in *FinishWebauthnRegistrationRequest, opts ...grpc.CallOption) (*WebauthnCredential, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WebauthnCredential)
+	err := c.cc.Invoke(ctx, Sam_FinishWebauthnRegistration_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) BeginWebauthnLogin(ctx context.Context, in *BeginWebauthnLoginRequest, opts ...grpc.CallOption) (*WebauthnChallenge, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WebauthnChallenge)
+	err := c.cc.Invoke(ctx, Sam_BeginWebauthnLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) FinishWebauthnLogin(ctx context.Context, in *FinishWebauthnLoginRequest, opts ...grpc.CallOption) (*LoginResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(LoginResponse)
+	err := c.cc.Invoke(ctx, Sam_FinishWebauthnLogin_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) ListWebauthnCredentials(ctx context.Context, in *SessionId, opts ...grpc.CallOption) (*WebauthnCredentialList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(WebauthnCredentialList)
+	err := c.cc.Invoke(ctx, Sam_ListWebauthnCredentials_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samClient) DeleteWebauthnCredential(ctx context.Context, in *DeleteWebauthnCredentialRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, Sam_DeleteWebauthnCredential_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
 // SamServer is the server API for Sam service.
 // All implementations must embed UnimplementedSamServer
 // for forward compatibility.
 type SamServer interface {
 	Signup(context.Context, *CredentialsRequest) (*User, error)
-	Login(context.Context, *CredentialsRequest) (*Session, error)
+	Login(context.Context, *CredentialsRequest) (*LoginResponse, error)
 	SignupAndLogin(context.Context, *CredentialsRequest) (*Session, error)
 	Logout(context.Context, *SessionId) (*Blank, error)
 	Authenticate(context.Context, *SessionId) (*User, error)
 	ChangePassword(context.Context, *ChangePasswordRequest) (*Blank, error)
+	ListSessions(context.Context, *SessionId) (*SessionList, error)
+	RevokeAllSessions(context.Context, *RevokeAllSessionsRequest) (*Blank, error)
+	VerifyMfa(context.Context, *VerifyMfaRequest) (*Session, error)
+	EnrollMfa(context.Context, *SessionId) (*MfaEnrollment, error)
+	ConfirmMfa(context.Context, *MfaCodeRequest) (*RecoveryCodes, error)
+	DisableMfa(context.Context, *MfaCodeRequest) (*Blank, error)
+	RegenerateRecoveryCodes(context.Context, *MfaCodeRequest) (*RecoveryCodes, error)
+	RequestPasswordReset(context.Context, *PasswordResetRequest) (*Blank, error)
+	ResetPassword(context.Context, *ResetPasswordRequest) (*Blank, error)
+	RefreshAccessToken(context.Context, *SessionId) (*AccessToken, error)
+	Authorize(context.Context, *AuthorizeRequest) (*AuthorizeResponse, error)
+	GetProfile(context.Context, *SessionId) (*User, error)
+	UpdateProfile(context.Context, *UpdateProfileRequest) (*User, error)
+	RequestEmailVerification(context.Context, *EmailVerificationRequest) (*Blank, error)
+	VerifyEmail(context.Context, *VerifyEmailRequest) (*User, error)
+	StartPasswordlessLogin(context.Context, *StartPasswordlessLoginRequest) (*PasswordlessChallenge, error)
+	CompletePasswordlessLogin(context.Context, *CompletePasswordlessLoginRequest) (*LoginResponse, error)
+	BeginWebauthnRegistration(context.Context, *SessionId) (*WebauthnChallenge, error)
+	FinishWebauthnRegistration(context.Context, *FinishWebauthnRegistrationRequest) (*WebauthnCredential, error)
+	BeginWebauthnLogin(context.Context, *BeginWebauthnLoginRequest) (*WebauthnChallenge, error)
+	FinishWebauthnLogin(context.Context, *FinishWebauthnLoginRequest) (*LoginResponse, error)
+	ListWebauthnCredentials(context.Context, *SessionId) (*WebauthnCredentialList, error)
+	DeleteWebauthnCredential(context.Context, *DeleteWebauthnCredentialRequest) (*Blank, error)
 	mustEmbedUnimplementedSamServer()
 }
 
@@ -130,7 +429,7 @@ type UnimplementedSamServer struct{}
 func (UnimplementedSamServer) Signup(context.Context, *CredentialsRequest) (*User, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Signup not implemented")
 }
-func (UnimplementedSamServer) Login(context.Context, *CredentialsRequest) (*Session, error) {
+func (UnimplementedSamServer) Login(context.Context, *CredentialsRequest) (*LoginResponse, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method Login not implemented")
 }
 func (UnimplementedSamServer) SignupAndLogin(context.Context, *CredentialsRequest) (*Session, error) {
@@ -145,6 +444,75 @@ func (UnimplementedSamServer) Authenticate(context.Context, *SessionId) (*User,
 func (UnimplementedSamServer) ChangePassword(context.Context, *ChangePasswordRequest) (*Blank, error) {
 	return nil, status.Errorf(codes.Unimplemented, "method ChangePassword not implemented")
 }
+func (UnimplementedSamServer) ListSessions(context.Context, *SessionId) (*SessionList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSessions not implemented")
+}
+func (UnimplementedSamServer) RevokeAllSessions(context.Context, *RevokeAllSessionsRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeAllSessions not implemented")
+}
+func (UnimplementedSamServer) VerifyMfa(context.Context, *VerifyMfaRequest) (*Session, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyMfa not implemented")
+}
+func (UnimplementedSamServer) EnrollMfa(context.Context, *SessionId) (*MfaEnrollment, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnrollMfa not implemented")
+}
+func (UnimplementedSamServer) ConfirmMfa(context.Context, *MfaCodeRequest) (*RecoveryCodes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ConfirmMfa not implemented")
+}
+func (UnimplementedSamServer) DisableMfa(context.Context, *MfaCodeRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableMfa not implemented")
+}
+func (UnimplementedSamServer) RegenerateRecoveryCodes(context.Context, *MfaCodeRequest) (*RecoveryCodes, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RegenerateRecoveryCodes not implemented")
+}
+func (UnimplementedSamServer) RequestPasswordReset(context.Context, *PasswordResetRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestPasswordReset not implemented")
+}
+func (UnimplementedSamServer) ResetPassword(context.Context, *ResetPasswordRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ResetPassword not implemented")
+}
+func (UnimplementedSamServer) RefreshAccessToken(context.Context, *SessionId) (*AccessToken, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RefreshAccessToken not implemented")
+}
+func (UnimplementedSamServer) Authorize(context.Context, *AuthorizeRequest) (*AuthorizeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Authorize not implemented")
+}
+func (UnimplementedSamServer) GetProfile(context.Context, *SessionId) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetProfile not implemented")
+}
+func (UnimplementedSamServer) UpdateProfile(context.Context, *UpdateProfileRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method UpdateProfile not implemented")
+}
+func (UnimplementedSamServer) RequestEmailVerification(context.Context, *EmailVerificationRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RequestEmailVerification not implemented")
+}
+func (UnimplementedSamServer) VerifyEmail(context.Context, *VerifyEmailRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method VerifyEmail not implemented")
+}
+func (UnimplementedSamServer) StartPasswordlessLogin(context.Context, *StartPasswordlessLoginRequest) (*PasswordlessChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method StartPasswordlessLogin not implemented")
+}
+func (UnimplementedSamServer) CompletePasswordlessLogin(context.Context, *CompletePasswordlessLoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CompletePasswordlessLogin not implemented")
+}
+func (UnimplementedSamServer) BeginWebauthnRegistration(context.Context, *SessionId) (*WebauthnChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginWebauthnRegistration not implemented")
+}
+func (UnimplementedSamServer) FinishWebauthnRegistration(context.Context, *FinishWebauthnRegistrationRequest) (*WebauthnCredential, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishWebauthnRegistration not implemented")
+}
+func (UnimplementedSamServer) BeginWebauthnLogin(context.Context, *BeginWebauthnLoginRequest) (*WebauthnChallenge, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method BeginWebauthnLogin not implemented")
+}
+func (UnimplementedSamServer) FinishWebauthnLogin(context.Context, *FinishWebauthnLoginRequest) (*LoginResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method FinishWebauthnLogin not implemented")
+}
+func (UnimplementedSamServer) ListWebauthnCredentials(context.Context, *SessionId) (*WebauthnCredentialList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListWebauthnCredentials not implemented")
+}
+func (UnimplementedSamServer) DeleteWebauthnCredential(context.Context, *DeleteWebauthnCredentialRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteWebauthnCredential not implemented")
+}
 func (UnimplementedSamServer) mustEmbedUnimplementedSamServer() {}
 func (UnimplementedSamServer) testEmbeddedByValue()             {}
 
@@ -274,36 +642,1562 @@ func _Sam_ChangePassword_Handler(srv interface{}, ctx context.Context, dec func(
 	return interceptor(ctx, in, info, handler)
 }
 
-// Sam_ServiceDesc is the grpc.ServiceDesc for Sam service.
-// It's only intended for direct use with grpc.RegisterService,
-// and not to be introspected or modified (even as a copy)
-var Sam_ServiceDesc = grpc.ServiceDesc{
-	ServiceName: "Sam",
-	HandlerType: (*SamServer)(nil),
-	Methods: []grpc.MethodDesc{
-		{
-			MethodName: "Signup",
-			Handler:    _Sam_Signup_Handler,
-		},
-		{
-			MethodName: "Login",
-			Handler:    _Sam_Login_Handler,
-		},
-		{
-			MethodName: "SignupAndLogin",
-			Handler:    _Sam_SignupAndLogin_Handler,
-		},
-		{
-			MethodName: "Logout",
-			Handler:    _Sam_Logout_Handler,
-		},
-		{
-			MethodName: "Authenticate",
-			Handler:    _Sam_Authenticate_Handler,
-		},
-		{
-			MethodName: "ChangePassword",
-			Handler:    _Sam_ChangePassword_Handler,
+func _Sam_ListSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).ListSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_ListSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).ListSessions(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_RevokeAllSessions_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeAllSessionsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).RevokeAllSessions(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_RevokeAllSessions_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).RevokeAllSessions(ctx, req.(*RevokeAllSessionsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_VerifyMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyMfaRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).VerifyMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_VerifyMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).VerifyMfa(ctx, req.(*VerifyMfaRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_EnrollMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).EnrollMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_EnrollMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).EnrollMfa(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_ConfirmMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MfaCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).ConfirmMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_ConfirmMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).ConfirmMfa(ctx, req.(*MfaCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_DisableMfa_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MfaCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).DisableMfa(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_DisableMfa_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).DisableMfa(ctx, req.(*MfaCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_RegenerateRecoveryCodes_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(MfaCodeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).RegenerateRecoveryCodes(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_RegenerateRecoveryCodes_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).RegenerateRecoveryCodes(ctx, req.(*MfaCodeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_RequestPasswordReset_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(PasswordResetRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).RequestPasswordReset(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_RequestPasswordReset_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).RequestPasswordReset(ctx, req.(*PasswordResetRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_ResetPassword_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ResetPasswordRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).ResetPassword(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_ResetPassword_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).ResetPassword(ctx, req.(*ResetPasswordRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_RefreshAccessToken_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).RefreshAccessToken(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_RefreshAccessToken_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).RefreshAccessToken(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_Authorize_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(AuthorizeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).Authorize(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_Authorize_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).Authorize(ctx, req.(*AuthorizeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_GetProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).GetProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_GetProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).GetProfile(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_UpdateProfile_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UpdateProfileRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).UpdateProfile(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_UpdateProfile_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).UpdateProfile(ctx, req.(*UpdateProfileRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_RequestEmailVerification_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EmailVerificationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).RequestEmailVerification(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_RequestEmailVerification_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).RequestEmailVerification(ctx, req.(*EmailVerificationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_VerifyEmail_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(VerifyEmailRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).VerifyEmail(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_VerifyEmail_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).VerifyEmail(ctx, req.(*VerifyEmailRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_StartPasswordlessLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(StartPasswordlessLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).StartPasswordlessLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_StartPasswordlessLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).StartPasswordlessLogin(ctx, req.(*StartPasswordlessLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_CompletePasswordlessLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CompletePasswordlessLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).CompletePasswordlessLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_CompletePasswordlessLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).CompletePasswordlessLogin(ctx, req.(*CompletePasswordlessLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_BeginWebauthnRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).BeginWebauthnRegistration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_BeginWebauthnRegistration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).BeginWebauthnRegistration(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_FinishWebauthnRegistration_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishWebauthnRegistrationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).FinishWebauthnRegistration(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_FinishWebauthnRegistration_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).FinishWebauthnRegistration(ctx, req.(*FinishWebauthnRegistrationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_BeginWebauthnLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BeginWebauthnLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).BeginWebauthnLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_BeginWebauthnLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).BeginWebauthnLogin(ctx, req.(*BeginWebauthnLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_FinishWebauthnLogin_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(FinishWebauthnLoginRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).FinishWebauthnLogin(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_FinishWebauthnLogin_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).FinishWebauthnLogin(ctx, req.(*FinishWebauthnLoginRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_ListWebauthnCredentials_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(SessionId)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).ListWebauthnCredentials(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_ListWebauthnCredentials_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).ListWebauthnCredentials(ctx, req.(*SessionId))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Sam_DeleteWebauthnCredential_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteWebauthnCredentialRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamServer).DeleteWebauthnCredential(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Sam_DeleteWebauthnCredential_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamServer).DeleteWebauthnCredential(ctx, req.(*DeleteWebauthnCredentialRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Sam_ServiceDesc is the grpc.ServiceDesc for Sam service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Sam_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "Sam",
+	HandlerType: (*SamServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "Signup",
+			Handler:    _Sam_Signup_Handler,
+		},
+		{
+			MethodName: "Login",
+			Handler:    _Sam_Login_Handler,
+		},
+		{
+			MethodName: "SignupAndLogin",
+			Handler:    _Sam_SignupAndLogin_Handler,
+		},
+		{
+			MethodName: "Logout",
+			Handler:    _Sam_Logout_Handler,
+		},
+		{
+			MethodName: "Authenticate",
+			Handler:    _Sam_Authenticate_Handler,
+		},
+		{
+			MethodName: "ChangePassword",
+			Handler:    _Sam_ChangePassword_Handler,
+		},
+		{
+			MethodName: "ListSessions",
+			Handler:    _Sam_ListSessions_Handler,
+		},
+		{
+			MethodName: "RevokeAllSessions",
+			Handler:    _Sam_RevokeAllSessions_Handler,
+		},
+		{
+			MethodName: "VerifyMfa",
+			Handler:    _Sam_VerifyMfa_Handler,
+		},
+		{
+			MethodName: "EnrollMfa",
+			Handler:    _Sam_EnrollMfa_Handler,
+		},
+		{
+			MethodName: "ConfirmMfa",
+			Handler:    _Sam_ConfirmMfa_Handler,
+		},
+		{
+			MethodName: "DisableMfa",
+			Handler:    _Sam_DisableMfa_Handler,
+		},
+		{
+			MethodName: "RegenerateRecoveryCodes",
+			Handler:    _Sam_RegenerateRecoveryCodes_Handler,
+		},
+		{
+			MethodName: "RequestPasswordReset",
+			Handler:    _Sam_RequestPasswordReset_Handler,
+		},
+		{
+			MethodName: "ResetPassword",
+			Handler:    _Sam_ResetPassword_Handler,
+		},
+		{
+			MethodName: "RefreshAccessToken",
+			Handler:    _Sam_RefreshAccessToken_Handler,
+		},
+		{
+			MethodName: "Authorize",
+			Handler:    _Sam_Authorize_Handler,
+		},
+		{
+			MethodName: "GetProfile",
+			Handler:    _Sam_GetProfile_Handler,
+		},
+		{
+			MethodName: "UpdateProfile",
+			Handler:    _Sam_UpdateProfile_Handler,
+		},
+		{
+			MethodName: "RequestEmailVerification",
+			Handler:    _Sam_RequestEmailVerification_Handler,
+		},
+		{
+			MethodName: "VerifyEmail",
+			Handler:    _Sam_VerifyEmail_Handler,
+		},
+		{
+			MethodName: "StartPasswordlessLogin",
+			Handler:    _Sam_StartPasswordlessLogin_Handler,
+		},
+		{
+			MethodName: "CompletePasswordlessLogin",
+			Handler:    _Sam_CompletePasswordlessLogin_Handler,
+		},
+		{
+			MethodName: "BeginWebauthnRegistration",
+			Handler:    _Sam_BeginWebauthnRegistration_Handler,
+		},
+		{
+			MethodName: "FinishWebauthnRegistration",
+			Handler:    _Sam_FinishWebauthnRegistration_Handler,
+		},
+		{
+			MethodName: "BeginWebauthnLogin",
+			Handler:    _Sam_BeginWebauthnLogin_Handler,
+		},
+		{
+			MethodName: "FinishWebauthnLogin",
+			Handler:    _Sam_FinishWebauthnLogin_Handler,
+		},
+		{
+			MethodName: "ListWebauthnCredentials",
+			Handler:    _Sam_ListWebauthnCredentials_Handler,
+		},
+		{
+			MethodName: "DeleteWebauthnCredential",
+			Handler:    _Sam_DeleteWebauthnCredential_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "api/sam_api.proto",
+}
+
+const (
+	SamAdmin_ListSigningKeys_FullMethodName      = "/SamAdmin/ListSigningKeys"
+	SamAdmin_RotateSigningKey_FullMethodName     = "/SamAdmin/RotateSigningKey"
+	SamAdmin_RevokeSigningKey_FullMethodName     = "/SamAdmin/RevokeSigningKey"
+	SamAdmin_CreateClient_FullMethodName         = "/SamAdmin/CreateClient"
+	SamAdmin_ListClients_FullMethodName          = "/SamAdmin/ListClients"
+	SamAdmin_DeleteClient_FullMethodName         = "/SamAdmin/DeleteClient"
+	SamAdmin_CreateServiceAccount_FullMethodName = "/SamAdmin/CreateServiceAccount"
+	SamAdmin_ListServiceAccounts_FullMethodName  = "/SamAdmin/ListServiceAccounts"
+	SamAdmin_DeleteServiceAccount_FullMethodName = "/SamAdmin/DeleteServiceAccount"
+	SamAdmin_CreateRole_FullMethodName           = "/SamAdmin/CreateRole"
+	SamAdmin_ListRoles_FullMethodName            = "/SamAdmin/ListRoles"
+	SamAdmin_DeleteRole_FullMethodName           = "/SamAdmin/DeleteRole"
+	SamAdmin_AddRolePermission_FullMethodName    = "/SamAdmin/AddRolePermission"
+	SamAdmin_RemoveRolePermission_FullMethodName = "/SamAdmin/RemoveRolePermission"
+	SamAdmin_GrantRole_FullMethodName            = "/SamAdmin/GrantRole"
+	SamAdmin_RevokeRole_FullMethodName           = "/SamAdmin/RevokeRole"
+	SamAdmin_ListUserRoles_FullMethodName        = "/SamAdmin/ListUserRoles"
+	SamAdmin_CreateTenant_FullMethodName         = "/SamAdmin/CreateTenant"
+	SamAdmin_ListTenants_FullMethodName          = "/SamAdmin/ListTenants"
+	SamAdmin_DisableTenant_FullMethodName        = "/SamAdmin/DisableTenant"
+	SamAdmin_ListUsers_FullMethodName            = "/SamAdmin/ListUsers"
+	SamAdmin_GetUser_FullMethodName              = "/SamAdmin/GetUser"
+	SamAdmin_DisableUser_FullMethodName          = "/SamAdmin/DisableUser"
+	SamAdmin_EnableUser_FullMethodName           = "/SamAdmin/EnableUser"
+	SamAdmin_DeleteUser_FullMethodName           = "/SamAdmin/DeleteUser"
+)
+
+// SamAdminClient is the client API for SamAdmin service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// SamAdmin is only served if an admin token is configured. Calls must carry
+// it as "authorization: Bearer <token>" metadata.
+type SamAdminClient interface {
+	ListSigningKeys(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*SigningKeyList, error)
+	RotateSigningKey(ctx context.Context, in *RotateSigningKeyRequest, opts ...grpc.CallOption) (*SigningKey, error)
+	RevokeSigningKey(ctx context.Context, in *RevokeSigningKeyRequest, opts ...grpc.CallOption) (*Blank, error)
+	CreateClient(ctx context.Context, in *CreateClientRequest, opts ...grpc.CallOption) (*Client, error)
+	ListClients(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*ClientList, error)
+	DeleteClient(ctx context.Context, in *DeleteClientRequest, opts ...grpc.CallOption) (*Blank, error)
+	CreateServiceAccount(ctx context.Context, in *CreateServiceAccountRequest, opts ...grpc.CallOption) (*ServiceAccount, error)
+	ListServiceAccounts(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*ServiceAccountList, error)
+	DeleteServiceAccount(ctx context.Context, in *DeleteServiceAccountRequest, opts ...grpc.CallOption) (*Blank, error)
+	CreateRole(ctx context.Context, in *CreateRoleRequest, opts ...grpc.CallOption) (*Role, error)
+	ListRoles(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*RoleList, error)
+	DeleteRole(ctx context.Context, in *DeleteRoleRequest, opts ...grpc.CallOption) (*Blank, error)
+	AddRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*Blank, error)
+	RemoveRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*Blank, error)
+	GrantRole(ctx context.Context, in *UserRoleRequest, opts ...grpc.CallOption) (*Blank, error)
+	RevokeRole(ctx context.Context, in *UserRoleRequest, opts ...grpc.CallOption) (*Blank, error)
+	ListUserRoles(ctx context.Context, in *ListUserRolesRequest, opts ...grpc.CallOption) (*RoleList, error)
+	CreateTenant(ctx context.Context, in *CreateTenantRequest, opts ...grpc.CallOption) (*Tenant, error)
+	ListTenants(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*TenantList, error)
+	DisableTenant(ctx context.Context, in *DisableTenantRequest, opts ...grpc.CallOption) (*Blank, error)
+	ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*UserList, error)
+	GetUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*User, error)
+	DisableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error)
+	EnableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error)
+	DeleteUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error)
+}
+
+type samAdminClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewSamAdminClient(cc grpc.ClientConnInterface) SamAdminClient {
+	return &samAdminClient{cc}
+}
+
+func (c *samAdminClient) ListSigningKeys(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*SigningKeyList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SigningKeyList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListSigningKeys_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) RotateSigningKey(ctx context.Context, in *RotateSigningKeyRequest, opts ...grpc.CallOption) (*SigningKey, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(SigningKey)
+	err := c.cc.Invoke(ctx, SamAdmin_RotateSigningKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) RevokeSigningKey(ctx context.Context, in *RevokeSigningKeyRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_RevokeSigningKey_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) CreateClient(ctx context.Context, in *CreateClientRequest, opts ...grpc.CallOption) (*Client, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Client)
+	err := c.cc.Invoke(ctx, SamAdmin_CreateClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListClients(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*ClientList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ClientList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListClients_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DeleteClient(ctx context.Context, in *DeleteClientRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DeleteClient_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) CreateServiceAccount(ctx context.Context, in *CreateServiceAccountRequest, opts ...grpc.CallOption) (*ServiceAccount, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceAccount)
+	err := c.cc.Invoke(ctx, SamAdmin_CreateServiceAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListServiceAccounts(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*ServiceAccountList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(ServiceAccountList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListServiceAccounts_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DeleteServiceAccount(ctx context.Context, in *DeleteServiceAccountRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DeleteServiceAccount_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) CreateRole(ctx context.Context, in *CreateRoleRequest, opts ...grpc.CallOption) (*Role, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Role)
+	err := c.cc.Invoke(ctx, SamAdmin_CreateRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListRoles(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*RoleList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoleList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListRoles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DeleteRole(ctx context.Context, in *DeleteRoleRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DeleteRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) AddRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_AddRolePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) RemoveRolePermission(ctx context.Context, in *RolePermissionRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_RemoveRolePermission_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) GrantRole(ctx context.Context, in *UserRoleRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_GrantRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) RevokeRole(ctx context.Context, in *UserRoleRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_RevokeRole_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListUserRoles(ctx context.Context, in *ListUserRolesRequest, opts ...grpc.CallOption) (*RoleList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(RoleList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListUserRoles_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) CreateTenant(ctx context.Context, in *CreateTenantRequest, opts ...grpc.CallOption) (*Tenant, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Tenant)
+	err := c.cc.Invoke(ctx, SamAdmin_CreateTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListTenants(ctx context.Context, in *Blank, opts ...grpc.CallOption) (*TenantList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(TenantList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListTenants_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DisableTenant(ctx context.Context, in *DisableTenantRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DisableTenant_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...grpc.CallOption) (*UserList, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(UserList)
+	err := c.cc.Invoke(ctx, SamAdmin_ListUsers_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) GetUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*User, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(User)
+	err := c.cc.Invoke(ctx, SamAdmin_GetUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DisableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DisableUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) EnableUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_EnableUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *samAdminClient) DeleteUser(ctx context.Context, in *UserRequest, opts ...grpc.CallOption) (*Blank, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(Blank)
+	err := c.cc.Invoke(ctx, SamAdmin_DeleteUser_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SamAdminServer is the server API for SamAdmin service.
+// All implementations must embed UnimplementedSamAdminServer
+// for forward compatibility.
+//
+// SamAdmin is only served if an admin token is configured. Calls must carry
+// it as "authorization: Bearer <token>" metadata.
+type SamAdminServer interface {
+	ListSigningKeys(context.Context, *Blank) (*SigningKeyList, error)
+	RotateSigningKey(context.Context, *RotateSigningKeyRequest) (*SigningKey, error)
+	RevokeSigningKey(context.Context, *RevokeSigningKeyRequest) (*Blank, error)
+	CreateClient(context.Context, *CreateClientRequest) (*Client, error)
+	ListClients(context.Context, *Blank) (*ClientList, error)
+	DeleteClient(context.Context, *DeleteClientRequest) (*Blank, error)
+	CreateServiceAccount(context.Context, *CreateServiceAccountRequest) (*ServiceAccount, error)
+	ListServiceAccounts(context.Context, *Blank) (*ServiceAccountList, error)
+	DeleteServiceAccount(context.Context, *DeleteServiceAccountRequest) (*Blank, error)
+	CreateRole(context.Context, *CreateRoleRequest) (*Role, error)
+	ListRoles(context.Context, *Blank) (*RoleList, error)
+	DeleteRole(context.Context, *DeleteRoleRequest) (*Blank, error)
+	AddRolePermission(context.Context, *RolePermissionRequest) (*Blank, error)
+	RemoveRolePermission(context.Context, *RolePermissionRequest) (*Blank, error)
+	GrantRole(context.Context, *UserRoleRequest) (*Blank, error)
+	RevokeRole(context.Context, *UserRoleRequest) (*Blank, error)
+	ListUserRoles(context.Context, *ListUserRolesRequest) (*RoleList, error)
+	CreateTenant(context.Context, *CreateTenantRequest) (*Tenant, error)
+	ListTenants(context.Context, *Blank) (*TenantList, error)
+	DisableTenant(context.Context, *DisableTenantRequest) (*Blank, error)
+	ListUsers(context.Context, *ListUsersRequest) (*UserList, error)
+	GetUser(context.Context, *UserRequest) (*User, error)
+	DisableUser(context.Context, *UserRequest) (*Blank, error)
+	EnableUser(context.Context, *UserRequest) (*Blank, error)
+	DeleteUser(context.Context, *UserRequest) (*Blank, error)
+	mustEmbedUnimplementedSamAdminServer()
+}
+
+// UnimplementedSamAdminServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedSamAdminServer struct{}
+
+func (UnimplementedSamAdminServer) ListSigningKeys(context.Context, *Blank) (*SigningKeyList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListSigningKeys not implemented")
+}
+func (UnimplementedSamAdminServer) RotateSigningKey(context.Context, *RotateSigningKeyRequest) (*SigningKey, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RotateSigningKey not implemented")
+}
+func (UnimplementedSamAdminServer) RevokeSigningKey(context.Context, *RevokeSigningKeyRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeSigningKey not implemented")
+}
+func (UnimplementedSamAdminServer) CreateClient(context.Context, *CreateClientRequest) (*Client, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateClient not implemented")
+}
+func (UnimplementedSamAdminServer) ListClients(context.Context, *Blank) (*ClientList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListClients not implemented")
+}
+func (UnimplementedSamAdminServer) DeleteClient(context.Context, *DeleteClientRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteClient not implemented")
+}
+func (UnimplementedSamAdminServer) CreateServiceAccount(context.Context, *CreateServiceAccountRequest) (*ServiceAccount, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateServiceAccount not implemented")
+}
+func (UnimplementedSamAdminServer) ListServiceAccounts(context.Context, *Blank) (*ServiceAccountList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListServiceAccounts not implemented")
+}
+func (UnimplementedSamAdminServer) DeleteServiceAccount(context.Context, *DeleteServiceAccountRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteServiceAccount not implemented")
+}
+func (UnimplementedSamAdminServer) CreateRole(context.Context, *CreateRoleRequest) (*Role, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateRole not implemented")
+}
+func (UnimplementedSamAdminServer) ListRoles(context.Context, *Blank) (*RoleList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListRoles not implemented")
+}
+func (UnimplementedSamAdminServer) DeleteRole(context.Context, *DeleteRoleRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteRole not implemented")
+}
+func (UnimplementedSamAdminServer) AddRolePermission(context.Context, *RolePermissionRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method AddRolePermission not implemented")
+}
+func (UnimplementedSamAdminServer) RemoveRolePermission(context.Context, *RolePermissionRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RemoveRolePermission not implemented")
+}
+func (UnimplementedSamAdminServer) GrantRole(context.Context, *UserRoleRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GrantRole not implemented")
+}
+func (UnimplementedSamAdminServer) RevokeRole(context.Context, *UserRoleRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method RevokeRole not implemented")
+}
+func (UnimplementedSamAdminServer) ListUserRoles(context.Context, *ListUserRolesRequest) (*RoleList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUserRoles not implemented")
+}
+func (UnimplementedSamAdminServer) CreateTenant(context.Context, *CreateTenantRequest) (*Tenant, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CreateTenant not implemented")
+}
+func (UnimplementedSamAdminServer) ListTenants(context.Context, *Blank) (*TenantList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListTenants not implemented")
+}
+func (UnimplementedSamAdminServer) DisableTenant(context.Context, *DisableTenantRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableTenant not implemented")
+}
+func (UnimplementedSamAdminServer) ListUsers(context.Context, *ListUsersRequest) (*UserList, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method ListUsers not implemented")
+}
+func (UnimplementedSamAdminServer) GetUser(context.Context, *UserRequest) (*User, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method GetUser not implemented")
+}
+func (UnimplementedSamAdminServer) DisableUser(context.Context, *UserRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DisableUser not implemented")
+}
+func (UnimplementedSamAdminServer) EnableUser(context.Context, *UserRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method EnableUser not implemented")
+}
+func (UnimplementedSamAdminServer) DeleteUser(context.Context, *UserRequest) (*Blank, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteUser not implemented")
+}
+func (UnimplementedSamAdminServer) mustEmbedUnimplementedSamAdminServer() {}
+func (UnimplementedSamAdminServer) testEmbeddedByValue()                  {}
+
+// UnsafeSamAdminServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to SamAdminServer will
+// result in compilation errors.
+type UnsafeSamAdminServer interface {
+	mustEmbedUnimplementedSamAdminServer()
+}
+
+func RegisterSamAdminServer(s grpc.ServiceRegistrar, srv SamAdminServer) {
+	// If the following call pancis, it indicates UnimplementedSamAdminServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&SamAdmin_ServiceDesc, srv)
+}
+
+func _SamAdmin_ListSigningKeys_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blank)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListSigningKeys(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListSigningKeys_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListSigningKeys(ctx, req.(*Blank))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_RotateSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RotateSigningKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).RotateSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_RotateSigningKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).RotateSigningKey(ctx, req.(*RotateSigningKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_RevokeSigningKey_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RevokeSigningKeyRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).RevokeSigningKey(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_RevokeSigningKey_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).RevokeSigningKey(ctx, req.(*RevokeSigningKeyRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_CreateClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateClientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).CreateClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_CreateClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).CreateClient(ctx, req.(*CreateClientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListClients_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blank)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListClients(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListClients_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListClients(ctx, req.(*Blank))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DeleteClient_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteClientRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DeleteClient(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DeleteClient_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DeleteClient(ctx, req.(*DeleteClientRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_CreateServiceAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateServiceAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).CreateServiceAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_CreateServiceAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).CreateServiceAccount(ctx, req.(*CreateServiceAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListServiceAccounts_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blank)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListServiceAccounts(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListServiceAccounts_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListServiceAccounts(ctx, req.(*Blank))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DeleteServiceAccount_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteServiceAccountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DeleteServiceAccount(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DeleteServiceAccount_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DeleteServiceAccount(ctx, req.(*DeleteServiceAccountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_CreateRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).CreateRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_CreateRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).CreateRole(ctx, req.(*CreateRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blank)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListRoles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListRoles(ctx, req.(*Blank))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DeleteRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DeleteRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DeleteRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DeleteRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DeleteRole(ctx, req.(*DeleteRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_AddRolePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RolePermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).AddRolePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_AddRolePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).AddRolePermission(ctx, req.(*RolePermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_RemoveRolePermission_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(RolePermissionRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).RemoveRolePermission(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_RemoveRolePermission_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).RemoveRolePermission(ctx, req.(*RolePermissionRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_GrantRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).GrantRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_GrantRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).GrantRole(ctx, req.(*UserRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_RevokeRole_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRoleRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).RevokeRole(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_RevokeRole_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).RevokeRole(ctx, req.(*UserRoleRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListUserRoles_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUserRolesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListUserRoles(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListUserRoles_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListUserRoles(ctx, req.(*ListUserRolesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_CreateTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CreateTenantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).CreateTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_CreateTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).CreateTenant(ctx, req.(*CreateTenantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListTenants_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(Blank)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListTenants(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListTenants_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListTenants(ctx, req.(*Blank))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DisableTenant_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(DisableTenantRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DisableTenant(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DisableTenant_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DisableTenant(ctx, req.(*DisableTenantRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_ListUsers_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListUsersRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).ListUsers(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_ListUsers_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).ListUsers(ctx, req.(*ListUsersRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_GetUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).GetUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_GetUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).GetUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DisableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DisableUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DisableUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DisableUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_EnableUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).EnableUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_EnableUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).EnableUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _SamAdmin_DeleteUser_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(UserRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(SamAdminServer).DeleteUser(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: SamAdmin_DeleteUser_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(SamAdminServer).DeleteUser(ctx, req.(*UserRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// SamAdmin_ServiceDesc is the grpc.ServiceDesc for SamAdmin service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var SamAdmin_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "SamAdmin",
+	HandlerType: (*SamAdminServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "ListSigningKeys",
+			Handler:    _SamAdmin_ListSigningKeys_Handler,
+		},
+		{
+			MethodName: "RotateSigningKey",
+			Handler:    _SamAdmin_RotateSigningKey_Handler,
+		},
+		{
+			MethodName: "RevokeSigningKey",
+			Handler:    _SamAdmin_RevokeSigningKey_Handler,
+		},
+		{
+			MethodName: "CreateClient",
+			Handler:    _SamAdmin_CreateClient_Handler,
+		},
+		{
+			MethodName: "ListClients",
+			Handler:    _SamAdmin_ListClients_Handler,
+		},
+		{
+			MethodName: "DeleteClient",
+			Handler:    _SamAdmin_DeleteClient_Handler,
+		},
+		{
+			MethodName: "CreateServiceAccount",
+			Handler:    _SamAdmin_CreateServiceAccount_Handler,
+		},
+		{
+			MethodName: "ListServiceAccounts",
+			Handler:    _SamAdmin_ListServiceAccounts_Handler,
+		},
+		{
+			MethodName: "DeleteServiceAccount",
+			Handler:    _SamAdmin_DeleteServiceAccount_Handler,
+		},
+		{
+			MethodName: "CreateRole",
+			Handler:    _SamAdmin_CreateRole_Handler,
+		},
+		{
+			MethodName: "ListRoles",
+			Handler:    _SamAdmin_ListRoles_Handler,
+		},
+		{
+			MethodName: "DeleteRole",
+			Handler:    _SamAdmin_DeleteRole_Handler,
+		},
+		{
+			MethodName: "AddRolePermission",
+			Handler:    _SamAdmin_AddRolePermission_Handler,
+		},
+		{
+			MethodName: "RemoveRolePermission",
+			Handler:    _SamAdmin_RemoveRolePermission_Handler,
+		},
+		{
+			MethodName: "GrantRole",
+			Handler:    _SamAdmin_GrantRole_Handler,
+		},
+		{
+			MethodName: "RevokeRole",
+			Handler:    _SamAdmin_RevokeRole_Handler,
+		},
+		{
+			MethodName: "ListUserRoles",
+			Handler:    _SamAdmin_ListUserRoles_Handler,
+		},
+		{
+			MethodName: "CreateTenant",
+			Handler:    _SamAdmin_CreateTenant_Handler,
+		},
+		{
+			MethodName: "ListTenants",
+			Handler:    _SamAdmin_ListTenants_Handler,
+		},
+		{
+			MethodName: "DisableTenant",
+			Handler:    _SamAdmin_DisableTenant_Handler,
+		},
+		{
+			MethodName: "ListUsers",
+			Handler:    _SamAdmin_ListUsers_Handler,
+		},
+		{
+			MethodName: "GetUser",
+			Handler:    _SamAdmin_GetUser_Handler,
+		},
+		{
+			MethodName: "DisableUser",
+			Handler:    _SamAdmin_DisableUser_Handler,
+		},
+		{
+			MethodName: "EnableUser",
+			Handler:    _SamAdmin_EnableUser_Handler,
+		},
+		{
+			MethodName: "DeleteUser",
+			Handler:    _SamAdmin_DeleteUser_Handler,
 		},
 	},
 	Streams:  []grpc.StreamDesc{},
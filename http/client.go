@@ -0,0 +1,21 @@
+package http
+
+import (
+	http_base "net/http"
+
+	"github.com/JustDean/sam/pkg/auth"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// clientInfo describes the caller of the request. Forwarded headers set by
+// a proxy are only honoured when trustProxy is set.
+func (s *Server) clientInfo(r *http_base.Request, deviceName string) auth.ClientInfo {
+	c := auth.ClientInfo{UserAgent: r.UserAgent(), DeviceName: deviceName}
+	if s.trustProxy {
+		c.Ip = utils.ForwardedIp(r.Header.Get("X-Forwarded-For"), r.Header.Get("X-Real-Ip"))
+	}
+	if c.Ip == "" {
+		c.Ip = utils.HostIp(r.RemoteAddr)
+	}
+	return c
+}
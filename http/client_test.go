@@ -0,0 +1,34 @@
+package http
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClientInfo(t *testing.T) {
+	tests := []struct {
+		name       string
+		trustProxy bool
+		headers    map[string]string
+		want       string
+	}{
+		{"remote address", false, nil, "192.0.2.1"},
+		{"forwarded for untrusted proxy", false, map[string]string{"X-Forwarded-For": "198.51.100.7"}, "192.0.2.1"},
+		{"first forwarded hop", true, map[string]string{"X-Forwarded-For": "198.51.100.7, 10.0.0.1"}, "198.51.100.7"},
+		{"real ip", true, map[string]string{"X-Real-Ip": "198.51.100.8"}, "198.51.100.8"},
+		{"trusted proxy without headers", true, nil, "192.0.2.1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("POST", "/authorize", nil)
+			r.RemoteAddr = "192.0.2.1:5000"
+			for k, v := range tt.headers {
+				r.Header.Set(k, v)
+			}
+			s := &Server{trustProxy: tt.trustProxy}
+			if got := s.clientInfo(r, "app").Ip; got != tt.want {
+				t.Fatalf("got client ip %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,19 @@
+package http
+
+import "fmt"
+
+type Config struct {
+	Host string
+	Port string
+	// TrustProxy makes client addresses be read from the X-Forwarded-For
+	// and X-Real-Ip headers. Enable only behind a trusted proxy.
+	TrustProxy bool
+	// Clients maps the ids of OAuth clients allowed to introspect and
+	// revoke sessions to their secrets. The OAuth endpoints are served
+	// only if there is one.
+	Clients map[string]string
+}
+
+func (c *Config) url() string {
+	return fmt.Sprintf("%s:%s", c.Host, c.Port)
+}
@@ -0,0 +1,19 @@
+package http
+
+import (
+	http_base "net/http"
+)
+
+// healthz reports the process is alive; it does not check dependencies.
+func (s *Server) healthz(w http_base.ResponseWriter, r *http_base.Request) {
+	w.Write([]byte("ok\n"))
+}
+
+// readyz reports whether Postgres and Redis passed the last health check.
+func (s *Server) readyz(w http_base.ResponseWriter, r *http_base.Request) {
+	if !s.checker.Ready() {
+		http_base.Error(w, "not ready", http_base.StatusServiceUnavailable)
+		return
+	}
+	w.Write([]byte("ok\n"))
+}
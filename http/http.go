@@ -0,0 +1,74 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	http_base "net/http"
+	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
+	"github.com/JustDean/sam/pkg/health"
+)
+
+const (
+	READ_HEADER_TIMEOUT = 10 * time.Second
+	SHUTDOWN_TIMEOUT    = 10 * time.Second
+)
+
+func SetServer(c Config, am *auth.AuthManager, checker *health.Checker) (*Server, error) {
+	lis, err := net.Listen("tcp", c.url())
+	if err != nil {
+		return nil, err
+	}
+	server := &Server{
+		l:          lis,
+		am:         am,
+		checker:    checker,
+		clients:    newOauthClients(c.Clients),
+		trustProxy: c.TrustProxy,
+	}
+	mux := http_base.NewServeMux()
+	mux.HandleFunc("GET /healthz", server.healthz)
+	mux.HandleFunc("GET /readyz", server.readyz)
+	mux.HandleFunc("GET /.well-known/jwks.json", server.jwks)
+	mux.HandleFunc("POST /token", server.token)
+	if am.OidcIssuer() != "" {
+		mux.HandleFunc("GET /.well-known/openid-configuration", server.discovery)
+		mux.HandleFunc("GET /authorize", server.authorize)
+		mux.HandleFunc("POST /authorize", server.authorize)
+		mux.HandleFunc("GET /userinfo", server.userinfo)
+		mux.HandleFunc("POST /userinfo", server.userinfo)
+	}
+	if len(server.clients) > 0 {
+		mux.HandleFunc("POST /oauth/introspect", server.introspect)
+		mux.HandleFunc("POST /oauth/revoke", server.revoke)
+	}
+	server.s = &http_base.Server{Handler: mux, ReadHeaderTimeout: READ_HEADER_TIMEOUT}
+	return server, nil
+}
+
+type Server struct {
+	l          net.Listener
+	s          *http_base.Server
+	am         *auth.AuthManager
+	checker    *health.Checker
+	clients    oauthClients
+	trustProxy bool
+}
+
+func (s *Server) Run(ctx context.Context) {
+	slog.Info("Starting HTTP Server", "addr", s.l.Addr().String())
+	go func() {
+		if err := s.s.Serve(s.l); err != nil && !errors.Is(err, http_base.ErrServerClosed) {
+			slog.Error("HTTP Server failed", "error", err)
+		}
+	}()
+	<-ctx.Done()
+	slog.Info("Stopping HTTP Server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	s.s.Shutdown(shutdownCtx)
+	slog.Info("HTTP Server is stopped")
+}
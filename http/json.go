@@ -0,0 +1,15 @@
+package http
+
+import (
+	"encoding/json"
+	"log/slog"
+	http_base "net/http"
+)
+
+func writeJson(w http_base.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		slog.Error("Error writing response", "error", err)
+	}
+}
@@ -0,0 +1,14 @@
+package http
+
+import (
+	http_base "net/http"
+)
+
+// JWKS_MAX_AGE is how long verifiers may cache the key set.
+const JWKS_MAX_AGE = "max-age=300"
+
+// jwks publishes the public keys access tokens are signed with.
+func (s *Server) jwks(w http_base.ResponseWriter, r *http_base.Request) {
+	w.Header().Set("Cache-Control", "public, "+JWKS_MAX_AGE)
+	writeJson(w, http_base.StatusOK, s.am.Jwks())
+}
@@ -0,0 +1,252 @@
+package http
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"log/slog"
+	http_base "net/http"
+	"strings"
+
+	"github.com/JustDean/sam/pkg/auth"
+)
+
+// MAX_FORM_SIZE bounds the bodies of OAuth requests.
+const MAX_FORM_SIZE = 8 << 10
+
+// Error codes of OAuth responses (RFC 6749, section 5.2).
+const (
+	OAUTH_INVALID_REQUEST         = "invalid_request"
+	OAUTH_INVALID_CLIENT          = "invalid_client"
+	OAUTH_TEMPORARILY_UNAVAILABLE = "temporarily_unavailable"
+	OAUTH_SERVER_ERROR            = "server_error"
+)
+
+// ParseClients parses OAuth clients given as "id:secret" pairs separated
+// by commas.
+func ParseClients(s string) (map[string]string, error) {
+	clients := map[string]string{}
+	for _, pair := range strings.Split(s, ",") {
+		pair = strings.TrimSpace(pair)
+		if pair == "" {
+			continue
+		}
+		id, secret, ok := strings.Cut(pair, ":")
+		if !ok || id == "" || secret == "" {
+			return nil, fmt.Errorf("malformed client %q, expected id:secret", id)
+		}
+		if _, ok := clients[id]; ok {
+			return nil, fmt.Errorf("duplicate client %q", id)
+		}
+		clients[id] = secret
+	}
+	return clients, nil
+}
+
+type oauthError struct {
+	Error       string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+}
+
+// Introspection is the response of the introspection endpoint (RFC 7662).
+// Only Active is set for tokens that are unknown, expired or revoked.
+type Introspection struct {
+	Active bool `json:"active"`
+	// Principal is "user" for session ids and access tokens and "service"
+	// for service tokens.
+	Principal string `json:"principal,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	ClientId  string `json:"client_id,omitempty"`
+	Scope     string `json:"scope,omitempty"`
+	Subject   string `json:"sub,omitempty"`
+	Username  string `json:"username,omitempty"`
+	TenantId  string `json:"tenant_id,omitempty"`
+	SessionId string `json:"sid,omitempty"`
+	IssuedAt  int64  `json:"iat,omitempty"`
+	ExpiresAt int64  `json:"exp,omitempty"`
+}
+
+// oauthClients authenticates the callers of the OAuth endpoints, which
+// hold secrets shared with SAM. Only hashes of the secrets are kept.
+type oauthClients map[string][sha256.Size]byte
+
+func newOauthClients(clients map[string]string) oauthClients {
+	res := make(oauthClients, len(clients))
+	for id, secret := range clients {
+		res[id] = sha256.Sum256([]byte(secret))
+	}
+	return res
+}
+
+// clientCredentials returns the client credentials sent with HTTP Basic
+// authentication or in the form body.
+func clientCredentials(r *http_base.Request) (string, string) {
+	if id, secret, ok := r.BasicAuth(); ok {
+		return id, secret
+	}
+	return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+}
+
+// authenticate checks the client credentials, returning the client id.
+func (c oauthClients) authenticate(r *http_base.Request) (string, bool) {
+	id, secret := clientCredentials(r)
+	expected, known := c[id]
+	// Compare even for unknown clients so that timing does not reveal them.
+	sum := sha256.Sum256([]byte(secret))
+	if subtle.ConstantTimeCompare(sum[:], expected[:]) != 1 || !known || secret == "" {
+		return id, false
+	}
+	return id, true
+}
+
+// parseOauthForm parses the form of an OAuth request, writing the error
+// response and returning false if it is malformed. Responses are not cached.
+func parseOauthForm(w http_base.ResponseWriter, r *http_base.Request) bool {
+	w.Header().Set("Cache-Control", "no-store")
+	r.Body = http_base.MaxBytesReader(w, r.Body, MAX_FORM_SIZE)
+	if err := r.ParseForm(); err != nil {
+		writeJson(w, http_base.StatusBadRequest, oauthError{Error: OAUTH_INVALID_REQUEST, Description: "malformed form body"})
+		return false
+	}
+	return true
+}
+
+func writeInvalidClient(w http_base.ResponseWriter, r *http_base.Request, client string) {
+	slog.WarnContext(r.Context(), "Rejected OAuth client", "path", r.URL.Path, "client_id", client)
+	w.Header().Set("WWW-Authenticate", `Basic realm="sam"`)
+	writeJson(w, http_base.StatusUnauthorized, oauthError{Error: OAUTH_INVALID_CLIENT})
+}
+
+// oauthForm parses the form and authenticates the client, writing the
+// error response and returning false on failure.
+func (s *Server) oauthForm(w http_base.ResponseWriter, r *http_base.Request) (string, bool) {
+	if !parseOauthForm(w, r) {
+		return "", false
+	}
+	client, ok := s.clients.authenticate(r)
+	if !ok {
+		writeInvalidClient(w, r, client)
+		return "", false
+	}
+	if r.PostForm.Get("token") == "" {
+		writeJson(w, http_base.StatusBadRequest, oauthError{Error: OAUTH_INVALID_REQUEST, Description: "token is required"})
+		return "", false
+	}
+	return client, true
+}
+
+// writeOauthFailure answers failures of the backend, which must not be
+// mistaken for an inactive or unknown token.
+func writeOauthFailure(w http_base.ResponseWriter, r *http_base.Request, err error) {
+	slog.ErrorContext(r.Context(), "OAuth request failed", "path", r.URL.Path, "error", err)
+	if errors.Is(err, auth.ErrBackendUnavailable) {
+		writeJson(w, http_base.StatusServiceUnavailable, oauthError{Error: OAUTH_TEMPORARILY_UNAVAILABLE})
+		return
+	}
+	writeJson(w, http_base.StatusInternalServerError, oauthError{Error: OAUTH_SERVER_ERROR})
+}
+
+// inactiveToken tells errors meaning that a token no longer grants anything,
+// as opposed to failures to find out.
+func inactiveToken(err error) bool {
+	return errors.Is(err, auth.ErrSessionNotFound) || errors.Is(err, auth.ErrSessionExpired) ||
+		errors.Is(err, auth.ErrUserDisabled) || errors.Is(err, auth.ErrTenantDisabled)
+}
+
+// introspect tells whether a session id, an access token or a service token is active
+// (RFC 7662). Like Authenticate, it extends the idle deadline of sessions.
+func (s *Server) introspect(w http_base.ResponseWriter, r *http_base.Request) {
+	client, ok := s.oauthForm(w, r)
+	if !ok {
+		return
+	}
+	// token_type_hint may be ignored: the kind of token shows in its format.
+	token := r.PostForm.Get("token")
+	if strings.HasPrefix(token, auth.OIDC_ACCESS_TOKEN_PREFIX) {
+		s.introspectOidcAccessToken(w, r, client, token)
+		return
+	}
+	p, err := s.am.Authenticate(r.Context(), token)
+	if inactiveToken(err) {
+		slog.InfoContext(r.Context(), "Introspected inactive token", "client_id", client)
+		writeJson(w, http_base.StatusOK, Introspection{Active: false})
+		return
+	}
+	if err != nil {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	if p.Kind == auth.PRINCIPAL_SERVICE {
+		slog.InfoContext(r.Context(), "Introspected service token", "client_id", client, "service_id", p.Service.ClientId)
+		writeJson(w, http_base.StatusOK, Introspection{
+			Active:    true,
+			Principal: p.Kind,
+			TokenType: "Bearer",
+			ClientId:  p.Service.ClientId,
+			Scope:     strings.Join(p.Service.Scopes, " "),
+			Subject:   p.Service.ClientId,
+			IssuedAt:  p.Service.IssuedAt.Unix(),
+			ExpiresAt: p.Service.ExpiresAt.Unix(),
+		})
+		return
+	}
+	slog.InfoContext(r.Context(), "Introspected session", "client_id", client, "tenant_id", p.User.TenantId, "username", p.User.Username, "session_id", p.Session.Id)
+	writeJson(w, http_base.StatusOK, Introspection{
+		Active:    true,
+		Principal: p.Kind,
+		TokenType: "Bearer",
+		Subject:   p.User.Subject(),
+		Username:  p.User.Username,
+		TenantId:  p.User.TenantId,
+		SessionId: p.Session.Handle(),
+		IssuedAt:  p.Session.CreatedAt.Unix(),
+		ExpiresAt: p.Session.ValidThrough.Unix(),
+	})
+}
+
+// introspectOidcAccessToken describes an access token issued to a relying
+// party, which is not accepted by Authenticate.
+func (s *Server) introspectOidcAccessToken(w http_base.ResponseWriter, r *http_base.Request, client, token string) {
+	grant, user, session, err := s.am.GetOidcGrant(r.Context(), token)
+	if inactiveToken(err) {
+		slog.InfoContext(r.Context(), "Introspected inactive token", "client_id", client)
+		writeJson(w, http_base.StatusOK, Introspection{Active: false})
+		return
+	}
+	if err != nil {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Introspected OIDC access token", "client_id", client, "oidc_client_id", grant.ClientId, "tenant_id", user.TenantId, "username", user.Username)
+	writeJson(w, http_base.StatusOK, Introspection{
+		Active:    true,
+		Principal: auth.PRINCIPAL_USER,
+		TokenType: "Bearer",
+		ClientId:  grant.ClientId,
+		Scope:     grant.Scope,
+		Subject:   user.Subject(),
+		Username:  user.Username,
+		TenantId:  user.TenantId,
+		SessionId: session.Handle(),
+		IssuedAt:  grant.IssuedAt.Unix(),
+		ExpiresAt: grant.ExpiresAt.Unix(),
+	})
+}
+
+// revoke invalidates a session id, a service token or an access token issued
+// to a relying party (RFC 7009). Unknown tokens are not an error, so callers
+// learn nothing about them.
+func (s *Server) revoke(w http_base.ResponseWriter, r *http_base.Request) {
+	client, ok := s.oauthForm(w, r)
+	if !ok {
+		return
+	}
+	err := s.am.RevokeToken(r.Context(), r.PostForm.Get("token"))
+	if err != nil && !errors.Is(err, auth.ErrSessionNotFound) {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	slog.InfoContext(r.Context(), "Revoked token", "client_id", client)
+	w.WriteHeader(http_base.StatusOK)
+}
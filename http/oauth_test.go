@@ -0,0 +1,135 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	http_base "net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/JustDean/sam/pkg/auth"
+)
+
+const (
+	testClientId     = "gateway"
+	testClientSecret = "gateway-secret"
+)
+
+// newOauthTestServer returns a server with one OAuth client on top of an
+// in-memory AuthManager, along with the session of a user.
+func newOauthTestServer(t *testing.T) (*Server, auth.Session) {
+	t.Helper()
+	am, err := auth.SetAuthManager(auth.AuthManagerConfig{
+		Backend: auth.BACKEND_MEMORY,
+		Hasher:  auth.Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16},
+	})
+	if err != nil {
+		t.Fatalf("SetAuthManager: %v", err)
+	}
+	ctx := context.Background()
+	if _, err := am.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	session, _, err := am.LoginUser(ctx, "", "alice", "secret", auth.ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	s := &Server{am: am, clients: newOauthClients(map[string]string{testClientId: testClientSecret})}
+	return s, session
+}
+
+// postOauthForm calls an OAuth endpoint with the token, authenticated with
+// the client secret.
+func postOauthForm(handler http_base.HandlerFunc, secret, token string) *httptest.ResponseRecorder {
+	form := url.Values{"token": {token}}
+	r := httptest.NewRequest("POST", "/oauth", strings.NewReader(form.Encode()))
+	r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	r.SetBasicAuth(testClientId, secret)
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+func TestIntrospect(t *testing.T) {
+	s, session := newOauthTestServer(t)
+	tests := []struct {
+		name       string
+		secret     string
+		token      string
+		wantStatus int
+		wantActive bool
+	}{
+		{"session id", testClientSecret, session.Id, http_base.StatusOK, true},
+		{"unknown token", testClientSecret, "unknown", http_base.StatusOK, false},
+		{"wrong client secret", "wrong", session.Id, http_base.StatusUnauthorized, false},
+		{"no client secret", "", session.Id, http_base.StatusUnauthorized, false},
+		{"no token", testClientSecret, "", http_base.StatusBadRequest, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postOauthForm(s.introspect, tt.secret, tt.token)
+			if w.Code != tt.wantStatus {
+				t.Fatalf("got status %d, want %d: %s", w.Code, tt.wantStatus, w.Body)
+			}
+			if w.Code != http_base.StatusOK {
+				return
+			}
+			var res Introspection
+			if err := json.Unmarshal(w.Body.Bytes(), &res); err != nil {
+				t.Fatalf("decoding %s: %v", w.Body, err)
+			}
+			if res.Active != tt.wantActive {
+				t.Fatalf("got active %v, want %v", res.Active, tt.wantActive)
+			}
+			if res.Active && (res.Username != "alice" || res.SessionId != session.Handle()) {
+				t.Fatalf("got %+v, want the session of alice by its handle", res)
+			}
+			if !res.Active && res != (Introspection{}) {
+				t.Fatalf("got %+v for an inactive token, want active only", res)
+			}
+		})
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	s, session := newOauthTestServer(t)
+	if w := postOauthForm(s.revoke, "wrong", session.Id); w.Code != http_base.StatusUnauthorized {
+		t.Fatalf("revoke with a wrong client secret: got status %d, want 401", w.Code)
+	}
+	if w := postOauthForm(s.introspect, testClientSecret, session.Id); !strings.Contains(w.Body.String(), `"active":true`) {
+		t.Fatalf("a failed revocation revoked the session: %s", w.Body)
+	}
+	for _, token := range []string{session.Id, session.Id, "unknown"} {
+		if w := postOauthForm(s.revoke, testClientSecret, token); w.Code != http_base.StatusOK {
+			t.Fatalf("revoke %q: got status %d, want 200: %s", token, w.Code, w.Body)
+		}
+	}
+	if w := postOauthForm(s.introspect, testClientSecret, session.Id); !strings.Contains(w.Body.String(), `"active":false`) {
+		t.Fatalf("introspecting a revoked session: got %s, want inactive", w.Body)
+	}
+}
+
+func TestInactiveToken(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{auth.ErrSessionNotFound, true},
+		{auth.ErrSessionExpired, true},
+		{auth.ErrUserDisabled, true},
+		{fmt.Errorf("checking tenant: %w", auth.ErrTenantDisabled), true},
+		{auth.ErrBackendUnavailable, false},
+		{errors.New("boom"), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			if got := inactiveToken(tt.err); got != tt.want {
+				t.Fatalf("inactiveToken(%v): got %v, want %v", tt.err, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,321 @@
+package http
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	http_base "net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
+)
+
+// Error codes of authorization and token responses (RFC 6749).
+const (
+	OAUTH_INVALID_GRANT             = "invalid_grant"
+	OAUTH_INVALID_SCOPE             = "invalid_scope"
+	OAUTH_INVALID_TOKEN             = "invalid_token"
+	OAUTH_UNSUPPORTED_RESPONSE_TYPE = "unsupported_response_type"
+)
+
+// loginPageHeaders keep the hosted login form out of caches and frames.
+var loginPageHeaders = map[string]string{
+	"Cache-Control":           "no-store",
+	"X-Frame-Options":         "DENY",
+	"Content-Security-Policy": "default-src 'none'; style-src 'unsafe-inline'; frame-ancestors 'none'",
+	"Referrer-Policy":         "no-referrer",
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>Sign in</title>
+<style>
+body { font-family: sans-serif; max-width: 22em; margin: 4em auto; padding: 0 1em; }
+input { display: block; width: 100%; margin: .3em 0 1em; padding: .4em; box-sizing: border-box; }
+.error { color: #b00020; }
+</style>
+</head>
+<body>
+{{if .Fatal}}
+<h1>Cannot sign in</h1>
+<p class="error">{{.Error}}</p>
+{{else}}
+<h1>Sign in to {{.ClientName}}</h1>
+{{if .Error}}<p class="error">{{.Error}}</p>{{end}}
+<form method="post" action="/authorize">
+{{range $name, $value := .Params}}<input type="hidden" name="{{$name}}" value="{{$value}}">
+{{end}}
+{{if .Challenge}}
+<input type="hidden" name="mfa_challenge" value="{{.Challenge}}">
+<label>Authentication code<input name="mfa_code" autocomplete="one-time-code" autofocus required></label>
+{{else}}
+<label>Username<input name="username" value="{{.Username}}" autocomplete="username" autofocus required></label>
+<label>Password<input name="password" type="password" autocomplete="current-password" required></label>
+{{end}}
+<input type="submit" value="Sign in">
+</form>
+{{end}}
+</body>
+</html>
+`))
+
+// authorizeParams are carried through the login form.
+var authorizeParams = []string{
+	"response_type", "client_id", "redirect_uri", "scope", "state", "nonce",
+	"code_challenge", "code_challenge_method", "tenant_id",
+}
+
+type loginPageData struct {
+	Fatal      bool
+	Error      string
+	ClientName string
+	Params     map[string]string
+	Username   string
+	Challenge  string
+}
+
+func writeLoginPage(w http_base.ResponseWriter, status int, data loginPageData) {
+	for k, v := range loginPageHeaders {
+		w.Header().Set(k, v)
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := loginPage.Execute(w, data); err != nil {
+		slog.Error("Error writing login page", "error", err)
+	}
+}
+
+func writeFatalLoginPage(w http_base.ResponseWriter, status int, message string) {
+	writeLoginPage(w, status, loginPageData{Fatal: true, Error: message})
+}
+
+// Discovery is the OpenID Provider Metadata document.
+type Discovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserinfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JwksUri                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IdTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+	AuthorizationResponseIssParameter bool     `json:"authorization_response_iss_parameter_supported"`
+}
+
+func (s *Server) discovery(w http_base.ResponseWriter, r *http_base.Request) {
+	issuer := s.am.OidcIssuer()
+	w.Header().Set("Cache-Control", "public, "+JWKS_MAX_AGE)
+	writeJson(w, http_base.StatusOK, Discovery{
+		Issuer:                            issuer,
+		AuthorizationEndpoint:             issuer + "/authorize",
+		TokenEndpoint:                     issuer + "/token",
+		UserinfoEndpoint:                  issuer + "/userinfo",
+		JwksUri:                           issuer + "/.well-known/jwks.json",
+		ScopesSupported:                   auth.SupportedScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{GRANT_AUTHORIZATION_CODE, GRANT_CLIENT_CREDENTIALS},
+		SubjectTypesSupported:             []string{"public"},
+		IdTokenSigningAlgValuesSupported:  []string{auth.ALGORITHM_EDDSA, auth.ALGORITHM_ES256, auth.ALGORITHM_RS256},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{auth.PKCE_METHOD_S256},
+		ClaimsSupported:                   []string{"iss", "sub", "aud", "exp", "iat", "auth_time", "nonce", "sid", "preferred_username", "name", "email", "email_verified"},
+		AuthorizationResponseIssParameter: true,
+	})
+}
+
+// redirectToClient sends the outcome of an authorization request to the
+// client, along with its state and our issuer (RFC 9207).
+func (s *Server) redirectToClient(w http_base.ResponseWriter, r *http_base.Request, params url.Values) {
+	u, _ := url.Parse(r.Form.Get("redirect_uri"))
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	if state := r.Form.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	q.Set("iss", s.am.OidcIssuer())
+	u.RawQuery = q.Encode()
+	w.Header().Set("Cache-Control", "no-store")
+	http_base.Redirect(w, r, u.String(), http_base.StatusSeeOther)
+}
+
+func (s *Server) redirectError(w http_base.ResponseWriter, r *http_base.Request, code, description string) {
+	s.redirectToClient(w, r, url.Values{"error": {code}, "error_description": {description}})
+}
+
+// authorize serves the login form of the authorization code flow and, once
+// the user signed in, redirects to the client with a code.
+func (s *Server) authorize(w http_base.ResponseWriter, r *http_base.Request) {
+	r.Body = http_base.MaxBytesReader(w, r.Body, MAX_FORM_SIZE)
+	if err := r.ParseForm(); err != nil {
+		writeFatalLoginPage(w, http_base.StatusBadRequest, "Malformed request.")
+		return
+	}
+	ctx := r.Context()
+	req := auth.AuthorizationRequest{
+		ClientId:            r.Form.Get("client_id"),
+		RedirectUri:         r.Form.Get("redirect_uri"),
+		Scope:               r.Form.Get("scope"),
+		Nonce:               r.Form.Get("nonce"),
+		CodeChallenge:       r.Form.Get("code_challenge"),
+		CodeChallengeMethod: r.Form.Get("code_challenge_method"),
+	}
+	client, err := s.am.CheckAuthorizationRequest(ctx, req)
+	switch {
+	case errors.Is(err, auth.ErrClientNotFound):
+		writeFatalLoginPage(w, http_base.StatusBadRequest, "Unknown application.")
+		return
+	case errors.Is(err, auth.ErrInvalidRedirectUri):
+		writeFatalLoginPage(w, http_base.StatusBadRequest, "The application sent an invalid redirect address.")
+		return
+	case errors.Is(err, auth.ErrInvalidScope):
+		s.redirectError(w, r, OAUTH_INVALID_SCOPE, err.Error())
+		return
+	case errors.Is(err, auth.ErrInvalidArgument):
+		s.redirectError(w, r, OAUTH_INVALID_REQUEST, err.Error())
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error checking authorization request", "client_id", req.ClientId, "error", err)
+		writeFatalLoginPage(w, http_base.StatusServiceUnavailable, "Sign-in is unavailable, try again later.")
+		return
+	}
+	if r.Form.Get("response_type") != "code" {
+		s.redirectError(w, r, OAUTH_UNSUPPORTED_RESPONSE_TYPE, "response_type must be code")
+		return
+	}
+	page := loginPageData{ClientName: client.Name, Params: map[string]string{}}
+	for _, name := range authorizeParams {
+		page.Params[name] = r.Form.Get(name)
+	}
+	if r.Method != http_base.MethodPost {
+		writeLoginPage(w, http_base.StatusOK, page)
+		return
+	}
+	var session auth.Session
+	var challenge *auth.MfaChallenge
+	if c := r.PostForm.Get("mfa_challenge"); c != "" {
+		session, err = s.am.VerifyMfa(ctx, c, r.PostForm.Get("mfa_code"))
+		if errors.Is(err, auth.ErrInvalidMfaCode) {
+			page.Challenge = c
+		}
+	} else {
+		page.Username = r.PostForm.Get("username")
+		session, challenge, err = s.am.LoginUser(ctx, r.Form.Get("tenant_id"), page.Username, r.PostForm.Get("password"), s.clientInfo(r, client.Name))
+	}
+	var rateLimited *auth.RateLimitedError
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials), errors.Is(err, auth.ErrInvalidArgument):
+		page.Error = "Invalid username or password."
+		writeLoginPage(w, http_base.StatusUnauthorized, page)
+		return
+	case errors.Is(err, auth.ErrTenantDisabled):
+		page.Error = "This organization is disabled."
+		writeLoginPage(w, http_base.StatusForbidden, page)
+		return
+	case errors.Is(err, auth.ErrEmailNotVerified):
+		page.Error = "Confirm your email address before signing in."
+		writeLoginPage(w, http_base.StatusForbidden, page)
+		return
+	case errors.Is(err, auth.ErrInvalidMfaCode):
+		page.Error = "Invalid authentication code."
+		writeLoginPage(w, http_base.StatusUnauthorized, page)
+		return
+	case errors.Is(err, auth.ErrMfaChallengeNotFound):
+		page.Error = "Sign-in expired, start again."
+		writeLoginPage(w, http_base.StatusUnauthorized, page)
+		return
+	case errors.As(err, &rateLimited):
+		page.Error = "Too many attempts, try again later."
+		w.Header().Set("Retry-After", retryAfterSeconds(rateLimited.RetryAfter))
+		writeLoginPage(w, http_base.StatusTooManyRequests, page)
+		return
+	case err != nil:
+		slog.ErrorContext(ctx, "Error signing in", "client_id", client.Id, "error", err)
+		writeFatalLoginPage(w, http_base.StatusServiceUnavailable, "Sign-in is unavailable, try again later.")
+		return
+	case challenge != nil:
+		page.Challenge = challenge.Token
+		writeLoginPage(w, http_base.StatusOK, page)
+		return
+	}
+	code, err := s.am.IssueAuthorizationCode(ctx, req, session)
+	if err != nil {
+		slog.ErrorContext(ctx, "Error issuing authorization code", "client_id", client.Id, "error", err)
+		s.redirectError(w, r, OAUTH_SERVER_ERROR, "sign-in failed")
+		return
+	}
+	slog.InfoContext(ctx, "Authorized client", "client_id", client.Id, "tenant_id", session.TenantId, "username", session.Username, "session_id", session.Id)
+	s.redirectToClient(w, r, url.Values{"code": {code}})
+}
+
+// retryAfterSeconds rounds up so that clients never retry too early.
+func retryAfterSeconds(d time.Duration) string {
+	return strconv.FormatInt(int64((d+time.Second-1)/time.Second), 10)
+}
+
+// authorizationCodeGrant exchanges an authorization code for tokens.
+func (s *Server) authorizationCodeGrant(w http_base.ResponseWriter, r *http_base.Request) {
+	ctx := r.Context()
+	id, secret := clientCredentials(r)
+	client, err := s.am.AuthenticateClient(ctx, id, secret)
+	if errors.Is(err, auth.ErrInvalidClient) {
+		writeInvalidClient(w, r, id)
+		return
+	}
+	if err != nil {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	tokens, err := s.am.ExchangeAuthorizationCode(ctx, client,
+		r.PostForm.Get("code"), r.PostForm.Get("redirect_uri"), r.PostForm.Get("code_verifier"))
+	if errors.Is(err, auth.ErrInvalidGrant) {
+		slog.WarnContext(ctx, "Rejected authorization code", "client_id", client.Id)
+		writeJson(w, http_base.StatusBadRequest, oauthError{Error: OAUTH_INVALID_GRANT})
+		return
+	}
+	if err != nil {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	writeJson(w, http_base.StatusOK, TokenResponse{
+		AccessToken: tokens.AccessToken,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(tokens.ExpiresAt).Seconds()),
+		IdToken:     tokens.IdToken,
+		Scope:       tokens.Scope,
+	})
+}
+
+// userinfo returns the claims about the user of a bearer access token.
+func (s *Server) userinfo(w http_base.ResponseWriter, r *http_base.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || token == "" {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sam"`)
+		w.WriteHeader(http_base.StatusUnauthorized)
+		return
+	}
+	info, err := s.am.GetUserInfo(r.Context(), token)
+	if inactiveToken(err) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="sam", error="`+OAUTH_INVALID_TOKEN+`"`)
+		w.WriteHeader(http_base.StatusUnauthorized)
+		return
+	}
+	if err != nil {
+		writeOauthFailure(w, r, err)
+		return
+	}
+	writeJson(w, http_base.StatusOK, info)
+}
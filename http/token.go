@@ -0,0 +1,69 @@
+package http
+
+import (
+	"errors"
+	"log/slog"
+	http_base "net/http"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/auth"
+)
+
+// Grant types of the token endpoint.
+const (
+	GRANT_AUTHORIZATION_CODE = "authorization_code"
+	GRANT_CLIENT_CREDENTIALS = "client_credentials"
+)
+
+const OAUTH_UNSUPPORTED_GRANT_TYPE = "unsupported_grant_type"
+
+// TokenResponse is the successful response of the token endpoint.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	IdToken     string `json:"id_token,omitempty"`
+	Scope       string `json:"scope"`
+}
+
+// token issues tokens for the grant of the request. The authorization
+// code grant is only available if OIDC is enabled.
+func (s *Server) token(w http_base.ResponseWriter, r *http_base.Request) {
+	if !parseOauthForm(w, r) {
+		return
+	}
+	switch grant := r.PostForm.Get("grant_type"); {
+	case grant == GRANT_CLIENT_CREDENTIALS:
+		s.clientCredentialsGrant(w, r)
+	case grant == GRANT_AUTHORIZATION_CODE && s.am.OidcIssuer() != "":
+		s.authorizationCodeGrant(w, r)
+	default:
+		writeJson(w, http_base.StatusBadRequest, oauthError{Error: OAUTH_UNSUPPORTED_GRANT_TYPE})
+	}
+}
+
+// clientCredentialsGrant issues a service token to a service account.
+func (s *Server) clientCredentialsGrant(w http_base.ResponseWriter, r *http_base.Request) {
+	ctx := r.Context()
+	id, secret := clientCredentials(r)
+	t, err := s.am.IssueServiceToken(ctx, id, secret, r.PostForm.Get("scope"))
+	switch {
+	case errors.Is(err, auth.ErrInvalidClient):
+		writeInvalidClient(w, r, id)
+		return
+	case errors.Is(err, auth.ErrInvalidScope):
+		slog.WarnContext(ctx, "Rejected service token scope", "client_id", id, "error", err)
+		writeJson(w, http_base.StatusBadRequest, oauthError{Error: OAUTH_INVALID_SCOPE, Description: err.Error()})
+		return
+	case err != nil:
+		writeOauthFailure(w, r, err)
+		return
+	}
+	writeJson(w, http_base.StatusOK, TokenResponse{
+		AccessToken: t.Token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(time.Until(t.ExpiresAt).Seconds()),
+		Scope:       strings.Join(t.Scopes, " "),
+	})
+}
@@ -2,22 +2,58 @@ package main
 
 import (
 	"context"
-	"log"
+	"log/slog"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/JustDean/sam/grpc"
+	"github.com/JustDean/sam/http"
 	"github.com/JustDean/sam/pkg/auth"
+	"github.com/JustDean/sam/pkg/health"
+	"github.com/JustDean/sam/pkg/logging"
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/notify"
 	"github.com/JustDean/sam/pkg/postgres"
 	"github.com/JustDean/sam/pkg/redis"
 	"github.com/JustDean/sam/pkg/utils"
 )
 
 func main() {
-	log.Println("Starting the app")
+	if _, err := logging.Setup(logging.Config{
+		Format: utils.GetEnv("LOG_FORMAT", logging.FORMAT_JSON),
+		Level:  utils.GetEnv("LOG_LEVEL", "info"),
+	}); err != nil {
+		fatal("Error setting logger", err)
+	}
+	slog.Info("Starting the app")
+	hasher, err := auth.NewPasswordHasher(utils.GetEnv("PASSWORD_HASHER", auth.HASHER_ARGON2ID))
+	if err != nil {
+		fatal("Error setting password hasher", err)
+	}
+	masterKey, err := auth.LoadMasterKey(utils.GetEnv("MASTER_KEY_FILE", ""), utils.GetEnv("MASTER_KEY", ""))
+	if err != nil {
+		fatal("Error loading master key", err)
+	}
+	notifier, err := notify.SetNotifier(notify.Config{
+		Backend: utils.GetEnv("NOTIFIER", notify.BACKEND_NONE),
+		File:    utils.GetEnv("NOTIFIER_FILE", ""),
+		Smtp: notify.SmtpConfig{
+			Host:     utils.GetEnv("SMTP_HOST", "localhost"),
+			Port:     utils.GetEnv("SMTP_PORT", "25"),
+			Username: utils.GetEnv("SMTP_USERNAME", ""),
+			Password: utils.GetEnv("SMTP_PASSWORD", ""),
+			From:     utils.GetEnv("SMTP_FROM", ""),
+			Domain:   utils.GetEnv("SMTP_DOMAIN", ""),
+		},
+	})
+	if err != nil {
+		fatal("Error setting notifier", err)
+	}
 	authManager, err := auth.SetAuthManager(auth.AuthManagerConfig{
+		Backend: utils.GetEnv("STORAGE_BACKEND", auth.BACKEND_POSTGRES),
 		Db: postgres.Config{
 			Host:     utils.GetEnv("DB_HOST", "localhost"),
 			Port:     utils.GetEnv("DB_PORT", "5432"),
@@ -31,16 +67,114 @@ func main() {
 			Password: utils.GetEnv("CACHE_PASSWORD", ""),
 			Db:       utils.GetEnv("CACHE_DB", "1"),
 		},
+		Hasher: hasher,
+		Session: auth.SessionConfig{
+			AbsoluteTimeout: utils.GetEnvDuration("SESSION_ABSOLUTE_TIMEOUT", 10*24*time.Hour),
+			IdleTimeout:     utils.GetEnvDuration("SESSION_IDLE_TIMEOUT", 0),
+			TouchInterval:   utils.GetEnvDuration("SESSION_TOUCH_INTERVAL", time.Minute),
+		},
+		Limiter: auth.LimiterConfig{
+			Window:           utils.GetEnvDuration("LOGIN_WINDOW", time.Minute),
+			UserLimit:        utils.GetEnvInt("LOGIN_USER_LIMIT", 10),
+			IpLimit:          utils.GetEnvInt("LOGIN_IP_LIMIT", 50),
+			LockoutThreshold: utils.GetEnvInt("LOGIN_LOCKOUT_THRESHOLD", 5),
+			LockoutBase:      utils.GetEnvDuration("LOGIN_LOCKOUT_BASE", 30*time.Second),
+			LockoutMax:       utils.GetEnvDuration("LOGIN_LOCKOUT_MAX", time.Hour),
+		},
+		Mfa: auth.MfaConfig{
+			Issuer:       utils.GetEnv("MFA_ISSUER", "SAM"),
+			ChallengeTtl: utils.GetEnvDuration("MFA_CHALLENGE_TTL", 5*time.Minute),
+		},
+		Notifier: notifier,
+		PasswordReset: auth.PasswordResetConfig{
+			TokenTtl: utils.GetEnvDuration("PASSWORD_RESET_TTL", time.Hour),
+			Link:     utils.GetEnv("PASSWORD_RESET_LINK", ""),
+		},
+		EmailVerification: auth.EmailVerificationConfig{
+			Enabled:          utils.GetEnv("EMAIL_VERIFICATION_ENABLED", "false") == "true",
+			TokenTtl:         utils.GetEnvDuration("EMAIL_VERIFICATION_TTL", 24*time.Hour),
+			Link:             utils.GetEnv("EMAIL_VERIFICATION_LINK", ""),
+			UnverifiedPolicy: utils.GetEnv("UNVERIFIED_LOGIN_POLICY", auth.UNVERIFIED_ALLOW),
+		},
+		Passwordless: auth.PasswordlessConfig{
+			Enabled:     utils.GetEnv("PASSWORDLESS_ENABLED", "false") == "true",
+			CodeLength:  utils.GetEnvInt("PASSWORDLESS_CODE_LENGTH", 6),
+			Ttl:         utils.GetEnvDuration("PASSWORDLESS_TTL", 10*time.Minute),
+			MaxAttempts: utils.GetEnvInt("PASSWORDLESS_MAX_ATTEMPTS", 5),
+			Link:        utils.GetEnv("PASSWORDLESS_LINK", ""),
+		},
+		Webauthn: auth.WebauthnConfig{
+			Enabled:                 utils.GetEnv("WEBAUTHN_ENABLED", "false") == "true",
+			RpId:                    utils.GetEnv("WEBAUTHN_RP_ID", ""),
+			RpName:                  utils.GetEnv("WEBAUTHN_RP_NAME", "SAM"),
+			Origins:                 utils.GetEnvList("WEBAUTHN_ORIGINS"),
+			ChallengeTtl:            utils.GetEnvDuration("WEBAUTHN_CHALLENGE_TTL", 5*time.Minute),
+			RequireUserVerification: utils.GetEnv("WEBAUTHN_REQUIRE_USER_VERIFICATION", "false") == "true",
+		},
+		AccessToken: auth.AccessTokenConfig{
+			Enabled:   utils.GetEnv("ACCESS_TOKEN_ENABLED", "false") == "true",
+			Ttl:       utils.GetEnvDuration("ACCESS_TOKEN_TTL", 5*time.Minute),
+			Issuer:    utils.GetEnv("ACCESS_TOKEN_ISSUER", "sam"),
+			Audience:  utils.GetEnv("ACCESS_TOKEN_AUDIENCE", ""),
+			KeyFile:   utils.GetEnv("ACCESS_TOKEN_KEY_FILE", ""),
+			Algorithm: utils.GetEnv("ACCESS_TOKEN_ALGORITHM", ""),
+		},
+		SigningKeys: auth.SigningKeyConfig{
+			Algorithm:        utils.GetEnv("SIGNING_KEY_ALGORITHM", ""),
+			MasterKey:        masterKey,
+			RotationInterval: utils.GetEnvDuration("SIGNING_KEY_ROTATION_INTERVAL", 30*24*time.Hour),
+			PublishDelay:     utils.GetEnvDuration("SIGNING_KEY_PUBLISH_DELAY", 10*time.Minute),
+			Overlap:          utils.GetEnvDuration("SIGNING_KEY_OVERLAP", time.Hour),
+			RefreshInterval:  utils.GetEnvDuration("SIGNING_KEY_REFRESH_INTERVAL", time.Minute),
+		},
+		Oidc: auth.OidcConfig{
+			Enabled:        utils.GetEnv("OIDC_ENABLED", "false") == "true",
+			Issuer:         utils.GetEnv("OIDC_ISSUER", ""),
+			CodeTtl:        utils.GetEnvDuration("OIDC_CODE_TTL", time.Minute),
+			IdTokenTtl:     utils.GetEnvDuration("OIDC_ID_TOKEN_TTL", time.Hour),
+			AccessTokenTtl: utils.GetEnvDuration("OIDC_ACCESS_TOKEN_TTL", 10*time.Minute),
+		},
+		ServiceToken: auth.ServiceTokenConfig{
+			Ttl: utils.GetEnvDuration("SERVICE_TOKEN_TTL", time.Hour),
+		},
+		Rbac: auth.RbacConfig{
+			RoleCacheTtl: utils.GetEnvDuration("ROLE_CACHE_TTL", 5*time.Minute),
+		},
 	})
 	if err != nil {
-		log.Fatalf("Error setting Auth Manager %v", err)
+		fatal("Error setting Auth Manager", err)
 	}
+	metrics.Registry.MustRegister(authManager.Collectors()...)
+	metricsServer, err := metrics.SetServer(metrics.Config{
+		Host: utils.GetEnv("METRICS_HOST", "localhost"),
+		Port: utils.GetEnv("METRICS_PORT", "9091"),
+	})
+	if err != nil {
+		fatal("Error setting metrics server", err)
+	}
+	checker := health.NewChecker(authManager.Ping, utils.GetEnvDuration("HEALTH_CHECK_INTERVAL", 10*time.Second))
+	trustProxy := utils.GetEnv("SERVER_TRUST_PROXY", "false") == "true"
 	server, err := grpc.SetServer(grpc.Config{
-		Host: utils.GetEnv("SERVER_HOST", "localhost"),
-		Port: utils.GetEnv("SERVER_PORT", "9999"),
-	}, authManager)
+		Host:       utils.GetEnv("SERVER_HOST", "localhost"),
+		Port:       utils.GetEnv("SERVER_PORT", "9999"),
+		TrustProxy: trustProxy,
+		AdminToken: utils.GetEnv("ADMIN_TOKEN", ""),
+	}, authManager, checker)
 	if err != nil {
-		log.Fatalf("Error setting gRPC server %v", err)
+		fatal("Error setting gRPC server", err)
+	}
+	oauthClients, err := http.ParseClients(utils.GetEnv("OAUTH_CLIENTS", ""))
+	if err != nil {
+		fatal("Error parsing OAuth clients", err)
+	}
+	httpServer, err := http.SetServer(http.Config{
+		Host:       utils.GetEnv("HTTP_HOST", "localhost"),
+		Port:       utils.GetEnv("HTTP_PORT", "8080"),
+		TrustProxy: trustProxy,
+		Clients:    oauthClients,
+	}, authManager, checker)
+	if err != nil {
+		fatal("Error setting HTTP server", err)
 	}
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
@@ -55,6 +189,26 @@ func main() {
 		defer wg.Done()
 		server.Run(ctx)
 	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		metricsServer.Run(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		httpServer.Run(ctx)
+	}()
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		checker.Run(ctx)
+	}()
 	wg.Wait()
-	log.Println("Service is shut down.")
+	slog.Info("Service is shut down.")
+}
+
+func fatal(msg string, err error) {
+	slog.Error(msg, "error", err)
+	os.Exit(1)
 }
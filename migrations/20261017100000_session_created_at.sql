@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now();
+CREATE INDEX sessions_username_valid_through_idx ON sessions (username, valid_through);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX sessions_username_valid_through_idx;
+ALTER TABLE sessions DROP COLUMN created_at;
+-- +goose StatementEnd
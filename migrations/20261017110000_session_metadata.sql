@@ -0,0 +1,17 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions
+    ADD COLUMN last_seen_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN client_ip VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN user_agent VARCHAR(512) NOT NULL DEFAULT '',
+    ADD COLUMN device_name VARCHAR(64) NOT NULL DEFAULT '';
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions
+    DROP COLUMN device_name,
+    DROP COLUMN user_agent,
+    DROP COLUMN client_ip,
+    DROP COLUMN last_seen_at;
+-- +goose StatementEnd
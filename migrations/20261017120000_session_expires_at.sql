@@ -0,0 +1,11 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE sessions ADD COLUMN expires_at TIMESTAMPTZ;
+UPDATE sessions SET expires_at = valid_through;
+ALTER TABLE sessions ALTER COLUMN expires_at SET NOT NULL;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE sessions DROP COLUMN expires_at;
+-- +goose StatementEnd
@@ -0,0 +1,9 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE INDEX sessions_valid_through_idx ON sessions (valid_through);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP INDEX sessions_valid_through_idx;
+-- +goose StatementEnd
@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE user_mfa (
+    username VARCHAR(32) PRIMARY KEY REFERENCES users (username) ON DELETE CASCADE,
+    secret VARCHAR NOT NULL,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    enabled_at TIMESTAMPTZ
+);
+CREATE TABLE mfa_recovery_codes (
+    username VARCHAR(32) REFERENCES user_mfa (username) ON DELETE CASCADE NOT NULL,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMPTZ,
+    PRIMARY KEY (username, code_hash)
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE mfa_recovery_codes;
+DROP TABLE user_mfa;
+-- +goose StatementEnd
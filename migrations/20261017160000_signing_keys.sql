@@ -0,0 +1,16 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE signing_keys (
+    kid VARCHAR(64) PRIMARY KEY,
+    algorithm VARCHAR(16) NOT NULL,
+    encrypted_key BYTEA NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    activates_at TIMESTAMPTZ NOT NULL,
+    revoked_at TIMESTAMPTZ
+);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE signing_keys;
+-- +goose StatementEnd
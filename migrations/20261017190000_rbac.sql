@@ -0,0 +1,28 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE roles (
+    name VARCHAR(64) PRIMARY KEY,
+    description VARCHAR(256) NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL
+);
+CREATE TABLE role_permissions (
+    role VARCHAR(64) REFERENCES roles (name) ON DELETE CASCADE NOT NULL,
+    permission VARCHAR(128) NOT NULL,
+    resource VARCHAR(256) NOT NULL DEFAULT '*',
+    PRIMARY KEY (role, permission, resource)
+);
+CREATE TABLE user_roles (
+    username VARCHAR(32) REFERENCES users (username) ON DELETE CASCADE NOT NULL,
+    role VARCHAR(64) REFERENCES roles (name) ON DELETE CASCADE NOT NULL,
+    granted_at TIMESTAMPTZ NOT NULL,
+    PRIMARY KEY (username, role)
+);
+CREATE INDEX user_roles_role_idx ON user_roles (role);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE user_roles;
+DROP TABLE role_permissions;
+DROP TABLE roles;
+-- +goose StatementEnd
@@ -0,0 +1,90 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE tenants (
+    id VARCHAR(32) PRIMARY KEY,
+    name VARCHAR(64) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    disabled_at TIMESTAMPTZ
+);
+INSERT INTO tenants (id, name, created_at) VALUES ('default', 'Default', now());
+
+-- Existing rows belong to the default tenant, as do rows inserted by
+-- instances not yet upgraded.
+ALTER TABLE users ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default' REFERENCES tenants (id);
+ALTER TABLE sessions ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE user_mfa ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE mfa_recovery_codes ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE password_reset_tokens ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+ALTER TABLE user_roles ADD COLUMN tenant_id VARCHAR(32) NOT NULL DEFAULT 'default';
+
+-- Usernames are unique per tenant: every reference to a user now goes
+-- through the tenant as well.
+ALTER TABLE sessions DROP CONSTRAINT sessions_username_fkey;
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_username_fkey;
+ALTER TABLE user_mfa DROP CONSTRAINT user_mfa_username_fkey;
+ALTER TABLE password_reset_tokens DROP CONSTRAINT password_reset_tokens_username_fkey;
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_username_fkey;
+
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD PRIMARY KEY (tenant_id, username);
+ALTER TABLE user_mfa DROP CONSTRAINT user_mfa_pkey;
+ALTER TABLE user_mfa ADD PRIMARY KEY (tenant_id, username);
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_pkey;
+ALTER TABLE mfa_recovery_codes ADD PRIMARY KEY (tenant_id, username, code_hash);
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles ADD PRIMARY KEY (tenant_id, username, role);
+
+ALTER TABLE sessions ADD FOREIGN KEY (tenant_id, username) REFERENCES users (tenant_id, username);
+ALTER TABLE user_mfa ADD FOREIGN KEY (tenant_id, username) REFERENCES users (tenant_id, username) ON DELETE CASCADE;
+ALTER TABLE mfa_recovery_codes ADD FOREIGN KEY (tenant_id, username) REFERENCES user_mfa (tenant_id, username) ON DELETE CASCADE;
+ALTER TABLE password_reset_tokens ADD FOREIGN KEY (tenant_id, username) REFERENCES users (tenant_id, username) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD FOREIGN KEY (tenant_id, username) REFERENCES users (tenant_id, username) ON DELETE CASCADE;
+
+DROP INDEX sessions_username_valid_through_idx;
+CREATE INDEX sessions_tenant_username_valid_through_idx ON sessions (tenant_id, username, valid_through);
+DROP INDEX password_reset_tokens_username_idx;
+CREATE INDEX password_reset_tokens_tenant_username_idx ON password_reset_tokens (tenant_id, username);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DELETE FROM sessions WHERE tenant_id <> 'default';
+DELETE FROM password_reset_tokens WHERE tenant_id <> 'default';
+DELETE FROM user_roles WHERE tenant_id <> 'default';
+DELETE FROM user_mfa WHERE tenant_id <> 'default';
+DELETE FROM users WHERE tenant_id <> 'default';
+
+DROP INDEX password_reset_tokens_tenant_username_idx;
+CREATE INDEX password_reset_tokens_username_idx ON password_reset_tokens (username);
+DROP INDEX sessions_tenant_username_valid_through_idx;
+CREATE INDEX sessions_username_valid_through_idx ON sessions (username, valid_through);
+
+ALTER TABLE sessions DROP CONSTRAINT sessions_tenant_id_username_fkey;
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_tenant_id_username_fkey;
+ALTER TABLE user_mfa DROP CONSTRAINT user_mfa_tenant_id_username_fkey;
+ALTER TABLE password_reset_tokens DROP CONSTRAINT password_reset_tokens_tenant_id_username_fkey;
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_tenant_id_username_fkey;
+
+ALTER TABLE user_roles DROP CONSTRAINT user_roles_pkey;
+ALTER TABLE user_roles ADD PRIMARY KEY (username, role);
+ALTER TABLE mfa_recovery_codes DROP CONSTRAINT mfa_recovery_codes_pkey;
+ALTER TABLE mfa_recovery_codes ADD PRIMARY KEY (username, code_hash);
+ALTER TABLE user_mfa DROP CONSTRAINT user_mfa_pkey;
+ALTER TABLE user_mfa ADD PRIMARY KEY (username);
+ALTER TABLE users DROP CONSTRAINT users_pkey;
+ALTER TABLE users ADD PRIMARY KEY (username);
+
+ALTER TABLE sessions ADD FOREIGN KEY (username) REFERENCES users (username);
+ALTER TABLE user_mfa ADD FOREIGN KEY (username) REFERENCES users (username) ON DELETE CASCADE;
+ALTER TABLE mfa_recovery_codes ADD FOREIGN KEY (username) REFERENCES user_mfa (username) ON DELETE CASCADE;
+ALTER TABLE password_reset_tokens ADD FOREIGN KEY (username) REFERENCES users (username) ON DELETE CASCADE;
+ALTER TABLE user_roles ADD FOREIGN KEY (username) REFERENCES users (username) ON DELETE CASCADE;
+
+ALTER TABLE user_roles DROP COLUMN tenant_id;
+ALTER TABLE password_reset_tokens DROP COLUMN tenant_id;
+ALTER TABLE mfa_recovery_codes DROP COLUMN tenant_id;
+ALTER TABLE user_mfa DROP COLUMN tenant_id;
+ALTER TABLE sessions DROP COLUMN tenant_id;
+ALTER TABLE users DROP COLUMN tenant_id;
+DROP TABLE tenants;
+-- +goose StatementEnd
@@ -0,0 +1,19 @@
+-- +goose Up
+-- +goose StatementBegin
+ALTER TABLE users
+    ADD COLUMN email VARCHAR(254) NOT NULL DEFAULT '',
+    ADD COLUMN display_name VARCHAR(64) NOT NULL DEFAULT '',
+    ADD COLUMN attributes JSONB NOT NULL DEFAULT '{}',
+    ADD COLUMN created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+    ADD COLUMN updated_at TIMESTAMPTZ NOT NULL DEFAULT now();
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+ALTER TABLE users
+    DROP COLUMN updated_at,
+    DROP COLUMN created_at,
+    DROP COLUMN attributes,
+    DROP COLUMN display_name,
+    DROP COLUMN email;
+-- +goose StatementEnd
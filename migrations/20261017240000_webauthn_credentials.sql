@@ -0,0 +1,22 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE TABLE webauthn_credentials (
+    tenant_id VARCHAR(32) NOT NULL,
+    id BYTEA NOT NULL,
+    username VARCHAR(32) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    public_key BYTEA NOT NULL,
+    sign_count BIGINT NOT NULL DEFAULT 0,
+    attestation_format VARCHAR(16) NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    last_used_at TIMESTAMPTZ,
+    PRIMARY KEY (tenant_id, id),
+    FOREIGN KEY (tenant_id, username) REFERENCES users (tenant_id, username) ON DELETE CASCADE
+);
+CREATE INDEX webauthn_credentials_tenant_username_idx ON webauthn_credentials (tenant_id, username);
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+DROP TABLE webauthn_credentials;
+-- +goose StatementEnd
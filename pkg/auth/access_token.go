@@ -0,0 +1,359 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Signing algorithms of access tokens.
+const (
+	ALGORITHM_EDDSA = "EdDSA"
+	ALGORITHM_ES256 = "ES256"
+	ALGORITHM_RS256 = "RS256"
+
+	rsaKeyBits = 2048
+)
+
+// AccessToken is a signed JWT that downstream services verify locally
+// against the JWKS instead of calling Authenticate.
+type AccessToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// AccessTokenClaims are carried by access tokens. The sid claim is the
+// handle of the session rather than its id, which is a credential; services
+// pass the token itself to Authenticate for the session behind it.
+type AccessTokenClaims struct {
+	SessionId string `json:"sid"`
+	jwt.RegisteredClaims
+}
+
+// Jwk is a public key in JSON Web Key format (RFC 7517).
+type Jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+type JwkSet struct {
+	Keys []Jwk `json:"keys"`
+}
+
+// signingKey is a private key along with the algorithm it signs with.
+type signingKey struct {
+	id        string
+	algorithm string
+	key       crypto.Signer
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func algorithmOf(key crypto.Signer) (string, error) {
+	switch k := key.Public().(type) {
+	case ed25519.PublicKey:
+		return ALGORITHM_EDDSA, nil
+	case *ecdsa.PublicKey:
+		if k.Curve != elliptic.P256() {
+			return "", fmt.Errorf("unsupported curve %s, use P-256", k.Curve.Params().Name)
+		}
+		return ALGORITHM_ES256, nil
+	case *rsa.PublicKey:
+		return ALGORITHM_RS256, nil
+	}
+	return "", fmt.Errorf("unsupported key type %T", key)
+}
+
+// publicJwk returns the public part of the key; its members are the ones
+// hashed into the key id.
+func publicJwk(key crypto.Signer) Jwk {
+	switch k := key.Public().(type) {
+	case ed25519.PublicKey:
+		return Jwk{Kty: "OKP", Crv: "Ed25519", X: b64(k)}
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		return Jwk{
+			Kty: "EC",
+			Crv: k.Curve.Params().Name,
+			X:   b64(k.X.FillBytes(make([]byte, size))),
+			Y:   b64(k.Y.FillBytes(make([]byte, size))),
+		}
+	case *rsa.PublicKey:
+		return Jwk{Kty: "RSA", N: b64(k.N.Bytes()), E: b64(big.NewInt(int64(k.E)).Bytes())}
+	}
+	return Jwk{}
+}
+
+// thumbprint computes the RFC 7638 thumbprint of a public JWK, hashing
+// its required members in lexicographic order.
+func thumbprint(j Jwk) string {
+	var members string
+	switch j.Kty {
+	case "OKP":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q}`, j.Crv, j.Kty, j.X)
+	case "EC":
+		members = fmt.Sprintf(`{"crv":%q,"kty":%q,"x":%q,"y":%q}`, j.Crv, j.Kty, j.X, j.Y)
+	case "RSA":
+		members = fmt.Sprintf(`{"e":%q,"kty":%q,"n":%q}`, j.E, j.Kty, j.N)
+	}
+	sum := sha256.Sum256([]byte(members))
+	return b64(sum[:])
+}
+
+func newSigningKey(key crypto.Signer) (signingKey, error) {
+	algorithm, err := algorithmOf(key)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return signingKey{id: thumbprint(publicJwk(key)), algorithm: algorithm, key: key}, nil
+}
+
+// generateSigningKey creates a fresh key for the algorithm.
+func generateSigningKey(algorithm string) (signingKey, error) {
+	var key crypto.Signer
+	var err error
+	switch algorithm {
+	case ALGORITHM_EDDSA, "":
+		_, key, err = ed25519.GenerateKey(rand.Reader)
+	case ALGORITHM_ES256:
+		key, err = ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	case ALGORITHM_RS256:
+		key, err = rsa.GenerateKey(rand.Reader, rsaKeyBits)
+	default:
+		return signingKey{}, fmt.Errorf("unknown signing algorithm %q", algorithm)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+	return newSigningKey(key)
+}
+
+// parseSigningKey reads a PEM encoded PKCS #8, PKCS #1 or SEC 1 private key.
+func parseSigningKey(data []byte) (signingKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return signingKey{}, fmt.Errorf("no PEM block found")
+	}
+	var key any
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return signingKey{}, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return signingKey{}, fmt.Errorf("unsupported key type %T", key)
+	}
+	return newSigningKey(signer)
+}
+
+func loadSigningKey(path string) (signingKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return signingKey{}, err
+	}
+	return parseSigningKey(data)
+}
+
+func (k signingKey) jwk() Jwk {
+	j := publicJwk(k.key)
+	j.Kid = k.id
+	j.Use = "sig"
+	j.Alg = k.algorithm
+	return j
+}
+
+func (k signingKey) sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(jwt.GetSigningMethod(k.algorithm), claims)
+	token.Header["kid"] = k.id
+	return token.SignedString(k.key)
+}
+
+// tokenIssuer signs access tokens with the active key of the ring.
+type tokenIssuer struct {
+	config AccessTokenConfig
+	keys   *keyRing
+}
+
+func newTokenId() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// issue signs a token for the session, expiring no later than the session.
+func (t *tokenIssuer) issue(s Session, now time.Time) (AccessToken, error) {
+	expiresAt := now.Add(t.config.Ttl)
+	if s.ValidThrough.Before(expiresAt) {
+		expiresAt = s.ValidThrough
+	}
+	claims := AccessTokenClaims{
+		SessionId: s.Handle(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenId(),
+			Issuer:    t.config.Issuer,
+			Subject:   subject(s.TenantId, s.Username),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	if t.config.Audience != "" {
+		claims.Audience = jwt.ClaimStrings{t.config.Audience}
+	}
+	key, ok := t.keys.current(now)
+	if !ok {
+		return AccessToken{}, fmt.Errorf("no active signing key")
+	}
+	token, err := key.sign(claims)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return AccessToken{Token: token, ExpiresAt: expiresAt}, nil
+}
+
+// verify checks the signature and the registered claims of an access token.
+func (t *tokenIssuer) verify(token string, now time.Time) (AccessTokenClaims, error) {
+	var claims AccessTokenClaims
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{ALGORITHM_EDDSA, ALGORITHM_ES256, ALGORITHM_RS256}),
+		jwt.WithIssuer(t.config.Issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	}
+	if t.config.Audience != "" {
+		opts = append(opts, jwt.WithAudience(t.config.Audience))
+	}
+	_, err := jwt.ParseWithClaims(token, &claims, func(token *jwt.Token) (any, error) {
+		kid, _ := token.Header["kid"].(string)
+		key, ok := t.keys.published(kid, now)
+		if !ok || key.algorithm != token.Method.Alg() {
+			return nil, fmt.Errorf("unknown signing key %q", kid)
+		}
+		return key.key.Public(), nil
+	}, opts...)
+	return claims, err
+}
+
+// isAccessToken tells access tokens from session ids, which have at most
+// one dot, and from service tokens, which have none.
+func isAccessToken(token string) bool {
+	return strings.Count(token, ".") == 2
+}
+
+func (a *AuthManager) composeSessionHandleKey(handle string) string {
+	return fmt.Sprintf("session_handle_%s", handle)
+}
+
+// issueAccessToken signs an access token and remembers which session the
+// handle in its sid claim stands for until the token expires.
+func (a *AuthManager) issueAccessToken(ctx context.Context, s Session) (AccessToken, error) {
+	now := utils.GetNowTz()
+	t, err := a.tokens.issue(s, now)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	if ttl := t.ExpiresAt.Sub(now); ttl > 0 {
+		data, err := json.Marshal(s.Id)
+		if err != nil {
+			return AccessToken{}, err
+		}
+		if err := a.cache.Set(ctx, a.composeSessionHandleKey(s.Handle()), data, ttl); err != nil {
+			return AccessToken{}, err
+		}
+	}
+	return t, nil
+}
+
+// getUserByAccessToken returns the session an access token was issued for,
+// looked up by the handle in its sid claim. Like Authenticate, it extends
+// the idle deadline of the session.
+func (a *AuthManager) getUserByAccessToken(ctx context.Context, token string) (User, Session, error) {
+	if a.tokens == nil {
+		return User{}, Session{}, ErrSessionNotFound
+	}
+	claims, err := a.tokens.verify(token, utils.GetNowTz())
+	if err != nil {
+		return User{}, Session{}, ErrSessionNotFound
+	}
+	var sessionId string
+	err = a.cacheGet(ctx, metrics.CACHE_PATH_SESSION_HANDLE, a.composeSessionHandleKey(claims.SessionId), &sessionId)
+	if errors.Is(err, ErrBackendUnavailable) {
+		return User{}, Session{}, err
+	}
+	if err != nil {
+		return User{}, Session{}, ErrSessionNotFound
+	}
+	return a.GetUserBySessionId(ctx, sessionId)
+}
+
+// IssueAccessToken signs an access token for a session just opened or
+// authenticated. It returns nil if access tokens are disabled.
+func (a *AuthManager) IssueAccessToken(ctx context.Context, s Session) (*AccessToken, error) {
+	if a.tokens == nil {
+		return nil, nil
+	}
+	t, err := a.issueAccessToken(ctx, s)
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// RefreshAccessToken issues a new access token for a valid session,
+// extending its idle deadline like Authenticate.
+func (a *AuthManager) RefreshAccessToken(ctx context.Context, sessionId string) (AccessToken, error) {
+	if a.tokens == nil {
+		return AccessToken{}, ErrAccessTokensDisabled
+	}
+	_, s, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return AccessToken{}, err
+	}
+	return a.issueAccessToken(ctx, s)
+}
+
+// Jwks returns the public keys access tokens are verified with.
+// The set is empty if access tokens are disabled.
+func (a *AuthManager) Jwks() JwkSet {
+	if a.keys == nil {
+		return JwkSet{Keys: []Jwk{}}
+	}
+	return a.keys.jwks(utils.GetNowTz())
+}
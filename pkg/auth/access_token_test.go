@@ -0,0 +1,147 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+var testMasterKey = bytes.Repeat([]byte{1}, MASTER_KEY_SIZE)
+
+// tampered changes the first character of a token, which is signed.
+func tampered(token string) string {
+	first := "A"
+	if token[0] == 'A' {
+		first = "B"
+	}
+	return first + token[1:]
+}
+
+func TestAccessToken(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{
+		AccessToken: AccessTokenConfig{Enabled: true},
+		SigningKeys: SigningKeyConfig{MasterKey: testMasterKey},
+	})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	token, err := a.IssueAccessToken(ctx, s)
+	if err != nil || token == nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+	var claims AccessTokenClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(token.Token, &claims); err != nil {
+		t.Fatalf("parsing the token: %v", err)
+	}
+	if claims.SessionId != s.Handle() {
+		t.Fatalf("got sid %q, want the session handle %q", claims.SessionId, s.Handle())
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"access token", token.Token, nil},
+		{"session id", s.Id, nil},
+		{"tampered token", tampered(token.Token), ErrSessionNotFound},
+		{"session handle", s.Handle(), ErrSessionNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := a.Authenticate(ctx, tt.token)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authenticate: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && (p.Kind != PRINCIPAL_USER || p.Session.Id != s.Id) {
+				t.Fatalf("Authenticate: got %s session %s, want user session %s", p.Kind, p.Session.Id, s.Id)
+			}
+		})
+	}
+
+	if err := a.InvalidateSession(ctx, s.Id); err != nil {
+		t.Fatalf("InvalidateSession: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, token.Token); err == nil {
+		t.Fatalf("Authenticate with the token of an invalidated session succeeded")
+	}
+}
+
+func TestParseSigningKey(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	sec1, _ := x509.MarshalECPrivateKey(ecKey)
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 1024)
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	pkcs8, _ := x509.MarshalPKCS8PrivateKey(edKey)
+	tests := []struct {
+		name          string
+		data          []byte
+		wantAlgorithm string
+	}{
+		{"SEC 1", pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: sec1}), ALGORITHM_ES256},
+		{"PKCS #1", pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(rsaKey)}), ALGORITHM_RS256},
+		{"PKCS #8", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: pkcs8}), ALGORITHM_EDDSA},
+		{"not PEM", []byte("secret"), ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			key, err := parseSigningKey(tt.data)
+			if (err != nil) != (tt.wantAlgorithm == "") {
+				t.Fatalf("parseSigningKey: got %v", err)
+			}
+			if key.algorithm != tt.wantAlgorithm {
+				t.Fatalf("got algorithm %q, want %q", key.algorithm, tt.wantAlgorithm)
+			}
+		})
+	}
+}
+
+func TestImportSigningKey(t *testing.T) {
+	ctx := context.Background()
+	key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+	path := filepath.Join(t.TempDir(), "key.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("writing the key: %v", err)
+	}
+	a, _ := newTestAuthManager(t, AuthManagerConfig{
+		AccessToken: AccessTokenConfig{Enabled: true, KeyFile: path},
+		SigningKeys: SigningKeyConfig{MasterKey: testMasterKey},
+	})
+	kid := thumbprint(publicJwk(key))
+	if keys := a.Jwks().Keys; len(keys) != 1 || keys[0].Kid != kid {
+		t.Fatalf("got JWKS %+v, want the imported key %s only", keys, kid)
+	}
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	token, err := a.IssueAccessToken(ctx, s)
+	if err != nil {
+		t.Fatalf("IssueAccessToken: %v", err)
+	}
+	parsed, _, err := jwt.NewParser().ParseUnverified(token.Token, &AccessTokenClaims{})
+	if err != nil || parsed.Header["kid"] != kid || parsed.Method.Alg() != ALGORITHM_ES256 {
+		t.Fatalf("got token header %v, want ES256 with kid %s", parsed.Header, kid)
+	}
+}
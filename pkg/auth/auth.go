@@ -2,247 +2,810 @@ package auth
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"crypto/rand"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"maps"
 	"time"
 
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/notify"
 	"github.com/JustDean/sam/pkg/postgres"
 	redis_utils "github.com/JustDean/sam/pkg/redis"
 	"github.com/JustDean/sam/pkg/utils"
-	"github.com/jackc/pgx/v5/pgxpool"
-	"github.com/redis/go-redis/v9"
+	"github.com/prometheus/client_golang/prometheus"
 )
 
 const (
 	QUERY_TIMEOUT = 10 * time.Second
 )
 
-var errInvalidCredentials = errors.New("invalid credentials")
+const MAX_USERNAME_LENGTH = 32
+
+// DUMMY_PASSWORD is hashed at startup. Passwords of unknown users are
+// checked against its hash, so that they take as long to refuse as wrong
+// passwords of existing users.
+const DUMMY_PASSWORD = "sam-dummy-password"
 
 func SetAuthManager(c AuthManagerConfig) (*AuthManager, error) {
-	dbpool, err := postgres.SetPostgresPool(c.Db)
+	switch c.Backend {
+	case BACKEND_MEMORY:
+		if (c.AccessToken.Enabled || c.Oidc.Enabled || c.EmailVerification.Enabled) && c.SigningKeys.MasterKey == nil {
+			slog.Warn("No master key, signing keys and verification tokens will not survive a restart")
+			c.SigningKeys.MasterKey = make([]byte, MASTER_KEY_SIZE)
+			if _, err := rand.Read(c.SigningKeys.MasterKey); err != nil {
+				return nil, err
+			}
+		}
+		store := NewMemoryStore()
+		return NewAuthManager(Stores{
+			Users:           store,
+			Sessions:        store,
+			Mfa:             store,
+			PasswordResets:  store,
+			SigningKeys:     store,
+			Clients:         store,
+			ServiceAccounts: store,
+			Roles:           store,
+			Tenants:         store,
+			Webauthn:        store,
+			Cache:           NewMemoryCache(),
+		}, c)
+	case BACKEND_POSTGRES, "":
+		dbpool, err := postgres.SetPostgresPool(c.Db)
+		if err != nil {
+			return nil, err
+		}
+		cache, err := redis_utils.SetRedisPool(c.Cache)
+		if err != nil {
+			dbpool.Close()
+			return nil, err
+		}
+		store := newPostgresStore(dbpool)
+		a, err := NewAuthManager(Stores{
+			Users:           store,
+			Sessions:        store,
+			Mfa:             store,
+			PasswordResets:  store,
+			SigningKeys:     store,
+			Clients:         store,
+			ServiceAccounts: store,
+			Roles:           store,
+			Tenants:         store,
+			Webauthn:        store,
+			Cache:           newRedisCache(cache),
+		}, c)
+		if err != nil {
+			dbpool.Close()
+			cache.Close()
+			return nil, err
+		}
+		a.limiter.backend = &fallbackLimiterBackend{
+			primary:  newRedisLimiterBackend(cache),
+			fallback: a.limiter.backend,
+		}
+		a.collectors = append(a.collectors,
+			metrics.NewPgxPoolCollector(dbpool),
+			metrics.NewRedisPoolCollector(cache),
+		)
+		return a, nil
+	}
+	return nil, fmt.Errorf("unknown storage backend %q", c.Backend)
+}
+
+// Stores groups the storage an AuthManager is built on.
+type Stores struct {
+	Users           UserStore
+	Sessions        SessionStore
+	Mfa             MfaStore
+	PasswordResets  PasswordResetStore
+	SigningKeys     SigningKeyStore
+	Clients         ClientStore
+	ServiceAccounts ServiceAccountStore
+	Roles           RoleStore
+	Tenants         TenantStore
+	Webauthn        WebauthnStore
+	Cache           SessionCache
+}
+
+// NewAuthManager builds an AuthManager on top of the given storage, ignoring
+// the connection settings of the config. A nil hasher defaults to argon2id,
+// zero timeouts and limits to their defaults. Login attempts are tracked in
+// process memory. Without a notifier, no messages are sent.
+func NewAuthManager(s Stores, c AuthManagerConfig) (*AuthManager, error) {
+	hasher := c.Hasher
+	if hasher == nil {
+		hasher = DefaultArgon2idHasher()
+	}
+	if _, ok := hasher.(Sha256Hasher); ok {
+		return nil, errLegacyHasher
+	}
+	dummyHash, err := hasher.Hash(DUMMY_PASSWORD)
+	if err != nil {
+		return nil, err
+	}
+	notifier := c.Notifier
+	if notifier == nil {
+		notifier = notify.NoNotifier{}
+	}
+	_, noNotifier := notifier.(notify.NoNotifier)
+	a := &AuthManager{
+		users:              s.Users,
+		sessions:           s.Sessions,
+		mfa:                s.Mfa,
+		resets:             s.PasswordResets,
+		signingKeys:        s.SigningKeys,
+		clients:            s.Clients,
+		serviceAccounts:    s.ServiceAccounts,
+		roles:              s.Roles,
+		tenants:            s.Tenants,
+		webauthn:           s.Webauthn,
+		cache:              s.Cache,
+		notifier:           notifier,
+		hasher:             newMigratingHasher(hasher),
+		dummyHash:          dummyHash,
+		sessionConfig:      c.Session.normalize(),
+		mfaConfig:          c.Mfa.normalize(),
+		resetConfig:        c.PasswordReset.normalize(),
+		serviceTokenConfig: c.ServiceToken.normalize(),
+		rbacConfig:         c.Rbac.normalize(),
+		limiter:            newLoginLimiter(c.Limiter, newMemoryLimiterBackend()),
+	}
+	if len(c.SigningKeys.MasterKey) > 0 {
+		if a.mfaSealer, err = newSealer(c.SigningKeys.MasterKey); err != nil {
+			return nil, fmt.Errorf("master key: %w", err)
+		}
+	}
+	oc, err := c.Oidc.normalize()
+	if err != nil {
+		return nil, err
+	}
+	a.oidcConfig = oc
+	vc, err := c.EmailVerification.normalize()
 	if err != nil {
 		return nil, err
 	}
-	cache, err := redis_utils.SetRedisPool(c.Cache)
+	a.verificationConfig = vc
+	a.passwordlessConfig = c.Passwordless.normalize()
+	wc, err := c.Webauthn.normalize()
 	if err != nil {
 		return nil, err
 	}
-	return &AuthManager{
-		dbpool, cache,
-	}, nil
+	a.webauthnConfig = wc
+	a.passwordlessChannels = map[string]PasswordlessChannel{}
+	if !noNotifier {
+		a.passwordlessChannels[PASSWORDLESS_CHANNEL_EMAIL] = &notifierChannel{notifier}
+	}
+	maps.Copy(a.passwordlessChannels, a.passwordlessConfig.Channels)
+	if a.passwordlessConfig.Enabled && len(a.passwordlessChannels) == 0 {
+		return nil, fmt.Errorf("passwordless login needs a notifier or another channel")
+	}
+	if vc.Enabled {
+		if noNotifier {
+			return nil, fmt.Errorf("email verification needs a notifier")
+		}
+		if len(c.SigningKeys.MasterKey) == 0 {
+			return nil, fmt.Errorf("a master key is required to sign email verification tokens")
+		}
+		a.verificationKey = deriveKey(c.SigningKeys.MasterKey, VERIFICATION_KEY_LABEL)
+	}
+	if c.AccessToken.Enabled || oc.Enabled {
+		tc := c.AccessToken.normalize()
+		// Replaced keys stay published as long as any token they signed.
+		tokenTtl := max(tc.Ttl, oc.IdTokenTtl)
+		kc := c.SigningKeys
+		if kc.Algorithm == "" {
+			kc.Algorithm = tc.Algorithm
+		}
+		keys, err := newKeyRing(kc.normalize(tokenTtl), s.SigningKeys)
+		if err != nil {
+			return nil, err
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), QUERY_TIMEOUT)
+		defer cancel()
+		if tc.KeyFile != "" {
+			slog.Warn("The access token key file is deprecated, signing keys are managed by SAM", "path", tc.KeyFile)
+			key, err := loadSigningKey(tc.KeyFile)
+			if err != nil {
+				return nil, fmt.Errorf("access token signing key: %w", err)
+			}
+			if err := keys.importKey(ctx, key); err != nil {
+				return nil, fmt.Errorf("signing keys: %w", err)
+			}
+		}
+		if err := keys.maintain(ctx); err != nil {
+			return nil, fmt.Errorf("signing keys: %w", err)
+		}
+		a.keys = keys
+		if c.AccessToken.Enabled {
+			a.tokens = &tokenIssuer{config: tc, keys: keys}
+		}
+	}
+	a.collectors = []prometheus.Collector{metrics.NewActiveSessionsCollector(a.countActiveSessions)}
+	return a, nil
 }
 
 type AuthManager struct {
-	dbpool *pgxpool.Pool
-	cache  *redis.Client
+	users                UserStore
+	sessions             SessionStore
+	mfa                  MfaStore
+	resets               PasswordResetStore
+	signingKeys          SigningKeyStore
+	clients              ClientStore
+	serviceAccounts      ServiceAccountStore
+	roles                RoleStore
+	tenants              TenantStore
+	webauthn             WebauthnStore
+	cache                SessionCache
+	notifier             notify.Notifier
+	hasher               PasswordHasher
+	dummyHash            string // hash of DUMMY_PASSWORD by the preferred hasher
+	sessionConfig        SessionConfig
+	mfaConfig            MfaConfig
+	mfaSealer            *sealer // nil without a master key, TOTP secrets are then stored as is
+	resetConfig          PasswordResetConfig
+	oidcConfig           OidcConfig
+	serviceTokenConfig   ServiceTokenConfig
+	rbacConfig           RbacConfig
+	verificationConfig   EmailVerificationConfig
+	verificationKey      []byte // nil if email verification is disabled
+	passwordlessConfig   PasswordlessConfig
+	passwordlessChannels map[string]PasswordlessChannel
+	webauthnConfig       WebauthnConfig
+	limiter              *loginLimiter
+	keys                 *keyRing     // nil if access tokens and OIDC are disabled
+	tokens               *tokenIssuer // nil if access tokens are disabled
+	collectors           []prometheus.Collector
+}
+
+// Collectors returns the metrics of the manager and its storage,
+// to be registered by the caller.
+func (a *AuthManager) Collectors() []prometheus.Collector {
+	return a.collectors
+}
+
+func (a *AuthManager) countActiveSessions(ctx context.Context) (int64, error) {
+	return a.sessions.CountActiveSessions(ctx, utils.GetNowTz())
 }
 
 func (a *AuthManager) Run(ctx context.Context) {
-	log.Println("Starting Auth Manager")
+	slog.Info("Starting Auth Manager")
+	keysDone := make(chan struct{})
+	go func() {
+		defer close(keysDone)
+		if a.keys != nil {
+			a.keys.run(ctx)
+		}
+	}()
 	<-ctx.Done()
-	log.Println("Stopping Auth Manager")
-	a.dbpool.Close()
+	slog.Info("Stopping Auth Manager")
+	<-keysDone
+	a.users.Close()
+	a.sessions.Close()
+	a.mfa.Close()
+	a.resets.Close()
+	a.signingKeys.Close()
+	a.clients.Close()
+	a.serviceAccounts.Close()
+	a.roles.Close()
+	a.tenants.Close()
+	a.webauthn.Close()
 	a.cache.Close()
-	log.Println("Auth Manager is stopped")
+	slog.Info("Auth Manager is stopped")
+}
+
+// Ping checks that the storage and the cache can be reached.
+func (a *AuthManager) Ping(ctx context.Context) error {
+	if err := a.users.Ping(ctx); err != nil {
+		return fmt.Errorf("users: %w", err)
+	}
+	if err := a.sessions.Ping(ctx); err != nil {
+		return fmt.Errorf("sessions: %w", err)
+	}
+	if err := a.mfa.Ping(ctx); err != nil {
+		return fmt.Errorf("mfa: %w", err)
+	}
+	if err := a.resets.Ping(ctx); err != nil {
+		return fmt.Errorf("password resets: %w", err)
+	}
+	if err := a.signingKeys.Ping(ctx); err != nil {
+		return fmt.Errorf("signing keys: %w", err)
+	}
+	if err := a.clients.Ping(ctx); err != nil {
+		return fmt.Errorf("clients: %w", err)
+	}
+	if err := a.serviceAccounts.Ping(ctx); err != nil {
+		return fmt.Errorf("service accounts: %w", err)
+	}
+	if err := a.roles.Ping(ctx); err != nil {
+		return fmt.Errorf("roles: %w", err)
+	}
+	if err := a.tenants.Ping(ctx); err != nil {
+		return fmt.Errorf("tenants: %w", err)
+	}
+	if err := a.webauthn.Ping(ctx); err != nil {
+		return fmt.Errorf("webauthn credentials: %w", err)
+	}
+	if err := a.cache.Ping(ctx); err != nil {
+		return fmt.Errorf("cache: %w", err)
+	}
+	return nil
 }
 
 func (a *AuthManager) composeSessionKey(sessionid string) string {
-	return fmt.Sprintf("sessionid_%s", sessionid)
+	tenantId, id := splitSessionId(sessionid)
+	return fmt.Sprintf("sessionid_%s_%s", tenantId, id)
 }
 
-func (a *AuthManager) composeUserKey(username string) string {
-	return fmt.Sprintf("user_%s", username)
+func (a *AuthManager) composeUserKey(tenantId, username string) string {
+	return fmt.Sprintf("user_%s_%s", tenantId, username)
 }
 
-func (a *AuthManager) cacheGet(ctx context.Context, key string) (User, error) {
-	var user User
-	res, err := a.cache.Get(ctx, key).Result()
+// sessionEntry is the payload cached under a session key. The roles of the
+// user are loaded on the first authorization and cached along, until
+// RolesLoadedAt is older than the role cache TTL.
+type sessionEntry struct {
+	User          User      `json:"user"`
+	Session       Session   `json:"session"`
+	Roles         []Role    `json:"roles,omitempty"`
+	RolesLoadedAt time.Time `json:"roles_loaded_at"`
+}
+
+// cacheGet reads the value under key into v, counting hits and misses
+// under the given metrics path.
+func (a *AuthManager) cacheGet(ctx context.Context, path, key string, v any) error {
+	res, err := a.cache.Get(ctx, key)
+	if err == nil {
+		err = json.Unmarshal(res, v)
+	}
 	if err != nil {
-		return user, err
+		metrics.CacheMiss(path)
+		return err
 	}
-	json.Unmarshal([]byte(res), &user)
-	return user, err
+	metrics.CacheHit(path)
+	return nil
 }
 
-func (a *AuthManager) cacheSetSession(ctx context.Context, session Session, user User) error {
-	key := a.composeSessionKey(session.Id)
-	ttl := session.ValidThrough.Sub(utils.GetNowTz())
-	data, err := json.Marshal(user)
+// cacheSetSession caches the session until its idle deadline. A session
+// past it is dropped instead: caches keep entries without a positive ttl
+// for good.
+func (a *AuthManager) cacheSetSession(ctx context.Context, entry sessionEntry) error {
+	key := a.composeSessionKey(entry.Session.Id)
+	ttl := entry.Session.ValidThrough.Sub(utils.GetNowTz())
+	if ttl <= 0 {
+		return a.cache.Del(ctx, key)
+	}
+	data, err := json.Marshal(entry)
 	if err != nil {
 		return err
 	}
-	return a.cache.Set(ctx, key, data, ttl).Err()
+	return a.cache.Set(ctx, key, data, ttl)
 }
 
 func (a *AuthManager) cacheSetUser(ctx context.Context, user User) error {
-	key := a.composeUserKey(user.Username)
+	key := a.composeUserKey(user.TenantId, user.Username)
 	data, err := json.Marshal(user)
 	if err != nil {
 		return err
 	}
-	return a.cache.Set(ctx, key, data, 0).Err()
+	return a.cache.Set(ctx, key, data, 0)
 }
 
-// GetUserBySessionId
-func (a *AuthManager) GetUserBySessionId(ctx context.Context, sessionid string) (User, error) {
-	var u User
-	u, err := a.cacheGet(ctx, a.composeSessionKey(sessionid))
-	if err == nil {
-		return u, nil
+// GetUserBySessionId returns the session and its user, extending the idle deadline.
+func (a *AuthManager) GetUserBySessionId(ctx context.Context, sessionid string) (User, Session, error) {
+	entry, err := a.getSessionEntry(ctx, sessionid)
+	if err != nil {
+		return User{}, Session{}, err
 	}
-	query := `SELECT u.username, u.password, s.valid_through
-		FROM users u JOIN sessions s 
-		ON u.username = s.username 
-		WHERE s.id = $1 AND s.valid_through > $2`
+	return entry.User, entry.Session, nil
+}
+
+// getSessionEntry returns the cached payload of a valid session, loading it
+// from the store on a miss, and extends the idle deadline.
+func (a *AuthManager) getSessionEntry(ctx context.Context, sessionid string) (sessionEntry, error) {
 	now := utils.GetNowTz()
-	s := Session{
-		Id:           sessionid,
-		ValidThrough: now,
-		Username:     "",
+	var entry sessionEntry
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_SESSION, a.composeSessionKey(sessionid), &entry)
+	if err != nil || !entry.Session.ValidThrough.After(now) {
+		entry = sessionEntry{}
+		entry.Session, entry.User, err = a.sessions.GetSession(ctx, sessionid)
+		if errors.Is(err, ErrNotFound) {
+			return sessionEntry{}, ErrSessionNotFound
+		}
+		if err != nil {
+			return sessionEntry{}, err
+		}
+		if !entry.Session.ValidThrough.After(now) {
+			return sessionEntry{}, ErrSessionExpired
+		}
+		a.cacheSetSession(ctx, entry)
+	}
+	if entry.User.Disabled() {
+		return sessionEntry{}, ErrUserDisabled
+	}
+	entry.Session.Restricted = a.restricted(entry.User)
+	return a.touchSession(ctx, entry, now)
+}
+
+// sessionDeadline returns when a session active at now goes idle.
+func (a *AuthManager) sessionDeadline(s Session, now time.Time) time.Time {
+	if a.sessionConfig.IdleTimeout <= 0 {
+		return s.ExpiresAt
+	}
+	deadline := now.Add(a.sessionConfig.IdleTimeout)
+	if deadline.After(s.ExpiresAt) {
+		return s.ExpiresAt
+	}
+	return deadline
+}
+
+// touchSession records activity and extends the idle deadline, at most once per TouchInterval.
+func (a *AuthManager) touchSession(ctx context.Context, entry sessionEntry, now time.Time) (sessionEntry, error) {
+	s := entry.Session
+	if now.Sub(s.LastSeenAt) < a.sessionConfig.TouchInterval {
+		return entry, nil
+	}
+	validThrough := a.sessionDeadline(s, now)
+	err := a.sessions.TouchSession(ctx, s.Id, now, validThrough)
+	if errors.Is(err, ErrNotFound) {
+		a.cache.Del(ctx, a.composeSessionKey(s.Id))
+		return sessionEntry{}, ErrSessionExpired
 	}
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	err = a.dbpool.QueryRow(queryCtx, query, sessionid, now).Scan(&u.Username, &u.Password, &s.ValidThrough)
 	if err != nil {
-		return User{}, err
+		slog.ErrorContext(ctx, "Error touching session", "tenant_id", s.TenantId, "username", s.Username, "session_id", s.Id, "error", err)
+		return entry, nil
 	}
-	s.Username = u.Username
-	a.cacheSetSession(ctx, s, u)
-	return u, nil
+	entry.Session.LastSeenAt = now
+	entry.Session.ValidThrough = validThrough
+	a.cacheSetSession(ctx, entry)
+	return entry, nil
 }
 
-func (a *AuthManager) encryptPassword(password string) string {
-	hash := sha256.New()
-	hash.Write([]byte(password))
-	hashedBytes := hash.Sum(nil)
-	return hex.EncodeToString(hashedBytes)
+func (a *AuthManager) hashPassword(password string) (string, error) {
+	return a.hasher.Hash(password)
 }
 
-func (a *AuthManager) CreateUser(ctx context.Context, username, password string) (User, error) {
-	u := User{Username: username, Password: a.encryptPassword(password)}
-	query := "INSERT INTO users (username, password) VALUES ($1, $2)"
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	_, err := a.dbpool.Exec(queryCtx, query, u.Username, u.Password)
+func (a *AuthManager) validateCredentials(username, password string) error {
+	if len(username) == 0 || len(username) > MAX_USERNAME_LENGTH {
+		return invalidArgument("username must be 1 to %d characters long", MAX_USERNAME_LENGTH)
+	}
+	if len(password) == 0 {
+		return invalidArgument("password must not be empty")
+	}
+	return nil
+}
+
+// CreateUser adds a user to the tenant, the default one if empty.
+func (a *AuthManager) CreateUser(ctx context.Context, tenantId, username, password, email string) (User, error) {
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.validateCredentials(username, password); err != nil {
+		return User{}, err
+	}
+	if err := a.checkSignupEmail(email); err != nil {
+		return User{}, err
+	}
+	if _, err := a.getTenant(ctx, tenantId); err != nil {
+		return User{}, err
+	}
+	hash, err := a.hashPassword(password)
+	if err != nil {
+		return User{}, err
+	}
+	u := newUser(tenantId, username, hash, email)
+	err = a.users.CreateUser(ctx, u)
+	if errors.Is(err, ErrAlreadyExists) {
+		return User{}, ErrUserExists
+	}
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrTenantDisabled
+	}
 	if err != nil {
-		return u, err
+		return User{}, err
 	}
 	a.cacheSetUser(ctx, u)
-	return u, nil
+	return u, a.sendVerification(ctx, u)
 }
 
-func (a *AuthManager) LoginUser(ctx context.Context, username, password string) (Session, error) {
-	user, err := a.getUserByUsername(ctx, username)
+// SignupAndLogin creates the user together with its first session.
+// Nothing is stored if the username is taken in the tenant. It is refused
+// with ErrEmailNotVerified if unverified users cannot sign in.
+func (a *AuthManager) SignupAndLogin(ctx context.Context, tenantId, username, password, email string, client ClientInfo) (Session, error) {
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.validateCredentials(username, password); err != nil {
+		return Session{}, err
+	}
+	if err := a.checkSignupEmail(email); err != nil {
+		return Session{}, err
+	}
+	if a.verificationConfig.Enabled && a.verificationConfig.UnverifiedPolicy == UNVERIFIED_DENY {
+		return Session{}, ErrEmailNotVerified
+	}
+	if _, err := a.getTenant(ctx, tenantId); err != nil {
+		return Session{}, err
+	}
+	hash, err := a.hashPassword(password)
 	if err != nil {
 		return Session{}, err
 	}
-	if !a.comparePasswords(user, password) {
-		return Session{}, errInvalidCredentials
+	u := newUser(tenantId, username, hash, email)
+	s, err := a.users.CreateUserWithSession(ctx, u, a.newSession(u, client))
+	if errors.Is(err, ErrAlreadyExists) {
+		return Session{}, ErrUserExists
+	}
+	if errors.Is(err, ErrNotFound) {
+		return Session{}, ErrTenantDisabled
 	}
-	s, err := a.createSesssion(ctx, user)
 	if err != nil {
 		return Session{}, err
 	}
-	return s, nil
+	a.cacheSetUser(ctx, u)
+	a.cacheSetSession(ctx, sessionEntry{User: u, Session: s})
+	s.Restricted = a.restricted(u)
+	return s, a.sendVerification(ctx, u)
+}
+
+// LoginUser checks the password and opens a session. Users with MFA enabled
+// get a challenge instead, to be completed with VerifyMfa. Users of unknown
+// tenants are reported as ErrInvalidCredentials, of disabled ones as
+// ErrTenantDisabled.
+func (a *AuthManager) LoginUser(ctx context.Context, tenantId, username, password string, client ClientInfo) (Session, *MfaChallenge, error) {
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.allowAttempt(ctx, LIMIT_LOGIN, tenantId, username, client.Ip); err != nil {
+		return Session{}, nil, err
+	}
+	user, err := a.getUserForPassword(ctx, tenantId, username, password)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if !a.checkPassword(ctx, user, password) {
+		return Session{}, nil, ErrInvalidCredentials
+	}
+	if a.hasher.NeedsRehash(user.Password) && !user.Disabled() {
+		if err := a.rehashPassword(ctx, user, password); err != nil {
+			slog.ErrorContext(ctx, "Error rehashing password", "tenant_id", user.TenantId, "username", user.Username, "error", err)
+		}
+	}
+	return a.completeLogin(ctx, user, client)
+}
+
+// completeLogin opens a session for a user who proved their identity with
+// a first factor, or returns an MFA challenge if they have MFA enabled.
+func (a *AuthManager) completeLogin(ctx context.Context, user User, client ClientInfo) (Session, *MfaChallenge, error) {
+	if user.Disabled() {
+		return Session{}, nil, ErrUserDisabled
+	}
+	if a.unverified(user) && a.verificationConfig.UnverifiedPolicy == UNVERIFIED_DENY {
+		return Session{}, nil, ErrEmailNotVerified
+	}
+	_, err := a.getEnabledMfa(ctx, user.TenantId, user.Username)
+	if err == nil {
+		c, err := a.newMfaChallenge(ctx, user, client)
+		return Session{}, c, err
+	}
+	if !errors.Is(err, ErrMfaNotEnabled) {
+		return Session{}, nil, err
+	}
+	a.resetFailures(ctx, LIMIT_LOGIN, user.TenantId, user.Username)
+	s, err := a.createSesssion(ctx, user, client)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	return s, nil, nil
+}
+
+// checkTenant reports an unknown tenant as ErrInvalidCredentials, like
+// getUserByUsername does for unknown users.
+func (a *AuthManager) checkTenant(ctx context.Context, tenantId string) error {
+	_, err := a.getTenant(ctx, tenantId)
+	if errors.Is(err, ErrTenantNotFound) {
+		return ErrInvalidCredentials
+	}
+	return err
+}
+
+// getUserForPassword is getUserByUsername for callers about to check the
+// password. For unknown tenants and users, it compares the password with
+// the dummy hash before reporting ErrInvalidCredentials, so that response
+// times do not reveal which usernames exist.
+func (a *AuthManager) getUserForPassword(ctx context.Context, tenantId, username, password string) (User, error) {
+	err := a.checkTenant(ctx, tenantId)
+	var user User
+	if err == nil {
+		user, err = a.getUserByUsername(ctx, tenantId, username)
+	}
+	if errors.Is(err, ErrInvalidCredentials) {
+		a.hasher.Verify(a.dummyHash, password)
+	}
+	return user, err
+}
+
+// allowAttempt applies the limits of the action. Limiter failures other
+// than rate limiting are logged and let the attempt through, so that a
+// broken limiter does not lock everybody out.
+func (a *AuthManager) allowAttempt(ctx context.Context, action, tenantId, username, ip string) error {
+	err := a.limiter.Allow(ctx, action, userKey(tenantId, username), ip, utils.GetNowTz())
+	if err == nil || errors.Is(err, ErrRateLimited) {
+		return err
+	}
+	slog.ErrorContext(ctx, "Error checking limits", "action", action, "tenant_id", tenantId, "username", username, "error", err)
+	return nil
 }
 
-func (a *AuthManager) getUserByUsername(ctx context.Context, username string) (User, error) {
-	u, err := a.cacheGet(ctx, a.composeUserKey(username))
+// checkPassword compares passwords, counting wrong ones towards a lockout.
+func (a *AuthManager) checkPassword(ctx context.Context, u User, password string) bool {
+	ok := a.comparePasswords(u, password)
+	if !ok {
+		a.recordFailure(ctx, LIMIT_LOGIN, u.TenantId, u.Username)
+	}
+	return ok
+}
+
+// recordFailure counts a wrong credential towards a lockout of the action.
+func (a *AuthManager) recordFailure(ctx context.Context, action, tenantId, username string) {
+	if err := a.limiter.Failure(ctx, action, userKey(tenantId, username)); err != nil {
+		slog.ErrorContext(ctx, "Error recording failed attempt", "action", action, "tenant_id", tenantId, "username", username, "error", err)
+	}
+}
+
+// resetFailures is called once the user fully proved their identity;
+// a right password alone does not reset failed MFA codes.
+func (a *AuthManager) resetFailures(ctx context.Context, action, tenantId, username string) {
+	if err := a.limiter.Success(ctx, action, userKey(tenantId, username)); err != nil {
+		slog.ErrorContext(ctx, "Error resetting failed attempts", "action", action, "tenant_id", tenantId, "username", username, "error", err)
+	}
+}
+
+// getUserByUsername reports an unknown user as ErrInvalidCredentials
+// so that callers do not reveal which usernames exist.
+func (a *AuthManager) getUserByUsername(ctx context.Context, tenantId, username string) (User, error) {
+	var u User
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_USER, a.composeUserKey(tenantId, username), &u)
 	if err == nil {
 		return u, nil
 	}
-	u = User{Username: username}
-	query := "SELECT password FROM users WHERE username = $1"
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	err = a.dbpool.QueryRow(queryCtx, query, username).Scan(&u.Password)
+	u, err = a.users.GetUser(ctx, tenantId, username)
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrInvalidCredentials
+	}
 	if err != nil {
-		return u, err
+		return User{}, err
 	}
 	a.cacheSetUser(ctx, u)
 	return u, nil
 }
 
-func (a *AuthManager) createSesssion(ctx context.Context, u User) (Session, error) {
-	expirationDate := utils.GetNowTz().AddDate(0, 0, 10)
-	newSession := Session{ValidThrough: expirationDate, Username: u.Username}
-	query := "INSERT INTO sessions (valid_through, username) VALUES ($1, $2) RETURNING id"
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	err := a.dbpool.QueryRow(queryCtx, query, newSession.ValidThrough, newSession.Username).Scan(&newSession.Id)
+// newSession prepares a session for the user starting now; the store assigns its id.
+func (a *AuthManager) newSession(u User, client ClientInfo) Session {
+	now := utils.GetNowTz()
+	s := Session{
+		TenantId:   u.TenantId,
+		ExpiresAt:  now.Add(a.sessionConfig.AbsoluteTimeout),
+		Username:   u.Username,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ClientInfo: client.normalize(),
+	}
+	s.ValidThrough = a.sessionDeadline(s, now)
+	return s
+}
+
+func (a *AuthManager) createSesssion(ctx context.Context, u User, client ClientInfo) (Session, error) {
+	newSession, err := a.sessions.CreateSession(ctx, a.newSession(u, client))
+	if errors.Is(err, ErrNotFound) {
+		// The user was deleted or disabled, or the tenant disabled, since
+		// the credentials were checked.
+		return Session{}, ErrInvalidCredentials
+	}
 	if err != nil {
 		return Session{}, err
 	}
-	a.cacheSetSession(ctx, newSession, u)
+	a.cacheSetSession(ctx, sessionEntry{User: u, Session: newSession})
+	newSession.Restricted = a.restricted(u)
 	return newSession, nil
 }
 
 func (a *AuthManager) comparePasswords(u User, password string) bool {
-	return u.Password == a.encryptPassword(password)
+	ok, err := a.hasher.Verify(u.Password, password)
+	if err != nil {
+		slog.Error("Error verifying password", "tenant_id", u.TenantId, "username", u.Username, "error", err)
+	}
+	return ok
 }
 
-func (a *AuthManager) invalidateUserSessions(ctx context.Context, u User) error {
-	query := "UPDATE sessions SET valid_through = $1 WHERE username = $2 RETURNING id"
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	rows, err := a.dbpool.Query(queryCtx, query, utils.GetNowTz(), u.Username)
+// rehashPassword replaces a legacy or outdated hash with one produced by the
+// configured hasher. It must only be called after a successful comparePasswords.
+func (a *AuthManager) rehashPassword(ctx context.Context, u User, password string) error {
+	hash, err := a.hashPassword(password)
 	if err != nil {
 		return err
 	}
-	defer rows.Close()
-	var sessionIds []string
-	for rows.Next() {
-		var id string
-		if err := rows.Scan(&id); err != nil {
-			continue
-		}
-		sessionIds = append(sessionIds, a.composeSessionKey(id))
+	if err := a.users.ReplacePassword(ctx, u.TenantId, u.Username, u.Password, hash); err != nil {
+		return err
 	}
-	if err := rows.Err(); err != nil {
+	return a.cache.Del(ctx, a.composeUserKey(u.TenantId, u.Username))
+}
+
+// invalidateUserSessions expires every session of the user but exceptSessionId.
+func (a *AuthManager) invalidateUserSessions(ctx context.Context, u User, exceptSessionId string) error {
+	ids, err := a.sessions.InvalidateUserSessions(ctx, u.TenantId, u.Username, exceptSessionId, utils.GetNowTz())
+	if err != nil {
 		return err
 	}
-	_, err = a.cache.Del(ctx, sessionIds...).Result()
-	return err
+	sessionKeys := make([]string, 0, len(ids))
+	for _, id := range ids {
+		sessionKeys = append(sessionKeys, a.composeSessionKey(id))
+	}
+	return a.cache.Del(ctx, sessionKeys...)
 }
 
-func (a *AuthManager) ChangePassword(ctx context.Context, username, currentPassword, newPassword string) (User, error) {
-	user, err := a.getUserByUsername(ctx, username)
+func (a *AuthManager) ChangePassword(ctx context.Context, tenantId, username, currentPassword, newPassword string) (User, error) {
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.allowAttempt(ctx, LIMIT_LOGIN, tenantId, username, ""); err != nil {
+		return User{}, err
+	}
+	user, err := a.getUserForPassword(ctx, tenantId, username, currentPassword)
 	if err != nil {
 		return user, err
 	}
-	if !a.comparePasswords(user, currentPassword) {
-		return User{}, errInvalidCredentials
+	if !a.checkPassword(ctx, user, currentPassword) {
+		return User{}, ErrInvalidCredentials
+	}
+	if user.Disabled() {
+		return User{}, ErrUserDisabled
 	}
-	query := "UPDATE users SET password = $1 WHERE username = $2"
-	encryptedPassword := a.encryptPassword(newPassword)
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	_, err = a.dbpool.Exec(queryCtx, query, encryptedPassword, username)
+	a.resetFailures(ctx, LIMIT_LOGIN, tenantId, username)
+	if len(newPassword) == 0 {
+		return User{}, invalidArgument("password must not be empty")
+	}
+	encryptedPassword, err := a.hashPassword(newPassword)
 	if err != nil {
 		return user, err
 	}
+	if err := a.users.UpdatePassword(ctx, tenantId, username, encryptedPassword); err != nil {
+		return user, err
+	}
 	user.Password = encryptedPassword
-	a.invalidateUserSessions(ctx, user)
-	_, err = a.cache.Del(ctx, a.composeUserKey(username)).Result()
-	if err != nil {
+	a.invalidateUserSessions(ctx, user, "")
+	if err := a.cache.Del(ctx, a.composeUserKey(tenantId, username)); err != nil {
 		return user, err
 	}
 	return user, nil
 }
 
 func (a *AuthManager) InvalidateSession(ctx context.Context, sessionId string) error {
-	query := "UPDATE sessions SET valid_through = $1 WHERE id = $2"
-	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
-	defer cancel()
-	_, err := a.dbpool.Exec(queryCtx, query, utils.GetNowTz(), sessionId)
+	err := a.sessions.InvalidateSession(ctx, sessionId, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return ErrSessionNotFound
+	}
 	if err != nil {
 		return err
 	}
-	_, err = a.cache.Del(ctx, a.composeSessionKey(sessionId)).Result()
-	return err
+	return a.cache.Del(ctx, a.composeSessionKey(sessionId))
+}
+
+// ListSessions returns every active session of the user of sessionId.
+// Session ids are credentials: callers must only show other sessions by
+// their Handle.
+func (a *AuthManager) ListSessions(ctx context.Context, sessionId string) ([]Session, error) {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	return a.sessions.ListSessions(ctx, user.TenantId, user.Username, utils.GetNowTz())
+}
+
+// RevokeAllSessions expires every session of the user of sessionId, but
+// sessionId itself if keepCurrent is set.
+func (a *AuthManager) RevokeAllSessions(ctx context.Context, sessionId string, keepCurrent bool) error {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+	var exceptSessionId string
+	if keepCurrent {
+		exceptSessionId = sessionId
+	}
+	return a.invalidateUserSessions(ctx, user, exceptSessionId)
 }
@@ -0,0 +1,264 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JustDean/sam/pkg/notify"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// testHasher is a cheap argon2id configuration, to keep tests fast.
+var testHasher = Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, KeyLen: 32, SaltLen: 16}
+
+// recordingNotifier keeps the messages it is given.
+type recordingNotifier struct {
+	mu       sync.Mutex
+	messages []notify.Message
+}
+
+func (n *recordingNotifier) Notify(ctx context.Context, m notify.Message) error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.messages = append(n.messages, m)
+	return nil
+}
+
+// newTestAuthManager returns a manager on top of a fresh MemoryStore and
+// MemoryCache, along with the store.
+func newTestAuthManager(t *testing.T, c AuthManagerConfig) (*AuthManager, *MemoryStore) {
+	t.Helper()
+	if c.Hasher == nil {
+		c.Hasher = testHasher
+	}
+	store := NewMemoryStore()
+	a, err := NewAuthManager(Stores{
+		Users:           store,
+		Sessions:        store,
+		Mfa:             store,
+		PasswordResets:  store,
+		SigningKeys:     store,
+		Clients:         store,
+		ServiceAccounts: store,
+		Roles:           store,
+		Tenants:         store,
+		Webauthn:        store,
+		Cache:           NewMemoryCache(),
+	}, c)
+	if err != nil {
+		t.Fatalf("NewAuthManager: %v", err)
+	}
+	return a, store
+}
+
+func TestNewPasswordHasher(t *testing.T) {
+	tests := []struct {
+		name    string
+		wantErr bool
+	}{
+		{HASHER_ARGON2ID, false},
+		{HASHER_BCRYPT, false},
+		{HASHER_SHA256, true},
+		{"md5", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := NewPasswordHasher(tt.name)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewPasswordHasher: got %v, want error %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, err := NewAuthManager(Stores{}, AuthManagerConfig{Hasher: Sha256Hasher{}}); err == nil {
+		t.Fatalf("NewAuthManager accepted sha256 as the preferred hasher")
+	}
+}
+
+func TestMigratingHasher(t *testing.T) {
+	h := newMigratingHasher(testHasher)
+	tests := []struct {
+		name       string
+		hasher     PasswordHasher
+		wantRehash bool
+	}{
+		{"preferred", testHasher, false},
+		{"stronger argon2id", Argon2idHasher{Time: 2, Memory: 2048, Threads: 1, KeyLen: 32, SaltLen: 16}, false},
+		{"weaker argon2id", Argon2idHasher{Time: 1, Memory: 512, Threads: 1, KeyLen: 32, SaltLen: 16}, true},
+		{"bcrypt", BcryptHasher{Cost: 4}, true},
+		{"legacy sha256", Sha256Hasher{}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoded, err := tt.hasher.Hash("secret")
+			if err != nil {
+				t.Fatalf("Hash: %v", err)
+			}
+			if ok, err := h.Verify(encoded, "secret"); !ok || err != nil {
+				t.Fatalf("Verify of the password: got %v, %v", ok, err)
+			}
+			if ok, err := h.Verify(encoded, "wrong"); ok || err != nil {
+				t.Fatalf("Verify of a wrong password: got %v, %v", ok, err)
+			}
+			if got := h.NeedsRehash(encoded); got != tt.wantRehash {
+				t.Fatalf("NeedsRehash: got %v, want %v", got, tt.wantRehash)
+			}
+		})
+	}
+	if _, err := h.Verify("plain", "plain"); err == nil {
+		t.Fatalf("Verify accepted a hash of unknown format")
+	}
+}
+
+func TestLoginUser(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", ""); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	tests := []struct {
+		name     string
+		tenantId string
+		username string
+		password string
+		wantErr  error
+	}{
+		{"right password", "", "alice", "secret", nil},
+		{"wrong password", "", "alice", "wrong", ErrInvalidCredentials},
+		{"unknown user", "", "bob", "secret", ErrInvalidCredentials},
+		{"unknown tenant", "acme", "alice", "secret", ErrInvalidCredentials},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, err := a.LoginUser(ctx, tt.tenantId, tt.username, tt.password, ClientInfo{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("LoginUser: got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			u, got, err := a.GetUserBySessionId(ctx, s.Id)
+			if err != nil {
+				t.Fatalf("GetUserBySessionId: %v", err)
+			}
+			if u.Username != tt.username || got.Id != s.Id {
+				t.Fatalf("GetUserBySessionId: got %s and %s", u.Username, got.Id)
+			}
+		})
+	}
+}
+
+func TestPasswordHashMigration(t *testing.T) {
+	ctx := context.Background()
+	legacy := func(h PasswordHasher) string {
+		hash, err := h.Hash("secret")
+		if err != nil {
+			t.Fatalf("Hash: %v", err)
+		}
+		return hash
+	}
+	tests := []struct {
+		name string
+		hash string
+	}{
+		{"sha256", legacy(Sha256Hasher{})},
+		{"bcrypt", legacy(BcryptHasher{Cost: 4})},
+		{"weaker argon2id", legacy(Argon2idHasher{Time: 1, Memory: 512, Threads: 1, KeyLen: 32, SaltLen: 16})},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, store := newTestAuthManager(t, AuthManagerConfig{})
+			if err := store.CreateUser(ctx, newUser(DEFAULT_TENANT, "alice", tt.hash, "")); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			if _, _, err := a.LoginUser(ctx, "", "alice", "wrong", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+				t.Fatalf("LoginUser with wrong password: got %v", err)
+			}
+			if u, _ := store.GetUser(ctx, DEFAULT_TENANT, "alice"); u.Password != tt.hash {
+				t.Fatalf("a wrong password replaced the hash")
+			}
+			if _, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{}); err != nil {
+				t.Fatalf("LoginUser: %v", err)
+			}
+			u, _ := store.GetUser(ctx, DEFAULT_TENANT, "alice")
+			if !testHasher.Identify(u.Password) || testHasher.NeedsRehash(u.Password) {
+				t.Fatalf("hash was not migrated: %s", u.Password)
+			}
+			if _, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{}); err != nil {
+				t.Fatalf("LoginUser after migration: %v", err)
+			}
+		})
+	}
+}
+
+func TestCacheSetSessionPastDeadline(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	s := Session{Id: "0f8fad5b-d9cb-469f-a165-70867728950e", ValidThrough: utils.GetNowTz().Add(time.Hour)}
+	key := a.composeSessionKey(s.Id)
+	if err := a.cacheSetSession(ctx, sessionEntry{Session: s}); err != nil {
+		t.Fatalf("cacheSetSession: %v", err)
+	}
+	if _, err := a.cache.Get(ctx, key); err != nil {
+		t.Fatalf("cached session: %v", err)
+	}
+	s.ValidThrough = utils.GetNowTz().Add(-time.Second)
+	if err := a.cacheSetSession(ctx, sessionEntry{Session: s}); err != nil {
+		t.Fatalf("cacheSetSession past the deadline: %v", err)
+	}
+	if _, err := a.cache.Get(ctx, key); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("session past its deadline: got %v, want ErrCacheMiss", err)
+	}
+}
+
+func TestRevokeAllSessions(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name        string
+		keepCurrent bool
+	}{
+		{"everywhere", false},
+		{"keep current", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestAuthManager(t, AuthManagerConfig{})
+			current, err := a.SignupAndLogin(ctx, "", "alice", "secret", "", ClientInfo{})
+			if err != nil {
+				t.Fatalf("SignupAndLogin: %v", err)
+			}
+			other, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+			if err != nil {
+				t.Fatalf("LoginUser: %v", err)
+			}
+			stranger, err := a.SignupAndLogin(ctx, "", "bob", "secret", "", ClientInfo{})
+			if err != nil {
+				t.Fatalf("SignupAndLogin: %v", err)
+			}
+			sessions, err := a.ListSessions(ctx, current.Id)
+			if err != nil {
+				t.Fatalf("ListSessions: %v", err)
+			}
+			if len(sessions) != 2 {
+				t.Fatalf("ListSessions: got %d sessions, want 2", len(sessions))
+			}
+			if err := a.RevokeAllSessions(ctx, current.Id, tt.keepCurrent); err != nil {
+				t.Fatalf("RevokeAllSessions: %v", err)
+			}
+			if _, _, err := a.GetUserBySessionId(ctx, other.Id); !errors.Is(err, ErrSessionExpired) {
+				t.Fatalf("other session: got %v, want ErrSessionExpired", err)
+			}
+			if _, _, err := a.GetUserBySessionId(ctx, current.Id); (err == nil) != tt.keepCurrent {
+				t.Fatalf("current session: got %v", err)
+			}
+			if _, _, err := a.GetUserBySessionId(ctx, stranger.Id); err != nil {
+				t.Fatalf("session of another user: %v", err)
+			}
+			if _, err := a.ListSessions(ctx, "unknown"); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("ListSessions with unknown session: got %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+}
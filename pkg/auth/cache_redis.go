@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisCache implements SessionCache on top of a redis client.
+type redisCache struct {
+	client *redis.Client
+}
+
+func newRedisCache(client *redis.Client) *redisCache {
+	return &redisCache{client}
+}
+
+func (r *redisCache) Ping(ctx context.Context) error {
+	return r.mapError(r.client.Ping(ctx).Err())
+}
+
+func (r *redisCache) Close() {
+	r.client.Close()
+}
+
+func (r *redisCache) Get(ctx context.Context, key string) ([]byte, error) {
+	res, err := r.client.Get(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return res, r.mapError(err)
+}
+
+func (r *redisCache) Take(ctx context.Context, key string) ([]byte, error) {
+	res, err := r.client.GetDel(ctx, key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrCacheMiss
+	}
+	return res, r.mapError(err)
+}
+
+func (r *redisCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return r.mapError(r.client.Set(ctx, key, value, ttl).Err())
+}
+
+func (r *redisCache) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.mapError(r.client.Del(ctx, keys...).Err())
+}
+
+func (r *redisCache) mapError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return backendUnavailable(err)
+}
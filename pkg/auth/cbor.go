@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// CBOR (RFC 8949) major types.
+const (
+	cborUint   = 0
+	cborNegInt = 1
+	cborBytes  = 2
+	cborText   = 3
+	cborArray  = 4
+	cborMap    = 5
+	cborTag    = 6
+	cborSimple = 7
+)
+
+// cborMaxDepth bounds the nesting of decoded items.
+const cborMaxDepth = 16
+
+var errCborTruncated = errors.New("cbor: truncated item")
+
+// decodeCbor decodes the first CBOR item of data, as used by WebAuthn, and
+// returns it along with the bytes that follow it. Only definite lengths are
+// supported, as the CTAP2 canonical encoding requires. Integers decode to
+// int64, byte strings to []byte, text to string, arrays to []any, maps to
+// map[any]any, tags to their content and simple values to bool or nil.
+func decodeCbor(data []byte) (any, []byte, error) {
+	return decodeCborItem(data, 0)
+}
+
+func decodeCborItem(data []byte, depth int) (any, []byte, error) {
+	if depth > cborMaxDepth {
+		return nil, nil, fmt.Errorf("cbor: nested too deeply")
+	}
+	if len(data) == 0 {
+		return nil, nil, errCborTruncated
+	}
+	major, info := data[0]>>5, data[0]&0x1f
+	arg, rest, err := cborArgument(info, data[1:])
+	if err != nil {
+		return nil, nil, err
+	}
+	switch major {
+	case cborUint:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("cbor: integer overflow")
+		}
+		return int64(arg), rest, nil
+	case cborNegInt:
+		if arg > 1<<63-1 {
+			return nil, nil, fmt.Errorf("cbor: integer overflow")
+		}
+		return -1 - int64(arg), rest, nil
+	case cborBytes, cborText:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errCborTruncated
+		}
+		if major == cborText {
+			return string(rest[:arg]), rest[arg:], nil
+		}
+		return append([]byte(nil), rest[:arg]...), rest[arg:], nil
+	case cborArray:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errCborTruncated
+		}
+		items := make([]any, 0, arg)
+		for i := uint64(0); i < arg; i++ {
+			var item any
+			item, rest, err = decodeCborItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			items = append(items, item)
+		}
+		return items, rest, nil
+	case cborMap:
+		if arg > uint64(len(rest)) {
+			return nil, nil, errCborTruncated
+		}
+		m := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			var k, v any
+			k, rest, err = decodeCborItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, fmt.Errorf("cbor: unsupported map key %T", k)
+			}
+			if _, ok := m[k]; ok {
+				return nil, nil, fmt.Errorf("cbor: duplicate map key %v", k)
+			}
+			v, rest, err = decodeCborItem(rest, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			m[k] = v
+		}
+		return m, rest, nil
+	case cborTag:
+		return decodeCborItem(rest, depth+1)
+	}
+	switch info {
+	case 20:
+		return false, rest, nil
+	case 21:
+		return true, rest, nil
+	case 22, 23:
+		return nil, rest, nil
+	}
+	return nil, nil, fmt.Errorf("cbor: unsupported simple value %d", info)
+}
+
+// cborArgument reads the argument of an item given the additional
+// information of its initial byte.
+func cborArgument(info byte, data []byte) (uint64, []byte, error) {
+	switch {
+	case info < 24:
+		return uint64(info), data, nil
+	case info == 24 && len(data) >= 1:
+		return uint64(data[0]), data[1:], nil
+	case info == 25 && len(data) >= 2:
+		return uint64(binary.BigEndian.Uint16(data)), data[2:], nil
+	case info == 26 && len(data) >= 4:
+		return uint64(binary.BigEndian.Uint32(data)), data[4:], nil
+	case info == 27 && len(data) >= 8:
+		return binary.BigEndian.Uint64(data), data[8:], nil
+	case info >= 24 && info <= 27:
+		return 0, nil, errCborTruncated
+	}
+	return 0, nil, fmt.Errorf("cbor: unsupported additional information %d", info)
+}
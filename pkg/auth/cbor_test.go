@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// cborPairs is a CBOR map keeping the order of its keys, as CTAP2 does.
+type cborPairs [][2]any
+
+func appendCborHead(b []byte, major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return append(b, major<<5|byte(n))
+	case n <= 0xff:
+		return append(b, major<<5|24, byte(n))
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16(append(b, major<<5|25), uint16(n))
+	}
+	return binary.BigEndian.AppendUint32(append(b, major<<5|26), uint32(n))
+}
+
+// encodeCbor encodes the subset of CBOR that authenticators send.
+func encodeCbor(v any) []byte {
+	return appendCbor(nil, v)
+}
+
+func appendCbor(b []byte, v any) []byte {
+	switch v := v.(type) {
+	case int:
+		if v >= 0 {
+			return appendCborHead(b, cborUint, uint64(v))
+		}
+		return appendCborHead(b, cborNegInt, uint64(-1-v))
+	case []byte:
+		return append(appendCborHead(b, cborBytes, uint64(len(v))), v...)
+	case string:
+		return append(appendCborHead(b, cborText, uint64(len(v))), v...)
+	case []any:
+		b = appendCborHead(b, cborArray, uint64(len(v)))
+		for _, item := range v {
+			b = appendCbor(b, item)
+		}
+		return b
+	case cborPairs:
+		b = appendCborHead(b, cborMap, uint64(len(v)))
+		for _, pair := range v {
+			b = appendCbor(appendCbor(b, pair[0]), pair[1])
+		}
+		return b
+	}
+	panic(fmt.Sprintf("cannot encode %T to CBOR", v))
+}
+
+func TestDecodeCbor(t *testing.T) {
+	tests := []struct {
+		name     string
+		data     []byte
+		want     any
+		wantRest []byte
+		wantErr  bool
+	}{
+		{"small integer", []byte{0x17}, int64(23), nil, false},
+		{"negative integer", encodeCbor(-257), int64(-257), nil, false},
+		{"byte string", encodeCbor([]byte{1, 2, 3}), []byte{1, 2, 3}, nil, false},
+		{"text", encodeCbor("packed"), "packed", nil, false},
+		{"array", encodeCbor([]any{1, "a"}), []any{int64(1), "a"}, nil, false},
+		{"map", encodeCbor(cborPairs{{1, 2}, {"fmt", "none"}}), map[any]any{int64(1): int64(2), "fmt": "none"}, nil, false},
+		{"tag", []byte{0xc2, 0x41, 0x01}, []byte{1}, nil, false},
+		{"simple values", []byte{0xf5, 0xf6}, true, []byte{0xf6}, false},
+		{"trailing bytes", []byte{0x01, 0x02}, int64(1), []byte{0x02}, false},
+		{"empty", nil, nil, nil, true},
+		{"truncated byte string", []byte{0x45, 0x01}, nil, nil, true},
+		{"truncated argument", []byte{0x19, 0x01}, nil, nil, true},
+		{"indefinite length", []byte{0x5f, 0x41, 0x01, 0xff}, nil, nil, true},
+		{"duplicate map key", []byte{0xa2, 0x01, 0x01, 0x01, 0x02}, nil, nil, true},
+		{"byte string map key", []byte{0xa1, 0x41, 0x01, 0x01}, nil, nil, true},
+		{"huge array", []byte{0x9a, 0xff, 0xff, 0xff, 0xff}, nil, nil, true},
+		{"integer overflow", []byte{0x1b, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff}, nil, nil, true},
+		{"nested too deeply", append(bytes.Repeat([]byte{0x81}, cborMaxDepth+2), 0x01), nil, nil, true},
+		{"float", []byte{0xf9, 0x3c, 0x00}, nil, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, rest, err := decodeCbor(tt.data)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("decodeCbor: got %v, want error %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) || !bytes.Equal(rest, tt.wantRest) {
+				t.Fatalf("decodeCbor: got %#v and rest %x, want %#v and %x", got, rest, tt.want, tt.wantRest)
+			}
+		})
+	}
+}
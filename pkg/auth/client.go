@@ -0,0 +1,155 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"log/slog"
+	"net"
+	"net/url"
+	"slices"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	MAX_CLIENT_NAME_LENGTH = 64
+	MAX_REDIRECT_URIS      = 10
+)
+
+// OAuthClient is an application registered to sign users in with SAM.
+// Public clients, such as single-page or native apps, cannot keep a secret
+// and rely on PKCE alone.
+type OAuthClient struct {
+	Id           string
+	Name         string
+	SecretHash   string // empty for public clients
+	RedirectUris []string
+	CreatedAt    time.Time
+}
+
+func (c OAuthClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// allowsRedirect compares redirect uris exactly, as OAuth 2.0 security
+// best practices require.
+func (c OAuthClient) allowsRedirect(uri string) bool {
+	return slices.Contains(c.RedirectUris, uri)
+}
+
+func hashClientSecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newClientCredentials() (string, string, error) {
+	b := make([]byte, 48)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	return hex.EncodeToString(b[:16]), base64.RawURLEncoding.EncodeToString(b[16:]), nil
+}
+
+// validateRedirectUri accepts absolute uris without fragment. Plain http
+// is only allowed for loopback addresses, used by native apps.
+func validateRedirectUri(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Host == "" && u.Scheme == "https" {
+		return invalidArgument("malformed redirect uri %q", uri)
+	}
+	if u.Fragment != "" {
+		return invalidArgument("redirect uri %q must not have a fragment", uri)
+	}
+	if u.Scheme == "http" {
+		ip := net.ParseIP(u.Hostname())
+		if u.Hostname() != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return invalidArgument("redirect uri %q must use https", uri)
+		}
+	}
+	return nil
+}
+
+// CreateClient registers a client and returns it along with its secret,
+// which is not stored and cannot be retrieved later. Public clients get
+// no secret.
+func (a *AuthManager) CreateClient(ctx context.Context, name string, redirectUris []string, public bool) (OAuthClient, string, error) {
+	if name == "" || len(name) > MAX_CLIENT_NAME_LENGTH {
+		return OAuthClient{}, "", invalidArgument("client name must be 1 to %d characters", MAX_CLIENT_NAME_LENGTH)
+	}
+	if len(redirectUris) == 0 || len(redirectUris) > MAX_REDIRECT_URIS {
+		return OAuthClient{}, "", invalidArgument("a client needs 1 to %d redirect uris", MAX_REDIRECT_URIS)
+	}
+	for _, uri := range redirectUris {
+		if err := validateRedirectUri(uri); err != nil {
+			return OAuthClient{}, "", err
+		}
+	}
+	id, secret, err := newClientCredentials()
+	if err != nil {
+		return OAuthClient{}, "", err
+	}
+	c := OAuthClient{
+		Id:           id,
+		Name:         name,
+		SecretHash:   hashClientSecret(secret),
+		RedirectUris: redirectUris,
+		CreatedAt:    utils.GetNowTz(),
+	}
+	if public {
+		c.SecretHash, secret = "", ""
+	}
+	if err := a.clients.CreateClient(ctx, c); err != nil {
+		return OAuthClient{}, "", err
+	}
+	slog.InfoContext(ctx, "Registered client", "client_id", c.Id, "name", c.Name, "public", public)
+	return c, secret, nil
+}
+
+func (a *AuthManager) GetClient(ctx context.Context, id string) (OAuthClient, error) {
+	c, err := a.clients.GetClient(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return OAuthClient{}, ErrClientNotFound
+	}
+	return c, err
+}
+
+func (a *AuthManager) ListClients(ctx context.Context) ([]OAuthClient, error) {
+	return a.clients.ListClients(ctx)
+}
+
+// DeleteClient unregisters a client. Sessions its users opened stay valid.
+func (a *AuthManager) DeleteClient(ctx context.Context, id string) error {
+	err := a.clients.DeleteClient(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrClientNotFound
+	}
+	return err
+}
+
+// AuthenticateClient checks the secret of a confidential client. Public
+// clients authenticate with their id alone.
+func (a *AuthManager) AuthenticateClient(ctx context.Context, id, secret string) (OAuthClient, error) {
+	c, err := a.clients.GetClient(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return OAuthClient{}, ErrInvalidClient
+	}
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	if c.Public() {
+		if secret != "" {
+			return OAuthClient{}, ErrInvalidClient
+		}
+		return c, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(hashClientSecret(secret)), []byte(c.SecretHash)) != 1 {
+		return OAuthClient{}, ErrInvalidClient
+	}
+	return c, nil
+}
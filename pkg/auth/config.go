@@ -1,11 +1,402 @@
 package auth
 
 import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/notify"
 	"github.com/JustDean/sam/pkg/postgres"
 	redis_utils "github.com/JustDean/sam/pkg/redis"
 )
 
+const (
+	BACKEND_POSTGRES = "postgres" // Postgres for storage and Redis for cache
+	BACKEND_MEMORY   = "memory"   // everything in process memory
+)
+
 type AuthManagerConfig struct {
-	Db    postgres.Config
-	Cache redis_utils.Config
+	Backend string
+	Db      postgres.Config
+	Cache   redis_utils.Config
+	// Hasher is used for new password hashes. Defaults to argon2id.
+	Hasher  PasswordHasher
+	Session SessionConfig
+	Limiter LimiterConfig
+	Mfa     MfaConfig
+	// Notifier delivers messages such as password resets. Defaults to logging them.
+	Notifier      notify.Notifier
+	PasswordReset PasswordResetConfig
+	AccessToken   AccessTokenConfig
+	SigningKeys   SigningKeyConfig
+	Oidc          OidcConfig
+	ServiceToken  ServiceTokenConfig
+	Rbac          RbacConfig
+	// EmailVerification signs its tokens with a key derived from the master
+	// key of SigningKeys.
+	EmailVerification EmailVerificationConfig
+	Passwordless      PasswordlessConfig
+	Webauthn          WebauthnConfig
+}
+
+type SessionConfig struct {
+	// AbsoluteTimeout caps the lifetime of a session regardless of activity.
+	AbsoluteTimeout time.Duration
+	// IdleTimeout expires a session without activity. Zero disables it.
+	IdleTimeout time.Duration
+	// TouchInterval throttles writes of session activity.
+	TouchInterval time.Duration
+}
+
+func DefaultSessionConfig() SessionConfig {
+	return SessionConfig{
+		AbsoluteTimeout: 10 * 24 * time.Hour,
+		IdleTimeout:     0,
+		TouchInterval:   time.Minute,
+	}
+}
+
+// normalize fills in defaults and keeps the touch interval short enough
+// for an active session to be extended before it goes idle.
+func (c SessionConfig) normalize() SessionConfig {
+	d := DefaultSessionConfig()
+	if c.AbsoluteTimeout <= 0 {
+		c.AbsoluteTimeout = d.AbsoluteTimeout
+	}
+	if c.TouchInterval <= 0 {
+		c.TouchInterval = d.TouchInterval
+	}
+	if c.IdleTimeout > 0 && c.TouchInterval > c.IdleTimeout/2 {
+		c.TouchInterval = c.IdleTimeout / 2
+	}
+	return c
+}
+
+type MfaConfig struct {
+	// Issuer names the service in authenticator apps.
+	Issuer string
+	// ChallengeTtl is how long a user has to enter a code after the password.
+	ChallengeTtl time.Duration
+}
+
+func DefaultMfaConfig() MfaConfig {
+	return MfaConfig{
+		Issuer:       "SAM",
+		ChallengeTtl: 5 * time.Minute,
+	}
+}
+
+func (c MfaConfig) normalize() MfaConfig {
+	d := DefaultMfaConfig()
+	if c.Issuer == "" {
+		c.Issuer = d.Issuer
+	}
+	if c.ChallengeTtl <= 0 {
+		c.ChallengeTtl = d.ChallengeTtl
+	}
+	return c
+}
+
+type PasswordResetConfig struct {
+	// TokenTtl is how long a reset token can be used.
+	TokenTtl time.Duration
+	// Link, if set, is sent instead of the bare token, with "{token}"
+	// replaced by it, e.g. "https://example.com/reset?token={token}".
+	Link string
+}
+
+func DefaultPasswordResetConfig() PasswordResetConfig {
+	return PasswordResetConfig{TokenTtl: time.Hour}
+}
+
+func (c PasswordResetConfig) normalize() PasswordResetConfig {
+	if c.TokenTtl <= 0 {
+		c.TokenTtl = DefaultPasswordResetConfig().TokenTtl
+	}
+	return c
+}
+
+type AccessTokenConfig struct {
+	// Enabled makes logins return a signed JWT along with the session,
+	// signed with the keys of SigningKeys.
+	Enabled  bool
+	Ttl      time.Duration
+	Issuer   string
+	Audience string // optional
+	// KeyFile is a PEM private key (Ed25519, P-256 or RSA) imported into
+	// the signing keys as the active one, unless they already hold it.
+	//
+	// Deprecated: signing keys are generated and rotated by SAM. KeyFile
+	// only carries over the key of deployments predating key rotation.
+	KeyFile string
+	// Algorithm is used when SigningKeys has none.
+	//
+	// Deprecated: use SigningKeyConfig.Algorithm.
+	Algorithm string
+}
+
+func DefaultAccessTokenConfig() AccessTokenConfig {
+	return AccessTokenConfig{
+		Ttl:    5 * time.Minute,
+		Issuer: "sam",
+	}
+}
+
+func (c AccessTokenConfig) normalize() AccessTokenConfig {
+	d := DefaultAccessTokenConfig()
+	if c.Ttl <= 0 {
+		c.Ttl = d.Ttl
+	}
+	if c.Issuer == "" {
+		c.Issuer = d.Issuer
+	}
+	return c
+}
+
+type OidcConfig struct {
+	// Enabled makes SAM an OpenID Connect provider, signing ID tokens
+	// with the keys of SigningKeys.
+	Enabled bool
+	// Issuer is the public URL of the HTTP server, e.g. https://sam.example.com.
+	Issuer string
+	// CodeTtl is how long a client has to exchange an authorization code.
+	CodeTtl    time.Duration
+	IdTokenTtl time.Duration
+	// AccessTokenTtl is how long an access token issued to a client is
+	// valid, at most as long as the session it was issued for.
+	AccessTokenTtl time.Duration
+}
+
+func DefaultOidcConfig() OidcConfig {
+	return OidcConfig{
+		CodeTtl:        time.Minute,
+		IdTokenTtl:     time.Hour,
+		AccessTokenTtl: 10 * time.Minute,
+	}
+}
+
+func (c OidcConfig) normalize() (OidcConfig, error) {
+	d := DefaultOidcConfig()
+	if c.CodeTtl <= 0 {
+		c.CodeTtl = d.CodeTtl
+	}
+	if c.IdTokenTtl <= 0 {
+		c.IdTokenTtl = d.IdTokenTtl
+	}
+	if c.AccessTokenTtl <= 0 {
+		c.AccessTokenTtl = d.AccessTokenTtl
+	}
+	c.Issuer = strings.TrimSuffix(c.Issuer, "/")
+	if !c.Enabled {
+		return c, nil
+	}
+	u, err := url.Parse(c.Issuer)
+	if err != nil || !u.IsAbs() || u.Host == "" || u.RawQuery != "" || u.Fragment != "" {
+		return c, fmt.Errorf("openid connect issuer must be an absolute URL without query, got %q", c.Issuer)
+	}
+	return c, nil
+}
+
+type ServiceTokenConfig struct {
+	// Ttl is how long a token issued to a service account is valid.
+	Ttl time.Duration
+}
+
+func DefaultServiceTokenConfig() ServiceTokenConfig {
+	return ServiceTokenConfig{Ttl: time.Hour}
+}
+
+func (c ServiceTokenConfig) normalize() ServiceTokenConfig {
+	if c.Ttl <= 0 {
+		c.Ttl = DefaultServiceTokenConfig().Ttl
+	}
+	return c
+}
+
+type RbacConfig struct {
+	// RoleCacheTtl is how long the roles of a user are cached with a session.
+	// Grants and revocations drop them right away; it bounds how long a change
+	// missed by the cache, e.g. when Redis is unreachable, goes unnoticed.
+	RoleCacheTtl time.Duration
+}
+
+func DefaultRbacConfig() RbacConfig {
+	return RbacConfig{RoleCacheTtl: 5 * time.Minute}
+}
+
+func (c RbacConfig) normalize() RbacConfig {
+	if c.RoleCacheTtl <= 0 {
+		c.RoleCacheTtl = DefaultRbacConfig().RoleCacheTtl
+	}
+	return c
+}
+
+// Policies for users who have not verified their email address.
+const (
+	UNVERIFIED_ALLOW    = "allow"    // sign in like verified users
+	UNVERIFIED_RESTRICT = "restrict" // sign in, but Authorize denies everything
+	UNVERIFIED_DENY     = "deny"     // cannot sign in
+)
+
+type EmailVerificationConfig struct {
+	// Enabled sends verification tokens to the email address given on
+	// signup or set on the profile.
+	Enabled bool
+	// TokenTtl is how long a verification token can be used.
+	TokenTtl time.Duration
+	// Link, if set, is sent instead of the bare token, with "{token}"
+	// replaced by it, e.g. "https://example.com/verify?token={token}".
+	Link string
+	// UnverifiedPolicy applies to users without a verified email address.
+	// Unless it is UNVERIFIED_ALLOW, Signup requires an email address.
+	UnverifiedPolicy string
+}
+
+func DefaultEmailVerificationConfig() EmailVerificationConfig {
+	return EmailVerificationConfig{TokenTtl: 24 * time.Hour, UnverifiedPolicy: UNVERIFIED_ALLOW}
+}
+
+// normalize fills in defaults; the policy only applies if verification is enabled.
+func (c EmailVerificationConfig) normalize() (EmailVerificationConfig, error) {
+	d := DefaultEmailVerificationConfig()
+	if c.TokenTtl <= 0 {
+		c.TokenTtl = d.TokenTtl
+	}
+	if !c.Enabled || c.UnverifiedPolicy == "" {
+		c.UnverifiedPolicy = d.UnverifiedPolicy
+	}
+	switch c.UnverifiedPolicy {
+	case UNVERIFIED_ALLOW, UNVERIFIED_RESTRICT, UNVERIFIED_DENY:
+		return c, nil
+	}
+	return c, fmt.Errorf("unknown policy for unverified users %q", c.UnverifiedPolicy)
+}
+
+type PasswordlessConfig struct {
+	// Enabled lets users sign in with a one-time code or magic link
+	// instead of their password.
+	Enabled bool
+	// CodeLength is the number of digits of one-time codes.
+	CodeLength int
+	// Ttl is how long a code and its link can be used.
+	Ttl time.Duration
+	// MaxAttempts is the number of wrong codes voiding a login.
+	MaxAttempts int
+	// Link, if set, is sent along with the code, with "{token}" replaced
+	// by the magic link token, e.g. "https://example.com/login?token={token}".
+	Link string
+	// Channels deliver codes by name. PASSWORDLESS_CHANNEL_EMAIL, sending
+	// them with the notifier, is added unless set.
+	Channels map[string]PasswordlessChannel
+}
+
+func DefaultPasswordlessConfig() PasswordlessConfig {
+	return PasswordlessConfig{CodeLength: 6, Ttl: 10 * time.Minute, MaxAttempts: 5}
+}
+
+func (c PasswordlessConfig) normalize() PasswordlessConfig {
+	d := DefaultPasswordlessConfig()
+	if c.CodeLength <= 0 {
+		c.CodeLength = d.CodeLength
+	}
+	if c.Ttl <= 0 {
+		c.Ttl = d.Ttl
+	}
+	if c.MaxAttempts <= 0 {
+		c.MaxAttempts = d.MaxAttempts
+	}
+	return c
+}
+
+type WebauthnConfig struct {
+	// Enabled lets users register passkeys and sign in with them.
+	Enabled bool
+	// RpId is the domain passkeys are scoped to, e.g. "example.com".
+	RpId string
+	// RpName is shown by authenticators. Defaults to "SAM".
+	RpName string
+	// Origins the ceremonies may be run from. Defaults to https://RpId.
+	Origins []string
+	// ChallengeTtl is how long a ceremony can take.
+	ChallengeTtl time.Duration
+	// RequireUserVerification makes authenticators check a PIN or
+	// biometric rather than only the presence of the user.
+	RequireUserVerification bool
+}
+
+func DefaultWebauthnConfig() WebauthnConfig {
+	return WebauthnConfig{RpName: "SAM", ChallengeTtl: 5 * time.Minute}
+}
+
+// normalize fills in defaults; an RP id is required if WebAuthn is enabled.
+func (c WebauthnConfig) normalize() (WebauthnConfig, error) {
+	d := DefaultWebauthnConfig()
+	if c.RpName == "" {
+		c.RpName = d.RpName
+	}
+	if c.ChallengeTtl <= 0 {
+		c.ChallengeTtl = d.ChallengeTtl
+	}
+	if !c.Enabled {
+		return c, nil
+	}
+	if c.RpId == "" {
+		return c, fmt.Errorf("WebAuthn needs an RP id")
+	}
+	if len(c.Origins) == 0 {
+		c.Origins = []string{"https://" + c.RpId}
+	}
+	return c, nil
+}
+
+type SigningKeyConfig struct {
+	// Algorithm of new keys: EdDSA, ES256 or RS256. Existing keys keep theirs.
+	Algorithm string
+	// MasterKey encrypts the private keys at rest. The memory backend
+	// generates one if missing; the Postgres backend requires it. TOTP
+	// secrets are encrypted with it too if set, and stored as is otherwise.
+	MasterKey []byte
+	// RotationInterval is how long a key signs before being replaced.
+	RotationInterval time.Duration
+	// PublishDelay is how long a new key is published before it signs,
+	// so that verifiers caching the JWKS pick it up in time.
+	PublishDelay time.Duration
+	// Overlap is how long a replaced key stays published. It is at least
+	// the lifetime of the tokens it signed.
+	Overlap time.Duration
+	// RefreshInterval is how often keys changed by other instances are loaded.
+	RefreshInterval time.Duration
+}
+
+func DefaultSigningKeyConfig() SigningKeyConfig {
+	return SigningKeyConfig{
+		Algorithm:        ALGORITHM_EDDSA,
+		RotationInterval: 30 * 24 * time.Hour,
+		PublishDelay:     10 * time.Minute,
+		Overlap:          time.Hour,
+		RefreshInterval:  time.Minute,
+	}
+}
+
+// normalize fills in defaults and keeps replaced keys published as long
+// as the tokens they signed are valid.
+func (c SigningKeyConfig) normalize(tokenTtl time.Duration) SigningKeyConfig {
+	d := DefaultSigningKeyConfig()
+	if c.Algorithm == "" {
+		c.Algorithm = d.Algorithm
+	}
+	if c.RotationInterval <= 0 {
+		c.RotationInterval = d.RotationInterval
+	}
+	if c.PublishDelay <= 0 {
+		c.PublishDelay = d.PublishDelay
+	}
+	if c.Overlap < tokenTtl {
+		c.Overlap = tokenTtl
+	}
+	if c.RefreshInterval <= 0 {
+		c.RefreshInterval = d.RefreshInterval
+	}
+	return c
 }
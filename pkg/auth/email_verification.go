@@ -0,0 +1,178 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/notify"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// VERIFICATION_KEY_LABEL derives the key signing verification tokens.
+const VERIFICATION_KEY_LABEL = "sam email verification"
+
+// verificationClaims are carried by verification tokens. The email address
+// is part of them so that a token is void once the address changes.
+type verificationClaims struct {
+	TenantId  string `json:"tid"`
+	Username  string `json:"sub"`
+	Email     string `json:"email"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+// signVerificationToken returns the base64url encoded claims and their
+// HMAC-SHA256, separated by a dot.
+func (a *AuthManager) signVerificationToken(c verificationClaims) (string, error) {
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	mac := hmac.New(sha256.New, a.verificationKey)
+	mac.Write([]byte(encoded))
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil)), nil
+}
+
+// parseVerificationToken checks the signature and expiry of a token.
+func (a *AuthManager) parseVerificationToken(token string, now time.Time) (verificationClaims, error) {
+	var c verificationClaims
+	encoded, sig, ok := strings.Cut(token, ".")
+	if !ok {
+		return c, ErrInvalidVerificationToken
+	}
+	got, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil {
+		return c, ErrInvalidVerificationToken
+	}
+	mac := hmac.New(sha256.New, a.verificationKey)
+	mac.Write([]byte(encoded))
+	if !hmac.Equal(got, mac.Sum(nil)) {
+		return c, ErrInvalidVerificationToken
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil || json.Unmarshal(payload, &c) != nil {
+		return c, ErrInvalidVerificationToken
+	}
+	if now.Unix() >= c.ExpiresAt {
+		return c, ErrInvalidVerificationToken
+	}
+	return c, nil
+}
+
+func (a *AuthManager) verificationMessage(u User, token string, expiresAt time.Time) notify.Message {
+	var body strings.Builder
+	body.WriteString("Please confirm this email address for your account.\n\n")
+	if a.verificationConfig.Link != "" {
+		fmt.Fprintf(&body, "Follow this link to confirm it:\n%s\n\n",
+			strings.ReplaceAll(a.verificationConfig.Link, "{token}", token))
+	} else {
+		fmt.Fprintf(&body, "Use this token to confirm it:\n%s\n\n", token)
+	}
+	fmt.Fprintf(&body, "It is valid until %s.\n", expiresAt.Format(time.RFC1123))
+	body.WriteString("If you did not sign up, ignore this message.\n")
+	return notify.Message{
+		Username: u.Subject(),
+		Email:    u.Email,
+		Subject:  "Confirm your email address",
+		Body:     body.String(),
+	}
+}
+
+// sendVerification sends a verification token to the email address of the
+// user, if verification is enabled and the address is not verified yet.
+// Delivery failures are logged, as the user can ask for another token.
+func (a *AuthManager) sendVerification(ctx context.Context, u User) error {
+	if !a.verificationConfig.Enabled || u.Email == "" || u.EmailVerified() {
+		return nil
+	}
+	expiresAt := utils.GetNowTz().Add(a.verificationConfig.TokenTtl)
+	token, err := a.signVerificationToken(verificationClaims{
+		TenantId:  u.TenantId,
+		Username:  u.Username,
+		Email:     u.Email,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	if err := a.notifier.Notify(ctx, a.verificationMessage(u, token, expiresAt)); err != nil {
+		slog.ErrorContext(ctx, "Error sending email verification", "tenant_id", u.TenantId, "username", u.Username, "error", err)
+		return nil
+	}
+	slog.InfoContext(ctx, "Sent email verification", "tenant_id", u.TenantId, "username", u.Username)
+	return nil
+}
+
+// checkSignupEmail validates the email address given on signup, which is
+// required unless unverified users are allowed in.
+func (a *AuthManager) checkSignupEmail(email string) error {
+	if email == "" && a.verificationConfig.UnverifiedPolicy != UNVERIFIED_ALLOW {
+		return invalidArgument("an email address is required")
+	}
+	return validateEmail(email)
+}
+
+// unverified tells whether the policy for unverified users applies to u.
+func (a *AuthManager) unverified(u User) bool {
+	return a.verificationConfig.Enabled && !u.EmailVerified()
+}
+
+// restricted tells whether the sessions of u are restricted.
+func (a *AuthManager) restricted(u User) bool {
+	return a.unverified(u) && a.verificationConfig.UnverifiedPolicy == UNVERIFIED_RESTRICT
+}
+
+// RequestEmailVerification sends a new verification token to the user. Like
+// RequestPasswordReset, it succeeds whether or not the user exists.
+func (a *AuthManager) RequestEmailVerification(ctx context.Context, tenantId, username string, client ClientInfo) error {
+	if !a.verificationConfig.Enabled {
+		return ErrEmailVerificationDisabled
+	}
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.allowAttempt(ctx, LIMIT_EMAIL_VERIFICATION, tenantId, username, client.Ip); err != nil {
+		return err
+	}
+	user, err := a.getUserByUsername(ctx, tenantId, username)
+	if errors.Is(err, ErrInvalidCredentials) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Disabled() {
+		return nil
+	}
+	return a.sendVerification(ctx, user)
+}
+
+// VerifyEmail marks the email address of a token as verified, provided it
+// is still the address of the user.
+func (a *AuthManager) VerifyEmail(ctx context.Context, token string) (User, error) {
+	if !a.verificationConfig.Enabled {
+		return User{}, ErrEmailVerificationDisabled
+	}
+	c, err := a.parseVerificationToken(token, utils.GetNowTz())
+	if err != nil {
+		return User{}, err
+	}
+	user, err := a.users.VerifyEmail(ctx, c.TenantId, c.Username, c.Email, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrInvalidVerificationToken
+	}
+	if err != nil {
+		return User{}, err
+	}
+	slog.InfoContext(ctx, "Verified email", "tenant_id", user.TenantId, "username", user.Username)
+	if err := a.cache.Del(ctx, a.composeUserKey(user.TenantId, user.Username)); err != nil {
+		return user, err
+	}
+	return user, a.dropCachedSessions(ctx, user)
+}
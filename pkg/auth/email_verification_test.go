@@ -0,0 +1,119 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+)
+
+var verifyLinkPattern = regexp.MustCompile(`verify\?token=([A-Za-z0-9_-]+\.[A-Za-z0-9_-]+)`)
+
+// verificationToken returns the token of the link in a verification mail.
+func verificationToken(t *testing.T, body string) string {
+	t.Helper()
+	match := verifyLinkPattern.FindStringSubmatch(body)
+	if match == nil {
+		t.Fatalf("no verification link in the mail")
+	}
+	return match[1]
+}
+
+func TestEmailVerification(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		policy         string
+		wantLoginErr   error
+		wantRestricted bool
+	}{
+		{UNVERIFIED_ALLOW, nil, false},
+		{UNVERIFIED_RESTRICT, nil, true},
+		{UNVERIFIED_DENY, ErrEmailNotVerified, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			a, _, server := newSmtpTestAuthManager(t, AuthManagerConfig{
+				EmailVerification: EmailVerificationConfig{
+					Enabled:          true,
+					Link:             "https://example.com/verify?token={token}",
+					UnverifiedPolicy: tt.policy,
+				},
+				SigningKeys: SigningKeyConfig{MasterKey: testMasterKey},
+			})
+			if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			token := verificationToken(t, lastMail(t, server, "alice@example.com"))
+
+			s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+			if !errors.Is(err, tt.wantLoginErr) {
+				t.Fatalf("LoginUser before verification: got %v, want %v", err, tt.wantLoginErr)
+			}
+			if err == nil && s.Restricted != tt.wantRestricted {
+				t.Fatalf("LoginUser before verification: got restricted %v, want %v", s.Restricted, tt.wantRestricted)
+			}
+
+			if _, err := a.VerifyEmail(ctx, tampered(token)); !errors.Is(err, ErrInvalidVerificationToken) {
+				t.Fatalf("VerifyEmail with a tampered token: got %v, want ErrInvalidVerificationToken", err)
+			}
+			user, err := a.VerifyEmail(ctx, token)
+			if err != nil {
+				t.Fatalf("VerifyEmail: %v", err)
+			}
+			if !user.EmailVerified() {
+				t.Fatalf("the email address is not verified")
+			}
+			s, _, err = a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+			if err != nil || s.Restricted {
+				t.Fatalf("LoginUser after verification: got restricted %v, %v", s.Restricted, err)
+			}
+		})
+	}
+}
+
+func TestEmailVerificationAfterEmailChange(t *testing.T) {
+	ctx := context.Background()
+	a, _, server := newSmtpTestAuthManager(t, AuthManagerConfig{
+		EmailVerification: EmailVerificationConfig{
+			Enabled: true,
+			Link:    "https://example.com/verify?token={token}",
+		},
+		SigningKeys: SigningKeyConfig{MasterKey: testMasterKey},
+	})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	oldToken := verificationToken(t, lastMail(t, server, "alice@example.com"))
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	email := "alice@example.org"
+	if _, err := a.UpdateProfile(ctx, s.Id, ProfileUpdate{Email: &email}); err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	newToken := verificationToken(t, lastMail(t, server, email))
+
+	if err := a.RequestEmailVerification(ctx, "", "bob", ClientInfo{}); err != nil {
+		t.Fatalf("RequestEmailVerification of unknown user: %v", err)
+	}
+	if n := len(server.Messages()); n != 2 {
+		t.Fatalf("got %d mails, want 2", n)
+	}
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr error
+	}{
+		{"token of the former address", oldToken, ErrInvalidVerificationToken},
+		{"token of the new address", newToken, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.VerifyEmail(ctx, tt.token); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyEmail: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
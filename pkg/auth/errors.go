@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// Errors returned by AuthManager. Callers should match them with errors.Is,
+// as they may be wrapped with details.
+var (
+	ErrInvalidArgument    = errors.New("invalid argument")
+	ErrUserExists         = errors.New("user already exists")
+	ErrInvalidCredentials = errors.New("invalid credentials")
+	ErrSessionNotFound    = errors.New("session not found")
+	ErrSessionExpired     = errors.New("session expired")
+	ErrBackendUnavailable = errors.New("backend unavailable")
+
+	ErrMfaNotEnabled        = errors.New("mfa not enabled")
+	ErrMfaAlreadyEnabled    = errors.New("mfa already enabled")
+	ErrInvalidMfaCode       = errors.New("invalid mfa code")
+	ErrMfaChallengeNotFound = errors.New("mfa challenge not found")
+
+	ErrInvalidResetToken = errors.New("invalid or expired reset token")
+
+	ErrAccessTokensDisabled = errors.New("access tokens are disabled")
+	ErrSigningKeyNotFound   = errors.New("signing key not found")
+
+	ErrOidcDisabled       = errors.New("openid connect is disabled")
+	ErrClientNotFound     = errors.New("client not found")
+	ErrInvalidClient      = errors.New("invalid client credentials")
+	ErrInvalidRedirectUri = errors.New("redirect uri not registered for the client")
+	ErrInvalidScope       = errors.New("invalid scope")
+	ErrInvalidGrant       = errors.New("invalid or expired authorization code")
+
+	ErrServiceAccountNotFound = errors.New("service account not found")
+
+	ErrUserNotFound       = errors.New("user not found")
+	ErrRoleNotFound       = errors.New("role not found")
+	ErrRoleExists         = errors.New("role already exists")
+	ErrPermissionNotFound = errors.New("permission not found for the role")
+	ErrRoleNotGranted     = errors.New("role not granted to the user")
+
+	ErrTenantNotFound = errors.New("tenant not found")
+	ErrTenantExists   = errors.New("tenant already exists")
+	ErrTenantDisabled = errors.New("tenant disabled")
+
+	ErrUserDisabled = errors.New("user disabled")
+
+	ErrEmailVerificationDisabled = errors.New("email verification is disabled")
+	ErrInvalidVerificationToken  = errors.New("invalid or expired verification token")
+	ErrEmailNotVerified          = errors.New("email address not verified")
+
+	ErrPasswordlessDisabled    = errors.New("passwordless login is disabled")
+	ErrInvalidPasswordlessCode = errors.New("invalid or expired passwordless code")
+
+	ErrWebauthnDisabled           = errors.New("WebAuthn is disabled")
+	ErrWebauthnChallengeNotFound  = errors.New("WebAuthn challenge not found or expired")
+	ErrInvalidWebauthnResponse    = errors.New("invalid WebAuthn response")
+	ErrWebauthnCredentialNotFound = errors.New("WebAuthn credential not found")
+	ErrWebauthnCredentialExists   = errors.New("WebAuthn credential already registered")
+)
+
+func invalidArgument(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidArgument, fmt.Sprintf(format, args...))
+}
+
+func backendUnavailable(err error) error {
+	return fmt.Errorf("%w: %w", ErrBackendUnavailable, err)
+}
+
+var ErrRateLimited = errors.New("too many attempts")
+
+// RateLimitedError rejects an attempt made too early; it matches ErrRateLimited.
+type RateLimitedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *RateLimitedError) Error() string {
+	return fmt.Sprintf("%s, retry after %s", ErrRateLimited, e.RetryAfter.Round(time.Second))
+}
+
+func (e *RateLimitedError) Is(target error) bool {
+	return target == ErrRateLimited
+}
@@ -0,0 +1,247 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+const (
+	HASHER_ARGON2ID = "argon2id"
+	HASHER_BCRYPT   = "bcrypt"
+	HASHER_SHA256   = "sha256"
+)
+
+var (
+	errUnknownHashFormat = errors.New("unknown password hash format")
+	errLegacyHasher      = errors.New("the sha256 password hasher is only supported to verify legacy hashes")
+)
+
+// PasswordHasher produces and checks encoded password hashes.
+// Encoded values are self-describing, so a hasher can tell whether
+// a stored hash was produced by it and with its current parameters.
+type PasswordHasher interface {
+	// Hash returns the encoded hash of the password.
+	Hash(password string) (string, error)
+	// Verify reports whether the password matches the encoded hash.
+	Verify(encoded, password string) (bool, error)
+	// Identify reports whether the encoded hash belongs to this hasher.
+	Identify(encoded string) bool
+	// NeedsRehash reports whether the encoded hash was produced with
+	// weaker parameters than the hasher is configured with.
+	NeedsRehash(encoded string) bool
+}
+
+// NewPasswordHasher returns the named hasher for new passwords. The legacy
+// sha256 scheme is refused: its hashes are only verified, then migrated.
+func NewPasswordHasher(name string) (PasswordHasher, error) {
+	switch name {
+	case HASHER_ARGON2ID:
+		return DefaultArgon2idHasher(), nil
+	case HASHER_BCRYPT:
+		return DefaultBcryptHasher(), nil
+	case HASHER_SHA256:
+		return nil, errLegacyHasher
+	}
+	return nil, fmt.Errorf("unknown password hasher %q", name)
+}
+
+// Argon2idHasher encodes hashes in PHC string format:
+// $argon2id$v=19$m=<memory>,t=<time>,p=<threads>$<salt>$<hash>
+type Argon2idHasher struct {
+	Time    uint32
+	Memory  uint32 // KiB
+	Threads uint8
+	KeyLen  uint32
+	SaltLen uint32
+}
+
+func DefaultArgon2idHasher() Argon2idHasher {
+	return Argon2idHasher{Time: 1, Memory: 64 * 1024, Threads: 4, KeyLen: 32, SaltLen: 16}
+}
+
+type argon2idParams struct {
+	version int
+	time    uint32
+	memory  uint32
+	threads uint8
+	salt    []byte
+	key     []byte
+}
+
+func (h Argon2idHasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLen)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (h Argon2idHasher) decode(encoded string) (argon2idParams, error) {
+	var p argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[0] != "" || parts[1] != HASHER_ARGON2ID {
+		return p, errUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &p.version); err != nil {
+		return p, errUnknownHashFormat
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.memory, &p.time, &p.threads); err != nil {
+		return p, errUnknownHashFormat
+	}
+	var err error
+	if p.salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, errUnknownHashFormat
+	}
+	if p.key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, errUnknownHashFormat
+	}
+	return p, nil
+}
+
+func (h Argon2idHasher) Verify(encoded, password string) (bool, error) {
+	p, err := h.decode(encoded)
+	if err != nil {
+		return false, err
+	}
+	if p.version != argon2.Version {
+		return false, fmt.Errorf("unsupported argon2 version %d", p.version)
+	}
+	key := argon2.IDKey([]byte(password), p.salt, p.time, p.memory, p.threads, uint32(len(p.key)))
+	return subtle.ConstantTimeCompare(key, p.key) == 1, nil
+}
+
+func (h Argon2idHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$argon2id$")
+}
+
+func (h Argon2idHasher) NeedsRehash(encoded string) bool {
+	p, err := h.decode(encoded)
+	if err != nil {
+		return true
+	}
+	return p.version != argon2.Version ||
+		p.time < h.Time ||
+		p.memory < h.Memory ||
+		p.threads < h.Threads ||
+		uint32(len(p.key)) < h.KeyLen ||
+		uint32(len(p.salt)) < h.SaltLen
+}
+
+// BcryptHasher uses the modular crypt format produced by bcrypt itself.
+type BcryptHasher struct {
+	Cost int
+}
+
+func DefaultBcryptHasher() BcryptHasher {
+	return BcryptHasher{Cost: 12}
+}
+
+func (h BcryptHasher) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), h.Cost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (h BcryptHasher) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h BcryptHasher) Identify(encoded string) bool {
+	return strings.HasPrefix(encoded, "$2a$") ||
+		strings.HasPrefix(encoded, "$2b$") ||
+		strings.HasPrefix(encoded, "$2y$")
+}
+
+func (h BcryptHasher) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost < h.Cost
+}
+
+// Sha256Hasher is the legacy unsalted hex-encoded SHA-256 scheme.
+// It is kept only to verify hashes stored before the migration.
+type Sha256Hasher struct{}
+
+func (h Sha256Hasher) Hash(password string) (string, error) {
+	sum := sha256.Sum256([]byte(password))
+	return hex.EncodeToString(sum[:]), nil
+}
+
+func (h Sha256Hasher) Verify(encoded, password string) (bool, error) {
+	hash, _ := h.Hash(password)
+	return subtle.ConstantTimeCompare([]byte(hash), []byte(encoded)) == 1, nil
+}
+
+func (h Sha256Hasher) Identify(encoded string) bool {
+	if len(encoded) != hex.EncodedLen(sha256.Size) {
+		return false
+	}
+	_, err := hex.DecodeString(encoded)
+	return err == nil
+}
+
+func (h Sha256Hasher) NeedsRehash(encoded string) bool {
+	return false
+}
+
+// migratingHasher hashes with the preferred hasher and verifies any
+// known format, flagging every hash not produced by the preferred one.
+type migratingHasher struct {
+	preferred PasswordHasher
+	known     []PasswordHasher
+}
+
+func newMigratingHasher(preferred PasswordHasher) *migratingHasher {
+	return &migratingHasher{
+		preferred: preferred,
+		known:     []PasswordHasher{preferred, DefaultArgon2idHasher(), DefaultBcryptHasher(), Sha256Hasher{}},
+	}
+}
+
+func (h *migratingHasher) Hash(password string) (string, error) {
+	return h.preferred.Hash(password)
+}
+
+func (h *migratingHasher) Verify(encoded, password string) (bool, error) {
+	for _, k := range h.known {
+		if k.Identify(encoded) {
+			return k.Verify(encoded, password)
+		}
+	}
+	return false, errUnknownHashFormat
+}
+
+func (h *migratingHasher) Identify(encoded string) bool {
+	for _, k := range h.known {
+		if k.Identify(encoded) {
+			return true
+		}
+	}
+	return false
+}
+
+func (h *migratingHasher) NeedsRehash(encoded string) bool {
+	if !h.preferred.Identify(encoded) {
+		return true
+	}
+	return h.preferred.NeedsRehash(encoded)
+}
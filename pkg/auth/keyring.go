@@ -0,0 +1,400 @@
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// States of a signing key over its lifetime. Pending keys are published
+// ahead of their use so that verifiers caching the JWKS know them in time;
+// retiring keys no longer sign but stay published while tokens they
+// signed may still be in use.
+const (
+	KEY_STATE_PENDING  = "pending"
+	KEY_STATE_ACTIVE   = "active"
+	KEY_STATE_RETIRING = "retiring"
+	KEY_STATE_RETIRED  = "retired"
+	KEY_STATE_REVOKED  = "revoked"
+)
+
+// SigningKeyRecord is a signing key as stored, its PKCS #8 private key
+// encrypted with the master key.
+type SigningKeyRecord struct {
+	Kid          string
+	Algorithm    string
+	EncryptedKey []byte
+	CreatedAt    time.Time
+	// ActivatesAt is when the key starts signing. It stops when the next
+	// key activates.
+	ActivatesAt time.Time
+	RevokedAt   *time.Time
+}
+
+// SigningKeyInfo describes a signing key to administrators.
+type SigningKeyInfo struct {
+	Kid         string
+	Algorithm   string
+	State       string
+	CreatedAt   time.Time
+	ActivatesAt time.Time
+	RetiresAt   *time.Time // when the next key activates, if there is one
+	RevokedAt   *time.Time
+}
+
+type ringKey struct {
+	SigningKeyRecord
+	signer signingKey
+}
+
+// keyRing caches the signing keys of the store and rotates them. Every
+// instance refreshes its copy periodically, so keys rotated or revoked by
+// one instance reach the others within RefreshInterval.
+type keyRing struct {
+	config SigningKeyConfig
+	store  SigningKeyStore
+	sealer *sealer
+
+	mu   sync.RWMutex
+	keys []ringKey // ordered by activation
+}
+
+func newKeyRing(c SigningKeyConfig, store SigningKeyStore) (*keyRing, error) {
+	if len(c.MasterKey) == 0 {
+		return nil, fmt.Errorf("a master key is required to store signing keys")
+	}
+	s, err := newSealer(c.MasterKey)
+	if err != nil {
+		return nil, fmt.Errorf("master key: %w", err)
+	}
+	return &keyRing{config: c, store: store, sealer: s}, nil
+}
+
+func (r *keyRing) encrypt(k signingKey) ([]byte, error) {
+	der, err := x509.MarshalPKCS8PrivateKey(k.key)
+	if err != nil {
+		return nil, err
+	}
+	return r.sealer.seal(der, []byte(k.id))
+}
+
+func (r *keyRing) decrypt(rec SigningKeyRecord) (signingKey, error) {
+	der, err := r.sealer.open(rec.EncryptedKey, []byte(rec.Kid))
+	if err != nil {
+		return signingKey{}, fmt.Errorf("decrypting signing key %s: %w", rec.Kid, err)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(der)
+	if err != nil {
+		return signingKey{}, err
+	}
+	signer, ok := key.(crypto.Signer)
+	if !ok {
+		return signingKey{}, fmt.Errorf("unsupported key type %T", key)
+	}
+	return newSigningKey(signer)
+}
+
+// refresh reloads the keys from the store, decrypting only new ones.
+func (r *keyRing) refresh(ctx context.Context) error {
+	records, err := r.store.ListSigningKeys(ctx)
+	if err != nil {
+		return err
+	}
+	r.mu.RLock()
+	known := make(map[string]signingKey, len(r.keys))
+	for _, k := range r.keys {
+		known[k.Kid] = k.signer
+	}
+	r.mu.RUnlock()
+	keys := make([]ringKey, 0, len(records))
+	for _, rec := range records {
+		signer, ok := known[rec.Kid]
+		if !ok {
+			if signer, err = r.decrypt(rec); err != nil {
+				return err
+			}
+		}
+		keys = append(keys, ringKey{SigningKeyRecord: rec, signer: signer})
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+	r.mu.Lock()
+	r.keys = keys
+	r.mu.Unlock()
+	return nil
+}
+
+// successors returns, for every key, the activation of the next key that
+// is not revoked, if any. It must be called with mu held.
+func (r *keyRing) successors() []*time.Time {
+	res := make([]*time.Time, len(r.keys))
+	var next *time.Time
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		res[i] = next
+		if r.keys[i].RevokedAt == nil {
+			next = &r.keys[i].ActivatesAt
+		}
+	}
+	return res
+}
+
+func (r *keyRing) state(k ringKey, successor *time.Time, now time.Time) string {
+	switch {
+	case k.RevokedAt != nil:
+		return KEY_STATE_REVOKED
+	case k.ActivatesAt.After(now):
+		return KEY_STATE_PENDING
+	case successor == nil || successor.After(now):
+		return KEY_STATE_ACTIVE
+	case successor.Add(r.config.Overlap).After(now):
+		return KEY_STATE_RETIRING
+	}
+	return KEY_STATE_RETIRED
+}
+
+// current returns the key to sign with at now.
+func (r *keyRing) current(now time.Time) (signingKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	successors := r.successors()
+	for i, k := range r.keys {
+		if r.state(k, successors[i], now) == KEY_STATE_ACTIVE {
+			return k.signer, true
+		}
+	}
+	return signingKey{}, false
+}
+
+// jwks returns the pending, active and retiring keys.
+func (r *keyRing) jwks(now time.Time) JwkSet {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	set := JwkSet{Keys: []Jwk{}}
+	successors := r.successors()
+	for i, k := range r.keys {
+		switch r.state(k, successors[i], now) {
+		case KEY_STATE_PENDING, KEY_STATE_ACTIVE, KEY_STATE_RETIRING:
+			set.Keys = append(set.Keys, k.signer.jwk())
+		}
+	}
+	return set
+}
+
+// published returns the key of the id if it is in the JWKS at now.
+func (r *keyRing) published(kid string, now time.Time) (signingKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	successors := r.successors()
+	for i, k := range r.keys {
+		if k.Kid != kid {
+			continue
+		}
+		switch r.state(k, successors[i], now) {
+		case KEY_STATE_PENDING, KEY_STATE_ACTIVE, KEY_STATE_RETIRING:
+			return k.signer, true
+		}
+	}
+	return signingKey{}, false
+}
+
+func (r *keyRing) list(now time.Time) []SigningKeyInfo {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	res := make([]SigningKeyInfo, 0, len(r.keys))
+	successors := r.successors()
+	for i, k := range r.keys {
+		res = append(res, SigningKeyInfo{
+			Kid:         k.Kid,
+			Algorithm:   k.Algorithm,
+			State:       r.state(k, successors[i], now),
+			CreatedAt:   k.CreatedAt,
+			ActivatesAt: k.ActivatesAt,
+			RetiresAt:   successors[i],
+			RevokedAt:   k.RevokedAt,
+		})
+	}
+	return res
+}
+
+// latest returns the last key to activate that is not revoked.
+func (r *keyRing) latest() (ringKey, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for i := len(r.keys) - 1; i >= 0; i-- {
+		if r.keys[i].RevokedAt == nil {
+			return r.keys[i], true
+		}
+	}
+	return ringKey{}, false
+}
+
+// rotate adds a key activating at activatesAt after the latest one.
+// It returns ErrAlreadyExists if another instance added a key meanwhile.
+func (r *keyRing) rotate(ctx context.Context, now, activatesAt time.Time) (SigningKeyInfo, error) {
+	key, err := generateSigningKey(r.config.Algorithm)
+	if err != nil {
+		return SigningKeyInfo{}, err
+	}
+	return r.add(ctx, key, now, activatesAt)
+}
+
+// add stores the key, activating at activatesAt after the latest one.
+func (r *keyRing) add(ctx context.Context, key signingKey, now, activatesAt time.Time) (SigningKeyInfo, error) {
+	previous, _ := r.latest()
+	encrypted, err := r.encrypt(key)
+	if err != nil {
+		return SigningKeyInfo{}, err
+	}
+	rec := SigningKeyRecord{
+		Kid:          key.id,
+		Algorithm:    key.algorithm,
+		EncryptedKey: encrypted,
+		CreatedAt:    now,
+		ActivatesAt:  activatesAt,
+	}
+	if err := r.store.CreateSigningKey(ctx, rec, previous.Kid); err != nil {
+		return SigningKeyInfo{}, err
+	}
+	slog.InfoContext(ctx, "Added signing key", "kid", rec.Kid, "algorithm", rec.Algorithm, "activates_at", activatesAt)
+	if err := r.refresh(ctx); err != nil {
+		return SigningKeyInfo{}, err
+	}
+	return SigningKeyInfo{
+		Kid:         rec.Kid,
+		Algorithm:   rec.Algorithm,
+		State:       r.state(ringKey{SigningKeyRecord: rec}, nil, now),
+		CreatedAt:   rec.CreatedAt,
+		ActivatesAt: rec.ActivatesAt,
+	}, nil
+}
+
+// importKey adds a key loaded from a file as the active one, unless the
+// ring already holds it, revoked or not.
+func (r *keyRing) importKey(ctx context.Context, key signingKey) error {
+	if err := r.refresh(ctx); err != nil {
+		return err
+	}
+	r.mu.RLock()
+	known := slices.ContainsFunc(r.keys, func(k ringKey) bool { return k.Kid == key.id })
+	r.mu.RUnlock()
+	if known {
+		return nil
+	}
+	now := utils.GetNowTz()
+	_, err := r.add(ctx, key, now, now)
+	if errors.Is(err, ErrAlreadyExists) {
+		return r.refresh(ctx)
+	}
+	return err
+}
+
+// maintain makes sure there is a key to sign with, and schedules the next
+// one once the latest is due for rotation.
+func (r *keyRing) maintain(ctx context.Context) error {
+	if err := r.refresh(ctx); err != nil {
+		return err
+	}
+	now := utils.GetNowTz()
+	var err error
+	if _, ok := r.current(now); !ok {
+		_, err = r.rotate(ctx, now, now)
+	} else if latest, _ := r.latest(); !latest.ActivatesAt.Add(r.config.RotationInterval).After(now) {
+		_, err = r.rotate(ctx, now, now.Add(r.config.PublishDelay))
+	}
+	if errors.Is(err, ErrAlreadyExists) {
+		return r.refresh(ctx)
+	}
+	return err
+}
+
+func (r *keyRing) run(ctx context.Context) {
+	ticker := time.NewTicker(r.config.RefreshInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.maintain(ctx); err != nil && ctx.Err() == nil {
+				slog.ErrorContext(ctx, "Error maintaining signing keys", "error", err)
+			}
+		}
+	}
+}
+
+// ListSigningKeys returns every signing key, oldest first.
+func (a *AuthManager) ListSigningKeys(ctx context.Context) ([]SigningKeyInfo, error) {
+	if a.keys == nil {
+		return nil, ErrAccessTokensDisabled
+	}
+	if err := a.keys.refresh(ctx); err != nil {
+		return nil, err
+	}
+	return a.keys.list(utils.GetNowTz()), nil
+}
+
+// RotateSigningKey adds a key that starts signing once published for
+// PublishDelay, or right away if immediate.
+func (a *AuthManager) RotateSigningKey(ctx context.Context, immediate bool) (SigningKeyInfo, error) {
+	if a.keys == nil {
+		return SigningKeyInfo{}, ErrAccessTokensDisabled
+	}
+	if err := a.keys.refresh(ctx); err != nil {
+		return SigningKeyInfo{}, err
+	}
+	now := utils.GetNowTz()
+	activatesAt := now.Add(a.keys.config.PublishDelay)
+	if immediate {
+		activatesAt = now
+	}
+	info, err := a.keys.rotate(ctx, now, activatesAt)
+	if errors.Is(err, ErrAlreadyExists) {
+		// Lost a race with another rotation; rotate after that one.
+		if err := a.keys.refresh(ctx); err != nil {
+			return SigningKeyInfo{}, err
+		}
+		info, err = a.keys.rotate(ctx, now, activatesAt)
+	}
+	return info, err
+}
+
+// RevokeSigningKey withdraws a key at once: tokens it signed stop verifying.
+// Revoking the active key activates a new one immediately.
+func (a *AuthManager) RevokeSigningKey(ctx context.Context, kid string) error {
+	if a.keys == nil {
+		return ErrAccessTokensDisabled
+	}
+	if err := a.keys.refresh(ctx); err != nil {
+		return err
+	}
+	now := utils.GetNowTz()
+	current, _ := a.keys.current(now)
+	err := a.keys.store.RevokeSigningKey(ctx, kid, now)
+	if errors.Is(err, ErrNotFound) {
+		return ErrSigningKeyNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Revoked signing key", "kid", kid)
+	if err := a.keys.refresh(ctx); err != nil {
+		return err
+	}
+	if current.id == kid {
+		_, err = a.keys.rotate(ctx, now, now)
+		if !errors.Is(err, ErrAlreadyExists) {
+			return err
+		}
+	}
+	return a.keys.maintain(ctx)
+}
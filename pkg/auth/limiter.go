@@ -0,0 +1,345 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+type LimiterConfig struct {
+	// Window is the period over which login attempts are counted.
+	Window time.Duration
+	// UserLimit and IpLimit cap the attempts per username and per client IP
+	// within Window. Zero disables the limit.
+	UserLimit int
+	IpLimit   int
+	// LockoutThreshold is the number of consecutive failures locking an
+	// account. The lock lasts LockoutBase, doubling with every further
+	// failure up to LockoutMax. Zero disables lockouts.
+	LockoutThreshold int
+	LockoutBase      time.Duration
+	LockoutMax       time.Duration
+	// FailureTtl is how long consecutive failures are remembered.
+	FailureTtl time.Duration
+}
+
+func DefaultLimiterConfig() LimiterConfig {
+	return LimiterConfig{
+		Window:           time.Minute,
+		UserLimit:        10,
+		IpLimit:          50,
+		LockoutThreshold: 5,
+		LockoutBase:      30 * time.Second,
+		LockoutMax:       time.Hour,
+		FailureTtl:       24 * time.Hour,
+	}
+}
+
+func (c LimiterConfig) normalize() LimiterConfig {
+	d := DefaultLimiterConfig()
+	if c.Window <= 0 {
+		c.Window = d.Window
+	}
+	if c.LockoutBase <= 0 {
+		c.LockoutBase = d.LockoutBase
+	}
+	if c.LockoutMax < c.LockoutBase {
+		c.LockoutMax = c.LockoutBase
+	}
+	if c.FailureTtl <= 0 {
+		c.FailureTtl = d.FailureTtl
+	}
+	return c
+}
+
+// limiterBackend holds the counters behind loginLimiter.
+type limiterBackend interface {
+	// Hit records a hit in the sliding window of the key unless limit hits
+	// are already there, in which case it returns how long until one expires.
+	Hit(ctx context.Context, key string, window time.Duration, limit int, now time.Time) (time.Duration, error)
+	// Incr increments the counter of the key, expiring it after ttl.
+	Incr(ctx context.Context, key string, ttl time.Duration) (int64, error)
+	// Lock marks the key locked for d.
+	Lock(ctx context.Context, key string, d time.Duration) error
+	// LockedFor returns how long the key stays locked, zero if it is not.
+	LockedFor(ctx context.Context, key string) (time.Duration, error)
+	Del(ctx context.Context, keys ...string) error
+}
+
+// Actions limited separately. Requests anyone can make for a user, such as
+// password resets, neither use up the attempts of credential checks nor
+// lock them; only credential checks record failures.
+const (
+	LIMIT_LOGIN              = "login" // password checks
+	LIMIT_MFA                = "mfa"
+	LIMIT_PASSWORDLESS       = "passwordless"
+	LIMIT_WEBAUTHN           = "webauthn"
+	LIMIT_PASSWORD_RESET     = "password_reset"
+	LIMIT_PASSWORDLESS_START = "passwordless_start"
+	LIMIT_EMAIL_VERIFICATION = "email_verification"
+)
+
+// loginLimiter applies per-user and per-IP rate limits and account lockouts,
+// for each action on its own.
+type loginLimiter struct {
+	config  LimiterConfig
+	backend limiterBackend
+}
+
+func newLoginLimiter(c LimiterConfig, backend limiterBackend) *loginLimiter {
+	return &loginLimiter{config: c.normalize(), backend: backend}
+}
+
+func (l *loginLimiter) composeUserKey(action, username string) string {
+	return fmt.Sprintf("%s_user_%s", action, username)
+}
+
+func (l *loginLimiter) composeIpKey(action, ip string) string {
+	return fmt.Sprintf("%s_ip_%s", action, ip)
+}
+
+func (l *loginLimiter) composeFailuresKey(action, username string) string {
+	return fmt.Sprintf("%s_failures_%s", action, username)
+}
+
+func (l *loginLimiter) composeLockKey(action, username string) string {
+	return fmt.Sprintf("%s_lock_%s", action, username)
+}
+
+// Allow records an attempt of the action, returning a RateLimitedError if
+// the action is locked for the account or the user or IP made too many
+// attempts.
+func (l *loginLimiter) Allow(ctx context.Context, action, username, ip string, now time.Time) error {
+	if l.config.LockoutThreshold > 0 {
+		d, err := l.backend.LockedFor(ctx, l.composeLockKey(action, username))
+		if err != nil {
+			return err
+		}
+		if d > 0 {
+			return &RateLimitedError{RetryAfter: d}
+		}
+	}
+	if l.config.UserLimit > 0 {
+		d, err := l.backend.Hit(ctx, l.composeUserKey(action, username), l.config.Window, l.config.UserLimit, now)
+		if err != nil {
+			return err
+		}
+		if d > 0 {
+			return &RateLimitedError{RetryAfter: d}
+		}
+	}
+	if l.config.IpLimit > 0 && ip != "" {
+		d, err := l.backend.Hit(ctx, l.composeIpKey(action, ip), l.config.Window, l.config.IpLimit, now)
+		if err != nil {
+			return err
+		}
+		if d > 0 {
+			return &RateLimitedError{RetryAfter: d}
+		}
+	}
+	return nil
+}
+
+// Failure records a wrong credential, locking the action for the account
+// once failures reach the threshold.
+func (l *loginLimiter) Failure(ctx context.Context, action, username string) error {
+	if l.config.LockoutThreshold <= 0 {
+		return nil
+	}
+	failures, err := l.backend.Incr(ctx, l.composeFailuresKey(action, username), l.config.FailureTtl)
+	if err != nil {
+		return err
+	}
+	if failures < int64(l.config.LockoutThreshold) {
+		return nil
+	}
+	return l.backend.Lock(ctx, l.composeLockKey(action, username), l.lockoutDuration(failures))
+}
+
+func (l *loginLimiter) lockoutDuration(failures int64) time.Duration {
+	d := l.config.LockoutBase
+	for i := int64(l.config.LockoutThreshold); i < failures && d < l.config.LockoutMax; i++ {
+		d *= 2
+	}
+	if d > l.config.LockoutMax {
+		d = l.config.LockoutMax
+	}
+	return d
+}
+
+// Success clears the consecutive failures of the action for the account.
+func (l *loginLimiter) Success(ctx context.Context, action, username string) error {
+	if l.config.LockoutThreshold <= 0 {
+		return nil
+	}
+	return l.backend.Del(ctx, l.composeFailuresKey(action, username))
+}
+
+// fallbackLimiterBackend uses the primary backend, switching to the
+// fallback for calls that find the primary unavailable.
+type fallbackLimiterBackend struct {
+	primary  limiterBackend
+	fallback limiterBackend
+}
+
+func (f *fallbackLimiterBackend) use(err error) bool {
+	if errors.Is(err, ErrBackendUnavailable) {
+		slog.Warn("Login limiter backend unavailable, using in-memory fallback", "error", err)
+		return true
+	}
+	return false
+}
+
+func (f *fallbackLimiterBackend) Hit(ctx context.Context, key string, window time.Duration, limit int, now time.Time) (time.Duration, error) {
+	d, err := f.primary.Hit(ctx, key, window, limit, now)
+	if f.use(err) {
+		return f.fallback.Hit(ctx, key, window, limit, now)
+	}
+	return d, err
+}
+
+func (f *fallbackLimiterBackend) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	n, err := f.primary.Incr(ctx, key, ttl)
+	if f.use(err) {
+		return f.fallback.Incr(ctx, key, ttl)
+	}
+	return n, err
+}
+
+func (f *fallbackLimiterBackend) Lock(ctx context.Context, key string, d time.Duration) error {
+	err := f.primary.Lock(ctx, key, d)
+	if f.use(err) {
+		return f.fallback.Lock(ctx, key, d)
+	}
+	return err
+}
+
+func (f *fallbackLimiterBackend) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	d, err := f.primary.LockedFor(ctx, key)
+	if f.use(err) {
+		return f.fallback.LockedFor(ctx, key)
+	}
+	return d, err
+}
+
+func (f *fallbackLimiterBackend) Del(ctx context.Context, keys ...string) error {
+	err := f.primary.Del(ctx, keys...)
+	if f.use(err) {
+		return f.fallback.Del(ctx, keys...)
+	}
+	return err
+}
+
+const memoryLimiterSweepInterval = time.Minute
+
+type memoryCounter struct {
+	value     int64
+	expiresAt time.Time
+}
+
+// memoryLimiterBackend implements limiterBackend in process memory.
+type memoryLimiterBackend struct {
+	mu        sync.Mutex
+	windows   map[string][]time.Time
+	counters  map[string]memoryCounter
+	locks     map[string]time.Time
+	lastSweep time.Time
+}
+
+func newMemoryLimiterBackend() *memoryLimiterBackend {
+	return &memoryLimiterBackend{
+		windows:  make(map[string][]time.Time),
+		counters: make(map[string]memoryCounter),
+		locks:    make(map[string]time.Time),
+	}
+}
+
+// sweep drops expired entries so that the maps do not grow unbounded.
+// It must be called with mu held.
+func (m *memoryLimiterBackend) sweep(now time.Time, window time.Duration) {
+	if now.Sub(m.lastSweep) < memoryLimiterSweepInterval {
+		return
+	}
+	m.lastSweep = now
+	for key, hits := range m.windows {
+		if len(hits) == 0 || !hits[len(hits)-1].After(now.Add(-window)) {
+			delete(m.windows, key)
+		}
+	}
+	for key, c := range m.counters {
+		if !c.expiresAt.After(now) {
+			delete(m.counters, key)
+		}
+	}
+	for key, until := range m.locks {
+		if !until.After(now) {
+			delete(m.locks, key)
+		}
+	}
+}
+
+func (m *memoryLimiterBackend) Hit(ctx context.Context, key string, window time.Duration, limit int, now time.Time) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.sweep(now, window)
+	hits := m.windows[key]
+	start := now.Add(-window)
+	i := 0
+	for i < len(hits) && !hits[i].After(start) {
+		i++
+	}
+	hits = hits[i:]
+	if len(hits) >= limit {
+		m.windows[key] = hits
+		return hits[0].Add(window).Sub(now), nil
+	}
+	m.windows[key] = append(hits, now)
+	return 0, nil
+}
+
+func (m *memoryLimiterBackend) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := utils.GetNowTz()
+	c := m.counters[key]
+	if !c.expiresAt.After(now) {
+		c.value = 0
+	}
+	c.value++
+	c.expiresAt = now.Add(ttl)
+	m.counters[key] = c
+	return c.value, nil
+}
+
+func (m *memoryLimiterBackend) Lock(ctx context.Context, key string, d time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.locks[key] = utils.GetNowTz().Add(d)
+	return nil
+}
+
+func (m *memoryLimiterBackend) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := m.locks[key].Sub(utils.GetNowTz())
+	if d <= 0 {
+		return 0, nil
+	}
+	return d, nil
+}
+
+func (m *memoryLimiterBackend) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.windows, key)
+		delete(m.counters, key)
+		delete(m.locks, key)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// slidingWindowScript trims the window of KEYS[1] to ARGV[2] ms before
+// ARGV[1] (now, in ms) and adds ARGV[4] unless ARGV[3] hits are left.
+// It returns 0 when the hit is recorded, or the ms until the oldest expires.
+var slidingWindowScript = redis.NewScript(`
+local now = tonumber(ARGV[1])
+local window = tonumber(ARGV[2])
+local limit = tonumber(ARGV[3])
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now - window)
+if redis.call('ZCARD', KEYS[1]) >= limit then
+	local oldest = redis.call('ZRANGE', KEYS[1], 0, 0, 'WITHSCORES')
+	return tonumber(oldest[2]) + window - now
+end
+redis.call('ZADD', KEYS[1], now, ARGV[4])
+redis.call('PEXPIRE', KEYS[1], window)
+return 0
+`)
+
+// redisLimiterBackend implements limiterBackend on top of the cache client.
+type redisLimiterBackend struct {
+	client *redis.Client
+}
+
+func newRedisLimiterBackend(client *redis.Client) *redisLimiterBackend {
+	return &redisLimiterBackend{client}
+}
+
+func (r *redisLimiterBackend) mapError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	return backendUnavailable(err)
+}
+
+func (r *redisLimiterBackend) Hit(ctx context.Context, key string, window time.Duration, limit int, now time.Time) (time.Duration, error) {
+	nowMs := now.UnixMilli()
+	member := fmt.Sprintf("%d-%d", nowMs, rand.Uint64())
+	res, err := slidingWindowScript.Run(ctx, r.client, []string{key}, nowMs, window.Milliseconds(), limit, member).Int64()
+	if err != nil {
+		return 0, r.mapError(err)
+	}
+	return time.Duration(res) * time.Millisecond, nil
+}
+
+func (r *redisLimiterBackend) Incr(ctx context.Context, key string, ttl time.Duration) (int64, error) {
+	pipe := r.client.TxPipeline()
+	incr := pipe.Incr(ctx, key)
+	pipe.PExpire(ctx, key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		return 0, r.mapError(err)
+	}
+	return incr.Val(), nil
+}
+
+func (r *redisLimiterBackend) Lock(ctx context.Context, key string, d time.Duration) error {
+	return r.mapError(r.client.Set(ctx, key, 1, d).Err())
+}
+
+func (r *redisLimiterBackend) LockedFor(ctx context.Context, key string) (time.Duration, error) {
+	d, err := r.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, r.mapError(err)
+	}
+	// PTTL reports a missing key as a negative duration
+	if d < 0 {
+		return 0, nil
+	}
+	return d, nil
+}
+
+func (r *redisLimiterBackend) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return r.mapError(r.client.Del(ctx, keys...).Err())
+}
@@ -0,0 +1,85 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestLimiterActions(t *testing.T) {
+	ctx := context.Background()
+	spam := []struct {
+		name    string
+		request func(a *AuthManager) error
+	}{
+		{"password resets", func(a *AuthManager) error {
+			return a.RequestPasswordReset(ctx, "", "alice", ClientInfo{Ip: "10.0.0.1"})
+		}},
+		{"passwordless codes", func(a *AuthManager) error {
+			_, err := a.StartPasswordlessLogin(ctx, "", "alice", "", ClientInfo{Ip: "10.0.0.1"})
+			return err
+		}},
+		{"wrong passwordless codes", func(a *AuthManager) error {
+			c, err := a.StartPasswordlessLogin(ctx, "", "alice", "", ClientInfo{Ip: "10.0.0.1"})
+			if err != nil {
+				return err
+			}
+			_, _, err = a.CompletePasswordlessLogin(ctx, c.Token, "wrong", ClientInfo{Ip: "10.0.0.1"})
+			if errors.Is(err, ErrInvalidPasswordlessCode) {
+				return nil
+			}
+			return err
+		}},
+	}
+	for _, tt := range spam {
+		t.Run(tt.name, func(t *testing.T) {
+			a, _ := newTestAuthManager(t, AuthManagerConfig{
+				Limiter:      LimiterConfig{UserLimit: 3, IpLimit: 3, LockoutThreshold: 2},
+				Passwordless: PasswordlessConfig{Enabled: true},
+				Notifier:     &recordingNotifier{},
+			})
+			if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+				t.Fatalf("CreateUser: %v", err)
+			}
+			var limited bool
+			for i := 0; i < 5; i++ {
+				err := tt.request(a)
+				if errors.Is(err, ErrRateLimited) {
+					limited = true
+					continue
+				}
+				if err != nil {
+					t.Fatalf("request %d: %v", i, err)
+				}
+			}
+			if !limited {
+				t.Fatalf("requests were not rate limited")
+			}
+			if _, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{Ip: "10.0.0.1"}); err != nil {
+				t.Fatalf("LoginUser after the requests: %v", err)
+			}
+		})
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{
+		Limiter:  LimiterConfig{LockoutThreshold: 2},
+		Notifier: &recordingNotifier{},
+	})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, _, err := a.LoginUser(ctx, "", "alice", "wrong", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("LoginUser with wrong password: got %v", err)
+		}
+	}
+	if _, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("LoginUser of locked account: got %v, want ErrRateLimited", err)
+	}
+	if err := a.RequestPasswordReset(ctx, "", "alice", ClientInfo{}); err != nil {
+		t.Fatalf("RequestPasswordReset of locked account: %v", err)
+	}
+}
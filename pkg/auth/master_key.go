@@ -0,0 +1,87 @@
+package auth
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// MASTER_KEY_SIZE is the size of the AES-256 key protecting stored secrets.
+const MASTER_KEY_SIZE = 32
+
+// LoadMasterKey reads the master key from the file at path if given,
+// otherwise from value. Either holds the key base64 encoded; a file may
+// also hold the raw bytes. It returns nil if neither is set.
+func LoadMasterKey(path, value string) ([]byte, error) {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if len(data) == MASTER_KEY_SIZE {
+			return data, nil
+		}
+		value = string(data)
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil, nil
+	}
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("master key is not base64: %w", err)
+	}
+	if len(key) != MASTER_KEY_SIZE {
+		return nil, fmt.Errorf("master key must be %d bytes, got %d", MASTER_KEY_SIZE, len(key))
+	}
+	return key, nil
+}
+
+// deriveKey derives a key for a single purpose from the master key, which
+// is otherwise only used for encryption.
+func deriveKey(masterKey []byte, label string) []byte {
+	mac := hmac.New(sha256.New, masterKey)
+	mac.Write([]byte(label))
+	return mac.Sum(nil)
+}
+
+// sealer encrypts secrets at rest with AES-GCM. The additional data binds
+// a ciphertext to its record so that it cannot be moved to another one.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+func newSealer(key []byte) (*sealer, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead}, nil
+}
+
+// seal returns the nonce followed by the ciphertext.
+func (s *sealer) seal(plaintext, additionalData []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, additionalData), nil
+}
+
+func (s *sealer) open(data, additionalData []byte) ([]byte, error) {
+	if len(data) < s.aead.NonceSize() {
+		return nil, fmt.Errorf("ciphertext too short")
+	}
+	nonce, ciphertext := data[:s.aead.NonceSize()], data[s.aead.NonceSize():]
+	return s.aead.Open(nil, nonce, ciphertext, additionalData)
+}
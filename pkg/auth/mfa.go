@@ -0,0 +1,323 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// Mfa is the TOTP second factor of a user. It stays pending, and is not
+// asked for at login, until the user confirms it with a first code.
+type Mfa struct {
+	TenantId string
+	Username string
+	// Secret is sealed with the master key in stores, see sealTotpSecret.
+	Secret string
+	// LastUsedStep is the time step of the last accepted code,
+	// so that a code cannot be used twice.
+	LastUsedStep int64
+	CreatedAt    time.Time
+	EnabledAt    *time.Time
+}
+
+func (m Mfa) Enabled() bool {
+	return m.EnabledAt != nil
+}
+
+// MfaEnrollment is what the user needs to set up an authenticator app.
+type MfaEnrollment struct {
+	Secret string
+	Uri    string
+}
+
+// MfaChallenge is returned by LoginUser instead of a session when the user
+// has MFA enabled. VerifyMfa exchanges it and a code for the session.
+type MfaChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// mfaChallengeEntry is the payload cached under a challenge key.
+type mfaChallengeEntry struct {
+	TenantId string     `json:"tenant_id"`
+	Username string     `json:"username"`
+	Client   ClientInfo `json:"client"`
+}
+
+func (a *AuthManager) composeMfaChallengeKey(token string) string {
+	return fmt.Sprintf("mfa_challenge_%s", token)
+}
+
+func (a *AuthManager) newMfaChallenge(ctx context.Context, u User, client ClientInfo) (*MfaChallenge, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return nil, err
+	}
+	c := &MfaChallenge{
+		Token:     hex.EncodeToString(b),
+		ExpiresAt: utils.GetNowTz().Add(a.mfaConfig.ChallengeTtl),
+	}
+	data, err := json.Marshal(mfaChallengeEntry{TenantId: u.TenantId, Username: u.Username, Client: client.normalize()})
+	if err != nil {
+		return nil, err
+	}
+	if err := a.cache.Set(ctx, a.composeMfaChallengeKey(c.Token), data, a.mfaConfig.ChallengeTtl); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// sealedTotpPrefix marks sealed secrets, telling them from those stored
+// as is before a master key was configured.
+const sealedTotpPrefix = "sealed:"
+
+// totpAdditionalData binds a sealed secret to its user.
+func totpAdditionalData(tenantId, username string) []byte {
+	return []byte("mfa:" + userKey(tenantId, username))
+}
+
+// sealTotpSecret encrypts a secret for the store if a master key is
+// configured, and returns it as is otherwise.
+func (a *AuthManager) sealTotpSecret(tenantId, username, secret string) (string, error) {
+	if a.mfaSealer == nil {
+		return secret, nil
+	}
+	sealed, err := a.mfaSealer.seal([]byte(secret), totpAdditionalData(tenantId, username))
+	if err != nil {
+		return "", err
+	}
+	return sealedTotpPrefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// getMfa returns the second factor of the user with its secret decrypted.
+func (a *AuthManager) getMfa(ctx context.Context, tenantId, username string) (Mfa, error) {
+	m, err := a.mfa.GetMfa(ctx, tenantId, username)
+	if err != nil {
+		return Mfa{}, err
+	}
+	sealed, ok := strings.CutPrefix(m.Secret, sealedTotpPrefix)
+	if !ok {
+		return m, nil
+	}
+	if a.mfaSealer == nil {
+		return Mfa{}, fmt.Errorf("the TOTP secret of %s is sealed but there is no master key", m.Username)
+	}
+	data, err := base64.RawStdEncoding.DecodeString(sealed)
+	if err == nil {
+		data, err = a.mfaSealer.open(data, totpAdditionalData(tenantId, username))
+	}
+	if err != nil {
+		return Mfa{}, fmt.Errorf("decrypting the TOTP secret of %s: %w", m.Username, err)
+	}
+	m.Secret = string(data)
+	return m, nil
+}
+
+// getEnabledMfa returns ErrMfaNotEnabled unless the user has confirmed MFA.
+func (a *AuthManager) getEnabledMfa(ctx context.Context, tenantId, username string) (Mfa, error) {
+	m, err := a.getMfa(ctx, tenantId, username)
+	if errors.Is(err, ErrNotFound) {
+		return Mfa{}, ErrMfaNotEnabled
+	}
+	if err != nil {
+		return Mfa{}, err
+	}
+	if !m.Enabled() {
+		return Mfa{}, ErrMfaNotEnabled
+	}
+	return m, nil
+}
+
+// checkMfaCode accepts either a TOTP code or an unused recovery code,
+// consuming it. Wrong codes count towards a lockout.
+func (a *AuthManager) checkMfaCode(ctx context.Context, m Mfa, code string) error {
+	now := utils.GetNowTz()
+	var err error
+	if step, ok := verifyTotp(m.Secret, code, now); ok {
+		err = a.mfa.UseMfaStep(ctx, m.TenantId, m.Username, step)
+	} else {
+		err = a.mfa.UseRecoveryCode(ctx, m.TenantId, m.Username, hashRecoveryCode(code), now)
+	}
+	if errors.Is(err, ErrNotFound) {
+		a.recordFailure(ctx, LIMIT_MFA, m.TenantId, m.Username)
+		return ErrInvalidMfaCode
+	}
+	return err
+}
+
+// newRecoveryCodes returns fresh recovery codes along with their hashes.
+func (a *AuthManager) newRecoveryCodes() ([]string, []string, error) {
+	codes, err := newRecoveryCodes()
+	if err != nil {
+		return nil, nil, err
+	}
+	hashes := make([]string, len(codes))
+	for i, code := range codes {
+		hashes[i] = hashRecoveryCode(code)
+	}
+	return codes, hashes, nil
+}
+
+// VerifyMfa completes a login started by LoginUser. A challenge may be
+// retried with another code until it expires.
+func (a *AuthManager) VerifyMfa(ctx context.Context, challenge, code string) (Session, error) {
+	key := a.composeMfaChallengeKey(challenge)
+	var entry mfaChallengeEntry
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_MFA_CHALLENGE, key, &entry)
+	if errors.Is(err, ErrBackendUnavailable) {
+		return Session{}, err
+	}
+	if err != nil {
+		return Session{}, ErrMfaChallengeNotFound
+	}
+	if err := a.allowAttempt(ctx, LIMIT_MFA, entry.TenantId, entry.Username, entry.Client.Ip); err != nil {
+		return Session{}, err
+	}
+	user, err := a.getUserByUsername(ctx, entry.TenantId, entry.Username)
+	if err != nil {
+		return Session{}, err
+	}
+	if user.Disabled() {
+		return Session{}, ErrUserDisabled
+	}
+	m, err := a.getEnabledMfa(ctx, user.TenantId, user.Username)
+	if errors.Is(err, ErrMfaNotEnabled) {
+		// Disabled since the password was checked; the challenge is moot.
+		a.cache.Del(ctx, key)
+		return Session{}, ErrMfaChallengeNotFound
+	}
+	if err != nil {
+		return Session{}, err
+	}
+	if err := a.checkMfaCode(ctx, m, code); err != nil {
+		return Session{}, err
+	}
+	a.cache.Del(ctx, key)
+	a.resetFailures(ctx, LIMIT_MFA, user.TenantId, user.Username)
+	a.resetFailures(ctx, LIMIT_LOGIN, user.TenantId, user.Username)
+	return a.createSesssion(ctx, user, entry.Client)
+}
+
+// EnrollMfa starts the MFA enrollment of the session's user, replacing any
+// previous enrollment that was not confirmed.
+func (a *AuthManager) EnrollMfa(ctx context.Context, sessionId string) (MfaEnrollment, error) {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return MfaEnrollment{}, err
+	}
+	secret, err := newTotpSecret()
+	if err != nil {
+		return MfaEnrollment{}, err
+	}
+	sealed, err := a.sealTotpSecret(user.TenantId, user.Username, secret)
+	if err != nil {
+		return MfaEnrollment{}, err
+	}
+	err = a.mfa.SetMfa(ctx, Mfa{TenantId: user.TenantId, Username: user.Username, Secret: sealed, CreatedAt: utils.GetNowTz()})
+	if errors.Is(err, ErrAlreadyExists) {
+		return MfaEnrollment{}, ErrMfaAlreadyEnabled
+	}
+	if err != nil {
+		return MfaEnrollment{}, err
+	}
+	return MfaEnrollment{
+		Secret: secret,
+		Uri:    totpUri(a.mfaConfig.Issuer, user.Subject(), secret),
+	}, nil
+}
+
+// ConfirmMfa enables the pending enrollment of the session's user given
+// a first code from the authenticator app, and returns recovery codes.
+func (a *AuthManager) ConfirmMfa(ctx context.Context, sessionId, code string) ([]string, error) {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.allowAttempt(ctx, LIMIT_MFA, user.TenantId, user.Username, ""); err != nil {
+		return nil, err
+	}
+	m, err := a.getMfa(ctx, user.TenantId, user.Username)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrMfaNotEnabled
+	}
+	if err != nil {
+		return nil, err
+	}
+	if m.Enabled() {
+		return nil, ErrMfaAlreadyEnabled
+	}
+	now := utils.GetNowTz()
+	step, ok := verifyTotp(m.Secret, code, now)
+	if !ok {
+		a.recordFailure(ctx, LIMIT_MFA, user.TenantId, user.Username)
+		return nil, ErrInvalidMfaCode
+	}
+	codes, hashes, err := a.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	err = a.mfa.EnableMfa(ctx, user.TenantId, user.Username, step, hashes, now)
+	if errors.Is(err, ErrNotFound) {
+		return nil, ErrInvalidMfaCode
+	}
+	if err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
+
+// DisableMfa removes the second factor of the session's user given a code
+// or a recovery code.
+func (a *AuthManager) DisableMfa(ctx context.Context, sessionId, code string) error {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+	if err := a.allowAttempt(ctx, LIMIT_MFA, user.TenantId, user.Username, ""); err != nil {
+		return err
+	}
+	m, err := a.getEnabledMfa(ctx, user.TenantId, user.Username)
+	if err != nil {
+		return err
+	}
+	if err := a.checkMfaCode(ctx, m, code); err != nil {
+		return err
+	}
+	return a.mfa.DeleteMfa(ctx, user.TenantId, user.Username)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the session's user
+// given a code or a recovery code.
+func (a *AuthManager) RegenerateRecoveryCodes(ctx context.Context, sessionId, code string) ([]string, error) {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.allowAttempt(ctx, LIMIT_MFA, user.TenantId, user.Username, ""); err != nil {
+		return nil, err
+	}
+	m, err := a.getEnabledMfa(ctx, user.TenantId, user.Username)
+	if err != nil {
+		return nil, err
+	}
+	if err := a.checkMfaCode(ctx, m, code); err != nil {
+		return nil, err
+	}
+	codes, hashes, err := a.newRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+	if err := a.mfa.ReplaceRecoveryCodes(ctx, user.TenantId, user.Username, hashes); err != nil {
+		return nil, err
+	}
+	return codes, nil
+}
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+func mustTotpCode(t *testing.T, secret string, step int64) string {
+	t.Helper()
+	code, err := totpCode(secret, step)
+	if err != nil {
+		t.Fatalf("totpCode: %v", err)
+	}
+	return code
+}
+
+// enableMfa creates alice with MFA enabled, confirmed with the code of the
+// current step, and returns her TOTP secret, that step and her recovery
+// codes.
+func enableMfa(t *testing.T, a *AuthManager) (string, int64, []string) {
+	t.Helper()
+	ctx := context.Background()
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	enrollment, err := a.EnrollMfa(ctx, s.Id)
+	if err != nil {
+		t.Fatalf("EnrollMfa: %v", err)
+	}
+	step := totpStep(utils.GetNowTz())
+	codes, err := a.ConfirmMfa(ctx, s.Id, mustTotpCode(t, enrollment.Secret, step))
+	if err != nil {
+		t.Fatalf("ConfirmMfa: %v", err)
+	}
+	return enrollment.Secret, step, codes
+}
+
+// loginWithMfa checks the password of alice and returns the MFA challenge.
+func loginWithMfa(t *testing.T, a *AuthManager) string {
+	t.Helper()
+	s, challenge, err := a.LoginUser(context.Background(), "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	if challenge == nil || s.Id != "" {
+		t.Fatalf("LoginUser: got session %q and challenge %v, want a challenge only", s.Id, challenge)
+	}
+	return challenge.Token
+}
+
+func TestVerifyTotp(t *testing.T) {
+	secret, err := newTotpSecret()
+	if err != nil {
+		t.Fatalf("newTotpSecret: %v", err)
+	}
+	now := utils.GetNowTz()
+	tests := []struct {
+		name   string
+		offset int64
+		wantOk bool
+	}{
+		{"two steps behind", -2, false},
+		{"one step behind", -1, true},
+		{"current step", 0, true},
+		{"one step ahead", 1, true},
+		{"two steps ahead", 2, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			step := totpStep(now) + tt.offset
+			code, err := totpCode(secret, step)
+			if err != nil {
+				t.Fatalf("totpCode: %v", err)
+			}
+			got, ok := verifyTotp(secret, " "+code+" ", now)
+			if ok != tt.wantOk || ok && got != step {
+				t.Fatalf("verifyTotp: got step %d, %v, want step %d, %v", got, ok, step, tt.wantOk)
+			}
+		})
+	}
+	if _, ok := verifyTotp(secret, "12345", now); ok {
+		t.Fatalf("verifyTotp accepted a code of 5 digits")
+	}
+}
+
+func TestMfaLogin(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	secret, step, recoveryCodes := enableMfa(t, a)
+	challenge := loginWithMfa(t, a)
+	tests := []struct {
+		name    string
+		code    string
+		wantErr error
+	}{
+		{"code used to confirm", mustTotpCode(t, secret, step), ErrInvalidMfaCode},
+		{"wrong code", "000000", ErrInvalidMfaCode},
+		{"next code", mustTotpCode(t, secret, step+1), nil},
+		{"replayed code", mustTotpCode(t, secret, step+1), ErrInvalidMfaCode},
+		{"recovery code", recoveryCodes[0], nil},
+		{"used recovery code", recoveryCodes[0], ErrInvalidMfaCode},
+		{"recovery code typed in upper case", strings.ToUpper(recoveryCodes[1]), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := a.VerifyMfa(ctx, challenge, tt.code)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("VerifyMfa: got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if s.Username != "alice" {
+				t.Fatalf("got a session of %q, want alice", s.Username)
+			}
+			if _, err := a.VerifyMfa(ctx, challenge, tt.code); !errors.Is(err, ErrMfaChallengeNotFound) {
+				t.Fatalf("VerifyMfa of a completed challenge: got %v, want ErrMfaChallengeNotFound", err)
+			}
+			challenge = loginWithMfa(t, a)
+		})
+	}
+}
+
+func TestMfaChallengeExpiry(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{Mfa: MfaConfig{ChallengeTtl: time.Nanosecond}})
+	secret, step, _ := enableMfa(t, a)
+	challenge := loginWithMfa(t, a)
+	time.Sleep(time.Millisecond)
+	if _, err := a.VerifyMfa(ctx, challenge, mustTotpCode(t, secret, step+1)); !errors.Is(err, ErrMfaChallengeNotFound) {
+		t.Fatalf("VerifyMfa of an expired challenge: got %v, want ErrMfaChallengeNotFound", err)
+	}
+	if _, err := a.VerifyMfa(ctx, "unknown", mustTotpCode(t, secret, step+1)); !errors.Is(err, ErrMfaChallengeNotFound) {
+		t.Fatalf("VerifyMfa of an unknown challenge: got %v, want ErrMfaChallengeNotFound", err)
+	}
+}
+
+func TestMfaSecretSealed(t *testing.T) {
+	ctx := context.Background()
+	a, store := newTestAuthManager(t, AuthManagerConfig{SigningKeys: SigningKeyConfig{MasterKey: testMasterKey}})
+	secret, step, _ := enableMfa(t, a)
+	m, err := store.GetMfa(ctx, DEFAULT_TENANT, "alice")
+	if err != nil {
+		t.Fatalf("GetMfa: %v", err)
+	}
+	if !strings.HasPrefix(m.Secret, sealedTotpPrefix) || strings.Contains(m.Secret, secret) {
+		t.Fatalf("the stored TOTP secret is not sealed: %q", m.Secret)
+	}
+	if _, err := a.VerifyMfa(ctx, loginWithMfa(t, a), mustTotpCode(t, secret, step+1)); err != nil {
+		t.Fatalf("VerifyMfa with a sealed secret: %v", err)
+	}
+
+	// Secrets stored before a master key was configured remain usable.
+	m.Secret = secret
+	if err := store.DeleteMfa(ctx, DEFAULT_TENANT, "alice"); err != nil {
+		t.Fatalf("DeleteMfa: %v", err)
+	}
+	if err := store.SetMfa(ctx, m); err != nil {
+		t.Fatalf("SetMfa: %v", err)
+	}
+	if err := store.EnableMfa(ctx, DEFAULT_TENANT, "alice", step, nil, utils.GetNowTz()); err != nil {
+		t.Fatalf("EnableMfa: %v", err)
+	}
+	if _, err := a.VerifyMfa(ctx, loginWithMfa(t, a), mustTotpCode(t, secret, step+1)); err != nil {
+		t.Fatalf("VerifyMfa with a plain secret: %v", err)
+	}
+}
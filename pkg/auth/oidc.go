@@ -0,0 +1,353 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Values of OpenID Connect requests understood by SAM.
+const (
+	SCOPE_OPENID     = "openid"
+	SCOPE_PROFILE    = "profile"
+	SCOPE_EMAIL      = "email"
+	PKCE_METHOD_S256 = "S256"
+
+	// OIDC_ACCESS_TOKEN_PREFIX tells access tokens issued to relying
+	// parties from session ids and service tokens.
+	OIDC_ACCESS_TOKEN_PREFIX = "oidc_"
+)
+
+// SupportedScopes are the scopes SAM grants; others are dropped.
+var SupportedScopes = []string{SCOPE_OPENID, SCOPE_PROFILE, SCOPE_EMAIL}
+
+// AuthorizationRequest is an authentication request of the authorization
+// code flow. PKCE with S256 is required of every client.
+type AuthorizationRequest struct {
+	ClientId            string
+	RedirectUri         string
+	Scope               string
+	Nonce               string
+	CodeChallenge       string
+	CodeChallengeMethod string
+}
+
+// OidcTokens are returned to a client exchanging an authorization code.
+// The access token is bound to the client and the granted scope, and only
+// lasts as long as the session the user opened by signing in.
+type OidcTokens struct {
+	AccessToken string
+	ExpiresAt   time.Time
+	IdToken     string
+	Scope       string
+}
+
+// IdTokenClaims are carried by ID tokens.
+type IdTokenClaims struct {
+	Nonce             string           `json:"nonce,omitempty"`
+	AuthTime          *jwt.NumericDate `json:"auth_time,omitempty"`
+	SessionId         string           `json:"sid,omitempty"`
+	PreferredUsername string           `json:"preferred_username,omitempty"`
+	Name              string           `json:"name,omitempty"`
+	Email             string           `json:"email,omitempty"`
+	EmailVerified     *bool            `json:"email_verified,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// OidcGrant is what an access token issued to a relying party allows.
+type OidcGrant struct {
+	ClientId  string    `json:"client_id"`
+	Scope     string    `json:"scope"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// oidcAccessTokenEntry is the payload cached under an access token issued
+// to a relying party. The session id never leaves SAM.
+type oidcAccessTokenEntry struct {
+	OidcGrant
+	SessionId string `json:"session_id"`
+}
+
+// UserInfo is returned by the userinfo endpoint.
+type UserInfo struct {
+	Subject           string `json:"sub"`
+	PreferredUsername string `json:"preferred_username,omitempty"`
+	Name              string `json:"name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	EmailVerified     *bool  `json:"email_verified,omitempty"`
+}
+
+// authorizationCodeEntry is the payload cached under an authorization code.
+type authorizationCodeEntry struct {
+	ClientId      string `json:"client_id"`
+	RedirectUri   string `json:"redirect_uri"`
+	Scope         string `json:"scope"`
+	Nonce         string `json:"nonce"`
+	CodeChallenge string `json:"code_challenge"`
+	SessionId     string `json:"session_id"`
+}
+
+func (a *AuthManager) composeAuthorizationCodeKey(code string) string {
+	return fmt.Sprintf("oidc_code_%s", code)
+}
+
+func (a *AuthManager) composeOidcAccessTokenKey(token string) string {
+	return fmt.Sprintf("oidc_access_token_%s", token)
+}
+
+// OidcIssuer returns the issuer of ID tokens, empty if OIDC is disabled.
+func (a *AuthManager) OidcIssuer() string {
+	if !a.oidcConfig.Enabled {
+		return ""
+	}
+	return a.oidcConfig.Issuer
+}
+
+// grantedScope keeps the supported scopes of a request, in their order.
+func grantedScope(scope string) (string, error) {
+	requested := strings.Fields(scope)
+	if !slices.Contains(requested, SCOPE_OPENID) {
+		return "", fmt.Errorf("%w: %s is required", ErrInvalidScope, SCOPE_OPENID)
+	}
+	granted := make([]string, 0, len(SupportedScopes))
+	for _, s := range SupportedScopes {
+		if slices.Contains(requested, s) {
+			granted = append(granted, s)
+		}
+	}
+	return strings.Join(granted, " "), nil
+}
+
+// validPkceValue checks the syntax of RFC 7636 code verifiers, which
+// S256 challenges share, being 43 characters of base64url.
+func validPkceValue(v string) bool {
+	if len(v) < 43 || len(v) > 128 {
+		return false
+	}
+	for _, c := range v {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '.', c == '_', c == '~':
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+// CheckAuthorizationRequest validates a request before the user signs in.
+// ErrClientNotFound and ErrInvalidRedirectUri must be shown to the user,
+// as the redirect uri cannot be trusted; other errors are meant for it.
+func (a *AuthManager) CheckAuthorizationRequest(ctx context.Context, r AuthorizationRequest) (OAuthClient, error) {
+	if !a.oidcConfig.Enabled {
+		return OAuthClient{}, ErrOidcDisabled
+	}
+	c, err := a.GetClient(ctx, r.ClientId)
+	if err != nil {
+		return OAuthClient{}, err
+	}
+	if !c.allowsRedirect(r.RedirectUri) {
+		return OAuthClient{}, ErrInvalidRedirectUri
+	}
+	if _, err := grantedScope(r.Scope); err != nil {
+		return c, err
+	}
+	if r.CodeChallengeMethod != PKCE_METHOD_S256 {
+		return c, invalidArgument("code_challenge_method must be %s", PKCE_METHOD_S256)
+	}
+	if !validPkceValue(r.CodeChallenge) {
+		return c, invalidArgument("malformed code_challenge")
+	}
+	return c, nil
+}
+
+// IssueAuthorizationCode returns a single-use code for the session the user
+// opened by signing in, to be sent to the redirect uri of a request that
+// passed CheckAuthorizationRequest.
+func (a *AuthManager) IssueAuthorizationCode(ctx context.Context, r AuthorizationRequest, s Session) (string, error) {
+	scope, err := grantedScope(r.Scope)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	code := base64.RawURLEncoding.EncodeToString(b)
+	data, err := json.Marshal(authorizationCodeEntry{
+		ClientId:      r.ClientId,
+		RedirectUri:   r.RedirectUri,
+		Scope:         scope,
+		Nonce:         r.Nonce,
+		CodeChallenge: r.CodeChallenge,
+		SessionId:     s.Id,
+	})
+	if err != nil {
+		return "", err
+	}
+	if err := a.cache.Set(ctx, a.composeAuthorizationCodeKey(code), data, a.oidcConfig.CodeTtl); err != nil {
+		return "", err
+	}
+	return code, nil
+}
+
+// ExchangeAuthorizationCode redeems a code for the authenticated client.
+// The code is consumed even if the exchange fails.
+func (a *AuthManager) ExchangeAuthorizationCode(ctx context.Context, c OAuthClient, code, redirectUri, verifier string) (OidcTokens, error) {
+	if !a.oidcConfig.Enabled {
+		return OidcTokens{}, ErrOidcDisabled
+	}
+	data, err := a.cache.Take(ctx, a.composeAuthorizationCodeKey(code))
+	if errors.Is(err, ErrCacheMiss) {
+		return OidcTokens{}, ErrInvalidGrant
+	}
+	if err != nil {
+		return OidcTokens{}, err
+	}
+	var entry authorizationCodeEntry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return OidcTokens{}, err
+	}
+	if entry.ClientId != c.Id || entry.RedirectUri != redirectUri {
+		return OidcTokens{}, ErrInvalidGrant
+	}
+	challenge := sha256.Sum256([]byte(verifier))
+	expected := base64.RawURLEncoding.EncodeToString(challenge[:])
+	if !validPkceValue(verifier) || subtle.ConstantTimeCompare([]byte(expected), []byte(entry.CodeChallenge)) != 1 {
+		return OidcTokens{}, ErrInvalidGrant
+	}
+	user, s, err := a.GetUserBySessionId(ctx, entry.SessionId)
+	if errors.Is(err, ErrSessionNotFound) || errors.Is(err, ErrSessionExpired) {
+		return OidcTokens{}, ErrInvalidGrant
+	}
+	if err != nil {
+		return OidcTokens{}, err
+	}
+	now := utils.GetNowTz()
+	idToken, err := a.issueIdToken(c, entry, user, s, now)
+	if err != nil {
+		return OidcTokens{}, err
+	}
+	accessToken, grant, err := a.issueOidcAccessToken(ctx, c, entry.Scope, s, now)
+	if err != nil {
+		return OidcTokens{}, err
+	}
+	return OidcTokens{
+		AccessToken: accessToken,
+		ExpiresAt:   grant.ExpiresAt,
+		IdToken:     idToken,
+		Scope:       entry.Scope,
+	}, nil
+}
+
+// issueOidcAccessToken returns an opaque token granting the client the scope
+// on the session, expiring no later than the session.
+func (a *AuthManager) issueOidcAccessToken(ctx context.Context, c OAuthClient, scope string, s Session, now time.Time) (string, OidcGrant, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", OidcGrant{}, err
+	}
+	token := OIDC_ACCESS_TOKEN_PREFIX + base64.RawURLEncoding.EncodeToString(b)
+	expiresAt := now.Add(a.oidcConfig.AccessTokenTtl)
+	if s.ValidThrough.Before(expiresAt) {
+		expiresAt = s.ValidThrough
+	}
+	entry := oidcAccessTokenEntry{
+		OidcGrant: OidcGrant{ClientId: c.Id, Scope: scope, IssuedAt: now, ExpiresAt: expiresAt},
+		SessionId: s.Id,
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", OidcGrant{}, err
+	}
+	if err := a.cache.Set(ctx, a.composeOidcAccessTokenKey(token), data, expiresAt.Sub(now)); err != nil {
+		return "", OidcGrant{}, err
+	}
+	return token, entry.OidcGrant, nil
+}
+
+// GetOidcGrant returns what an access token issued to a relying party
+// allows, along with the user and the session behind it. Unknown and
+// expired tokens fail with ErrSessionNotFound, like session ids.
+func (a *AuthManager) GetOidcGrant(ctx context.Context, token string) (OidcGrant, User, Session, error) {
+	if !strings.HasPrefix(token, OIDC_ACCESS_TOKEN_PREFIX) {
+		return OidcGrant{}, User{}, Session{}, ErrSessionNotFound
+	}
+	var entry oidcAccessTokenEntry
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_OIDC_ACCESS_TOKEN, a.composeOidcAccessTokenKey(token), &entry)
+	if errors.Is(err, ErrBackendUnavailable) {
+		return OidcGrant{}, User{}, Session{}, err
+	}
+	if err != nil || !entry.ExpiresAt.After(utils.GetNowTz()) {
+		return OidcGrant{}, User{}, Session{}, ErrSessionNotFound
+	}
+	user, s, err := a.GetUserBySessionId(ctx, entry.SessionId)
+	if err != nil {
+		return OidcGrant{}, User{}, Session{}, err
+	}
+	return entry.OidcGrant, user, s, nil
+}
+
+func (a *AuthManager) issueIdToken(c OAuthClient, entry authorizationCodeEntry, u User, s Session, now time.Time) (string, error) {
+	claims := IdTokenClaims{
+		Nonce:     entry.Nonce,
+		AuthTime:  jwt.NewNumericDate(s.CreatedAt),
+		SessionId: s.Handle(),
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newTokenId(),
+			Issuer:    a.oidcConfig.Issuer,
+			Subject:   u.Subject(),
+			Audience:  jwt.ClaimStrings{c.Id},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(a.oidcConfig.IdTokenTtl)),
+		},
+	}
+	if slices.Contains(strings.Fields(entry.Scope), SCOPE_PROFILE) {
+		claims.PreferredUsername = u.Username
+		claims.Name = u.DisplayName
+	}
+	if slices.Contains(strings.Fields(entry.Scope), SCOPE_EMAIL) && u.Email != "" {
+		verified := u.EmailVerified()
+		claims.Email, claims.EmailVerified = u.Email, &verified
+	}
+	key, ok := a.keys.current(now)
+	if !ok {
+		return "", fmt.Errorf("no active signing key")
+	}
+	return key.sign(claims)
+}
+
+// GetUserInfo returns the claims the scope of an access token returned by
+// ExchangeAuthorizationCode allows about its user.
+func (a *AuthManager) GetUserInfo(ctx context.Context, accessToken string) (UserInfo, error) {
+	if !a.oidcConfig.Enabled {
+		return UserInfo{}, ErrOidcDisabled
+	}
+	grant, user, _, err := a.GetOidcGrant(ctx, accessToken)
+	if err != nil {
+		return UserInfo{}, err
+	}
+	scope := strings.Fields(grant.Scope)
+	info := UserInfo{Subject: user.Subject()}
+	if slices.Contains(scope, SCOPE_PROFILE) {
+		info.PreferredUsername, info.Name = user.Username, user.DisplayName
+	}
+	if slices.Contains(scope, SCOPE_EMAIL) && user.Email != "" {
+		verified := user.EmailVerified()
+		info.Email, info.EmailVerified = user.Email, &verified
+	}
+	return info, nil
+}
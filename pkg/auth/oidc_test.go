@@ -0,0 +1,101 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestOidcAccessToken(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{
+		Oidc:        OidcConfig{Enabled: true, Issuer: "https://sam.example.com"},
+		SigningKeys: SigningKeyConfig{MasterKey: testMasterKey},
+	})
+	client, secret, err := a.CreateClient(ctx, "app", []string{"https://app.example.com/callback"}, false)
+	if err != nil {
+		t.Fatalf("CreateClient: %v", err)
+	}
+	if client, err = a.AuthenticateClient(ctx, client.Id, secret); err != nil {
+		t.Fatalf("AuthenticateClient: %v", err)
+	}
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	verifier := strings.Repeat("v", 43)
+	challenge := sha256.Sum256([]byte(verifier))
+	r := AuthorizationRequest{
+		ClientId:            client.Id,
+		RedirectUri:         "https://app.example.com/callback",
+		Scope:               "openid email",
+		CodeChallenge:       base64.RawURLEncoding.EncodeToString(challenge[:]),
+		CodeChallengeMethod: PKCE_METHOD_S256,
+	}
+	code, err := a.IssueAuthorizationCode(ctx, r, s)
+	if err != nil {
+		t.Fatalf("IssueAuthorizationCode: %v", err)
+	}
+	tokens, err := a.ExchangeAuthorizationCode(ctx, client, code, r.RedirectUri, verifier)
+	if err != nil {
+		t.Fatalf("ExchangeAuthorizationCode: %v", err)
+	}
+	if tokens.AccessToken == s.Id || !strings.HasPrefix(tokens.AccessToken, OIDC_ACCESS_TOKEN_PREFIX) {
+		t.Fatalf("got access token %q, want an %s token", tokens.AccessToken, OIDC_ACCESS_TOKEN_PREFIX)
+	}
+	var claims IdTokenClaims
+	if _, _, err := jwt.NewParser().ParseUnverified(tokens.IdToken, &claims); err != nil {
+		t.Fatalf("parsing the ID token: %v", err)
+	}
+	if claims.SessionId != s.Handle() {
+		t.Fatalf("got sid %q, want the session handle %q", claims.SessionId, s.Handle())
+	}
+
+	info, err := a.GetUserInfo(ctx, tokens.AccessToken)
+	if err != nil {
+		t.Fatalf("GetUserInfo: %v", err)
+	}
+	if info.Email != "alice@example.com" || info.PreferredUsername != "" {
+		t.Fatalf("got %+v, want the email claims of the scope only", info)
+	}
+	grant, _, _, err := a.GetOidcGrant(ctx, tokens.AccessToken)
+	if err != nil || grant.ClientId != client.Id || grant.Scope != "openid email" {
+		t.Fatalf("GetOidcGrant: got %+v, %v", grant, err)
+	}
+
+	tests := []struct {
+		name  string
+		check func() error
+	}{
+		{"userinfo with the session id", func() error {
+			_, err := a.GetUserInfo(ctx, s.Id)
+			return err
+		}},
+		{"authenticate with the access token", func() error {
+			_, err := a.Authenticate(ctx, tokens.AccessToken)
+			return err
+		}},
+		{"userinfo with a revoked access token", func() error {
+			if err := a.RevokeToken(ctx, tokens.AccessToken); err != nil {
+				return err
+			}
+			_, err := a.GetUserInfo(ctx, tokens.AccessToken)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.check(); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("got %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+}
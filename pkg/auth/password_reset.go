@@ -0,0 +1,121 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/notify"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// PasswordResetToken is stored by hash only; the token itself is only
+// ever known to the user it was sent to.
+type PasswordResetToken struct {
+	Hash      string
+	TenantId  string
+	Username  string
+	CreatedAt time.Time
+	ExpiresAt time.Time
+}
+
+func hashResetToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newResetToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func (a *AuthManager) resetMessage(u User, token string, expiresAt time.Time) notify.Message {
+	var body strings.Builder
+	body.WriteString("A password reset was requested for your account.\n\n")
+	if a.resetConfig.Link != "" {
+		fmt.Fprintf(&body, "Follow this link to choose a new password:\n%s\n\n",
+			strings.ReplaceAll(a.resetConfig.Link, "{token}", token))
+	} else {
+		fmt.Fprintf(&body, "Use this token to choose a new password:\n%s\n\n", token)
+	}
+	fmt.Fprintf(&body, "It can be used once, until %s.\n", expiresAt.Format(time.RFC1123))
+	body.WriteString("If you did not ask for it, ignore this message.\n")
+	return notify.Message{
+		Username: u.Subject(),
+		Email:    u.Email,
+		Subject:  "Password reset",
+		Body:     body.String(),
+	}
+}
+
+// RequestPasswordReset sends a single-use reset token to the user. It
+// succeeds whether or not the user exists, so that callers do not reveal
+// which usernames exist; delivery failures are only logged for that reason.
+func (a *AuthManager) RequestPasswordReset(ctx context.Context, tenantId, username string, client ClientInfo) error {
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.allowAttempt(ctx, LIMIT_PASSWORD_RESET, tenantId, username, client.Ip); err != nil {
+		return err
+	}
+	user, err := a.getUserByUsername(ctx, tenantId, username)
+	if errors.Is(err, ErrInvalidCredentials) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if user.Disabled() {
+		return nil
+	}
+	token, err := newResetToken()
+	if err != nil {
+		return err
+	}
+	now := utils.GetNowTz()
+	t := PasswordResetToken{
+		Hash:      hashResetToken(token),
+		TenantId:  user.TenantId,
+		Username:  user.Username,
+		CreatedAt: now,
+		ExpiresAt: now.Add(a.resetConfig.TokenTtl),
+	}
+	if err := a.resets.CreatePasswordResetToken(ctx, t); err != nil {
+		return err
+	}
+	if err := a.notifier.Notify(ctx, a.resetMessage(user, token, t.ExpiresAt)); err != nil {
+		slog.ErrorContext(ctx, "Error sending password reset", "tenant_id", user.TenantId, "username", user.Username, "error", err)
+	}
+	return nil
+}
+
+// ResetPassword sets a new password given a token from RequestPasswordReset
+// and signs the user out everywhere.
+func (a *AuthManager) ResetPassword(ctx context.Context, token, newPassword string) error {
+	if len(newPassword) == 0 {
+		return invalidArgument("password must not be empty")
+	}
+	hash, err := a.hashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	user, err := a.resets.ResetPassword(ctx, hashResetToken(token), hash, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return ErrInvalidResetToken
+	}
+	if err != nil {
+		return err
+	}
+	a.resetFailures(ctx, LIMIT_LOGIN, user.TenantId, user.Username)
+	if err := a.invalidateUserSessions(ctx, user, ""); err != nil {
+		return err
+	}
+	return a.cache.Del(ctx, a.composeUserKey(user.TenantId, user.Username))
+}
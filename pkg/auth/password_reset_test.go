@@ -0,0 +1,92 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+
+	"github.com/JustDean/sam/pkg/notify"
+	"github.com/JustDean/sam/pkg/notify/notifytest"
+)
+
+// newSmtpTestAuthManager returns a manager sending mails to a fake SMTP server.
+func newSmtpTestAuthManager(t *testing.T, c AuthManagerConfig) (*AuthManager, *MemoryStore, *notifytest.SmtpServer) {
+	t.Helper()
+	server := notifytest.NewSmtpServer(t)
+	n, err := notify.NewSmtpNotifier(notify.SmtpConfig{Host: server.Host(), Port: server.Port(), From: "sam@example.com"})
+	if err != nil {
+		t.Fatalf("NewSmtpNotifier: %v", err)
+	}
+	c.Notifier = n
+	a, store := newTestAuthManager(t, c)
+	return a, store, server
+}
+
+// lastMail returns the body of the latest mail sent to the address.
+func lastMail(t *testing.T, server *notifytest.SmtpServer, to string) string {
+	t.Helper()
+	messages := server.Messages()
+	for i := len(messages) - 1; i >= 0; i-- {
+		if len(messages[i].To) == 1 && messages[i].To[0] == to {
+			return messages[i].Body()
+		}
+	}
+	t.Fatalf("no mail was sent to %s", to)
+	return ""
+}
+
+var resetLinkPattern = regexp.MustCompile(`reset\?token=([0-9a-f]+)`)
+
+func TestPasswordReset(t *testing.T) {
+	ctx := context.Background()
+	a, _, server := newSmtpTestAuthManager(t, AuthManagerConfig{
+		PasswordReset: PasswordResetConfig{Link: "https://example.com/reset?token={token}"},
+	})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	if err := a.RequestPasswordReset(ctx, "", "bob", ClientInfo{}); err != nil {
+		t.Fatalf("RequestPasswordReset of unknown user: %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Fatalf("a mail was sent for an unknown user")
+	}
+	if err := a.RequestPasswordReset(ctx, "", "alice", ClientInfo{}); err != nil {
+		t.Fatalf("RequestPasswordReset: %v", err)
+	}
+	match := resetLinkPattern.FindStringSubmatch(lastMail(t, server, "alice@example.com"))
+	if match == nil {
+		t.Fatalf("no reset link in the mail")
+	}
+	token := match[1]
+
+	tests := []struct {
+		name     string
+		token    string
+		password string
+		wantErr  error
+	}{
+		{"empty password", token, "", ErrInvalidArgument},
+		{"unknown token", "0123", "new secret", ErrInvalidResetToken},
+		{"valid token", token, "new secret", nil},
+		{"used token", token, "newer secret", ErrInvalidResetToken},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := a.ResetPassword(ctx, tt.token, tt.password); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ResetPassword: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+	if _, _, err := a.GetUserBySessionId(ctx, s.Id); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("session after reset: got %v, want ErrSessionExpired", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "", "alice", "new secret", ClientInfo{}); err != nil {
+		t.Fatalf("LoginUser with new password: %v", err)
+	}
+}
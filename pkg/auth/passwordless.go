@@ -0,0 +1,244 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/big"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/notify"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// PASSWORDLESS_CHANNEL_EMAIL delivers passwordless codes with the notifier.
+const PASSWORDLESS_CHANNEL_EMAIL = "email"
+
+// PasswordlessDelivery is what a channel sends to the user.
+type PasswordlessDelivery struct {
+	Code string
+	// Link is the magic link, empty unless PasswordlessConfig.Link is set.
+	Link      string
+	ExpiresAt time.Time
+}
+
+// PasswordlessChannel delivers one-time codes and magic links to users.
+type PasswordlessChannel interface {
+	Deliver(ctx context.Context, u User, d PasswordlessDelivery) error
+}
+
+// notifierChannel delivers codes as notify messages.
+type notifierChannel struct {
+	notifier notify.Notifier
+}
+
+func (c *notifierChannel) Deliver(ctx context.Context, u User, d PasswordlessDelivery) error {
+	var body strings.Builder
+	fmt.Fprintf(&body, "Your sign-in code is %s\n\n", d.Code)
+	if d.Link != "" {
+		fmt.Fprintf(&body, "Or follow this link to sign in:\n%s\n\n", d.Link)
+	}
+	fmt.Fprintf(&body, "It can be used once, until %s.\n", d.ExpiresAt.Format(time.RFC1123))
+	body.WriteString("If you did not try to sign in, ignore this message.\n")
+	return c.notifier.Notify(ctx, notify.Message{
+		Username: u.Subject(),
+		Email:    u.Email,
+		Subject:  "Sign-in code",
+		Body:     body.String(),
+	})
+}
+
+// PasswordlessChallenge is returned by StartPasswordlessLogin.
+// CompletePasswordlessLogin exchanges it and the delivered code for a session.
+type PasswordlessChallenge struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// passwordlessEntry is the payload cached under a challenge key. The code
+// and the magic link are only kept hashed.
+type passwordlessEntry struct {
+	TenantId  string    `json:"tenant_id"`
+	Username  string    `json:"username"`
+	CodeHash  string    `json:"code_hash"`
+	LinkHash  string    `json:"link_hash"`
+	Attempts  int       `json:"attempts"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+func (a *AuthManager) composePasswordlessKey(token string) string {
+	return fmt.Sprintf("passwordless_%s", token)
+}
+
+// hashPasswordlessSecret hashes a code or link secret along with its
+// challenge, so that equal codes of different challenges differ.
+func hashPasswordlessSecret(challenge, secret string) string {
+	sum := sha256.Sum256([]byte(challenge + ":" + secret))
+	return hex.EncodeToString(sum[:])
+}
+
+func newPasswordlessCode(digits int) (string, error) {
+	n, err := rand.Int(rand.Reader, new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(digits)), nil))
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", digits, n), nil
+}
+
+func newPasswordlessToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// StartPasswordlessLogin sends a one-time code, and a magic link if
+// configured, to the user through the named channel, the email channel by
+// default. Like RequestPasswordReset, it returns a challenge whether or not
+// the user exists, and delivery failures are only logged.
+func (a *AuthManager) StartPasswordlessLogin(ctx context.Context, tenantId, username, channel string, client ClientInfo) (PasswordlessChallenge, error) {
+	if !a.passwordlessConfig.Enabled {
+		return PasswordlessChallenge{}, ErrPasswordlessDisabled
+	}
+	if channel == "" {
+		channel = PASSWORDLESS_CHANNEL_EMAIL
+	}
+	ch, ok := a.passwordlessChannels[channel]
+	if !ok {
+		return PasswordlessChallenge{}, invalidArgument("unknown passwordless channel %q", channel)
+	}
+	tenantId = tenantOrDefault(tenantId)
+	if err := a.allowAttempt(ctx, LIMIT_PASSWORDLESS_START, tenantId, username, client.Ip); err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	token, err := newPasswordlessToken()
+	if err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	now := utils.GetNowTz()
+	c := PasswordlessChallenge{Token: token, ExpiresAt: now.Add(a.passwordlessConfig.Ttl)}
+	err = a.checkTenant(ctx, tenantId)
+	var user User
+	if err == nil {
+		user, err = a.getUserByUsername(ctx, tenantId, username)
+	}
+	if errors.Is(err, ErrInvalidCredentials) {
+		return c, nil
+	}
+	if err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	if user.Disabled() {
+		return c, nil
+	}
+	code, err := newPasswordlessCode(a.passwordlessConfig.CodeLength)
+	if err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	linkSecret, err := newPasswordlessToken()
+	if err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	data, err := json.Marshal(passwordlessEntry{
+		TenantId:  user.TenantId,
+		Username:  user.Username,
+		CodeHash:  hashPasswordlessSecret(token, code),
+		LinkHash:  hashPasswordlessSecret(token, linkSecret),
+		ExpiresAt: c.ExpiresAt,
+	})
+	if err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	if err := a.cache.Set(ctx, a.composePasswordlessKey(token), data, a.passwordlessConfig.Ttl); err != nil {
+		return PasswordlessChallenge{}, err
+	}
+	d := PasswordlessDelivery{Code: code, ExpiresAt: c.ExpiresAt}
+	if a.passwordlessConfig.Link != "" {
+		d.Link = strings.ReplaceAll(a.passwordlessConfig.Link, "{token}", token+"."+linkSecret)
+	}
+	if err := ch.Deliver(ctx, user, d); err != nil {
+		slog.ErrorContext(ctx, "Error delivering passwordless code", "tenant_id", user.TenantId, "username", user.Username, "channel", channel, "error", err)
+	}
+	return c, nil
+}
+
+// CompletePasswordlessLogin completes a login started by
+// StartPasswordlessLogin, given either the challenge token and the code,
+// or the token of the magic link and no code. Like LoginUser, it returns
+// an MFA challenge instead of a session if the user has MFA enabled.
+// Wrong codes count towards a lockout of passwordless logins and void the
+// challenge after PasswordlessConfig.MaxAttempts.
+func (a *AuthManager) CompletePasswordlessLogin(ctx context.Context, token, code string, client ClientInfo) (Session, *MfaChallenge, error) {
+	if !a.passwordlessConfig.Enabled {
+		return Session{}, nil, ErrPasswordlessDisabled
+	}
+	challenge, secret, isLink := strings.Cut(token, ".")
+	if !isLink {
+		secret = code
+	}
+	key := a.composePasswordlessKey(challenge)
+	var entry passwordlessEntry
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_PASSWORDLESS, key, &entry)
+	if errors.Is(err, ErrBackendUnavailable) {
+		return Session{}, nil, err
+	}
+	if err != nil {
+		return Session{}, nil, ErrInvalidPasswordlessCode
+	}
+	if err := a.allowAttempt(ctx, LIMIT_PASSWORDLESS, entry.TenantId, entry.Username, client.Ip); err != nil {
+		return Session{}, nil, err
+	}
+	// Taking the entry makes concurrent attempts on the challenge fail
+	// rather than go uncounted.
+	data, err := a.cache.Take(ctx, key)
+	if errors.Is(err, ErrCacheMiss) {
+		return Session{}, nil, ErrInvalidPasswordlessCode
+	}
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return Session{}, nil, err
+	}
+	want := entry.CodeHash
+	if isLink {
+		want = entry.LinkHash
+	}
+	if secret == "" || subtle.ConstantTimeCompare([]byte(hashPasswordlessSecret(challenge, secret)), []byte(want)) != 1 {
+		a.recordFailure(ctx, LIMIT_PASSWORDLESS, entry.TenantId, entry.Username)
+		a.retainPasswordlessEntry(ctx, key, entry)
+		return Session{}, nil, ErrInvalidPasswordlessCode
+	}
+	a.resetFailures(ctx, LIMIT_PASSWORDLESS, entry.TenantId, entry.Username)
+	user, err := a.getUserByUsername(ctx, entry.TenantId, entry.Username)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	return a.completeLogin(ctx, user, client)
+}
+
+// retainPasswordlessEntry puts back an entry after a wrong code, unless it
+// ran out of attempts or time.
+func (a *AuthManager) retainPasswordlessEntry(ctx context.Context, key string, entry passwordlessEntry) {
+	entry.Attempts++
+	ttl := entry.ExpiresAt.Sub(utils.GetNowTz())
+	if entry.Attempts >= a.passwordlessConfig.MaxAttempts || ttl <= 0 {
+		return
+	}
+	data, err := json.Marshal(entry)
+	if err == nil {
+		err = a.cache.Set(ctx, key, data, ttl)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "Error keeping passwordless challenge", "tenant_id", entry.TenantId, "username", entry.Username, "error", err)
+	}
+}
@@ -0,0 +1,141 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/JustDean/sam/pkg/notify/notifytest"
+)
+
+var (
+	passwordlessCodePattern = regexp.MustCompile(`sign-in code is ([0-9]+)`)
+	passwordlessLinkPattern = regexp.MustCompile(`login\?token=([0-9a-f]+\.[0-9a-f]+)`)
+)
+
+// newPasswordlessTestAuthManager returns a manager with passwordless logins
+// sending magic links, and a user alice.
+func newPasswordlessTestAuthManager(t *testing.T, c PasswordlessConfig) (*AuthManager, *notifytest.SmtpServer) {
+	t.Helper()
+	c.Enabled = true
+	c.Link = "https://example.com/login?token={token}"
+	a, _, server := newSmtpTestAuthManager(t, AuthManagerConfig{Passwordless: c})
+	if _, err := a.CreateUser(context.Background(), "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return a, server
+}
+
+// startPasswordlessLogin starts a login of alice and returns the challenge
+// token, the code and the magic link token of the mail.
+func startPasswordlessLogin(t *testing.T, a *AuthManager, server *notifytest.SmtpServer) (string, string, string) {
+	t.Helper()
+	c, err := a.StartPasswordlessLogin(context.Background(), "", "alice", "", ClientInfo{})
+	if err != nil {
+		t.Fatalf("StartPasswordlessLogin: %v", err)
+	}
+	body := lastMail(t, server, "alice@example.com")
+	code := passwordlessCodePattern.FindStringSubmatch(body)
+	link := passwordlessLinkPattern.FindStringSubmatch(body)
+	if code == nil || link == nil {
+		t.Fatalf("no code or link in the mail")
+	}
+	return c.Token, code[1], link[1]
+}
+
+func TestPasswordlessLogin(t *testing.T) {
+	ctx := context.Background()
+	a, server := newPasswordlessTestAuthManager(t, PasswordlessConfig{})
+	if _, err := a.StartPasswordlessLogin(ctx, "", "bob", "", ClientInfo{}); err != nil {
+		t.Fatalf("StartPasswordlessLogin of unknown user: %v", err)
+	}
+	if len(server.Messages()) != 0 {
+		t.Fatalf("a mail was sent for an unknown user")
+	}
+	if _, err := a.StartPasswordlessLogin(ctx, "", "alice", "sms", ClientInfo{}); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("StartPasswordlessLogin with an unknown channel: got %v, want ErrInvalidArgument", err)
+	}
+
+	token, code, _ := startPasswordlessLogin(t, a, server)
+	_, _, link := startPasswordlessLogin(t, a, server)
+	tests := []struct {
+		name    string
+		token   string
+		code    string
+		wantErr error
+	}{
+		{"code", token, code, nil},
+		{"used code", token, code, ErrInvalidPasswordlessCode},
+		{"link", link, "", nil},
+		{"used link", link, "", ErrInvalidPasswordlessCode},
+		{"unknown token", "0123", code, ErrInvalidPasswordlessCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, _, err := a.CompletePasswordlessLogin(ctx, tt.token, tt.code, ClientInfo{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompletePasswordlessLogin: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && s.Username != "alice" {
+				t.Fatalf("got a session of %q, want alice", s.Username)
+			}
+		})
+	}
+}
+
+func TestPasswordlessAttempts(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		wrong   int
+		useLink bool
+		wantErr error
+	}{
+		{"code after wrong codes", 2, false, nil},
+		{"code after too many wrong codes", 3, false, ErrInvalidPasswordlessCode},
+		{"link after too many wrong codes", 3, true, ErrInvalidPasswordlessCode},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, server := newPasswordlessTestAuthManager(t, PasswordlessConfig{MaxAttempts: 3})
+			token, code, link := startPasswordlessLogin(t, a, server)
+			for i := 0; i < tt.wrong; i++ {
+				if _, _, err := a.CompletePasswordlessLogin(ctx, token, "wrong", ClientInfo{}); !errors.Is(err, ErrInvalidPasswordlessCode) {
+					t.Fatalf("CompletePasswordlessLogin with a wrong code: got %v, want ErrInvalidPasswordlessCode", err)
+				}
+			}
+			if tt.useLink {
+				token, code = link, ""
+			}
+			if _, _, err := a.CompletePasswordlessLogin(ctx, token, code, ClientInfo{}); !errors.Is(err, tt.wantErr) {
+				t.Fatalf("CompletePasswordlessLogin: got %v, want %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestPasswordlessExpiry(t *testing.T) {
+	ctx := context.Background()
+	a, server := newPasswordlessTestAuthManager(t, PasswordlessConfig{Ttl: time.Nanosecond})
+	token, code, link := startPasswordlessLogin(t, a, server)
+	time.Sleep(time.Millisecond)
+	if _, _, err := a.CompletePasswordlessLogin(ctx, token, code, ClientInfo{}); !errors.Is(err, ErrInvalidPasswordlessCode) {
+		t.Fatalf("CompletePasswordlessLogin with an expired code: got %v, want ErrInvalidPasswordlessCode", err)
+	}
+	if _, _, err := a.CompletePasswordlessLogin(ctx, link, "", ClientInfo{}); !errors.Is(err, ErrInvalidPasswordlessCode) {
+		t.Fatalf("CompletePasswordlessLogin with an expired link: got %v, want ErrInvalidPasswordlessCode", err)
+	}
+}
+
+func TestPasswordlessDisabled(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	if _, err := a.StartPasswordlessLogin(ctx, "", "alice", "", ClientInfo{}); !errors.Is(err, ErrPasswordlessDisabled) {
+		t.Fatalf("StartPasswordlessLogin: got %v, want ErrPasswordlessDisabled", err)
+	}
+	if _, _, err := a.CompletePasswordlessLogin(ctx, "0123", "0123", ClientInfo{}); !errors.Is(err, ErrPasswordlessDisabled) {
+		t.Fatalf("CompletePasswordlessLogin: got %v, want ErrPasswordlessDisabled", err)
+	}
+}
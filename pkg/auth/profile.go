@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"maps"
+	"net/mail"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	MAX_EMAIL_LENGTH           = 254
+	MAX_DISPLAY_NAME_LENGTH    = 64
+	MAX_ATTRIBUTES             = 32
+	MAX_ATTRIBUTE_KEY_LENGTH   = 64
+	MAX_ATTRIBUTE_VALUE_LENGTH = 1024
+)
+
+// ProfileUpdate changes the profile fields that are set. Attributes are
+// merged into the existing ones, an empty value removing the attribute.
+type ProfileUpdate struct {
+	Email       *string
+	DisplayName *string
+	Attributes  map[string]string
+}
+
+// setAttributes returns the attributes set by the update.
+func (p ProfileUpdate) setAttributes() map[string]string {
+	set := make(map[string]string, len(p.Attributes))
+	for k, v := range p.Attributes {
+		if v != "" {
+			set[k] = v
+		}
+	}
+	return set
+}
+
+// removedAttributes returns the keys of the attributes removed by the update.
+func (p ProfileUpdate) removedAttributes() []string {
+	removed := []string{}
+	for k, v := range p.Attributes {
+		if v == "" {
+			removed = append(removed, k)
+		}
+	}
+	return removed
+}
+
+func validateEmail(email string) error {
+	if email == "" {
+		return nil
+	}
+	addr, err := mail.ParseAddress(email)
+	if err != nil || addr.Address != email || len(email) > MAX_EMAIL_LENGTH {
+		return invalidArgument("malformed email address")
+	}
+	return nil
+}
+
+func validateDisplayName(name string) error {
+	if !utf8.ValidString(name) || utf8.RuneCountInString(name) > MAX_DISPLAY_NAME_LENGTH {
+		return invalidArgument("display name must be at most %d characters", MAX_DISPLAY_NAME_LENGTH)
+	}
+	for _, c := range name {
+		if !unicode.IsPrint(c) {
+			return invalidArgument("display name must be printable")
+		}
+	}
+	return nil
+}
+
+func (p ProfileUpdate) validate() error {
+	if p.Email != nil {
+		if err := validateEmail(*p.Email); err != nil {
+			return err
+		}
+	}
+	if p.DisplayName != nil {
+		if err := validateDisplayName(*p.DisplayName); err != nil {
+			return err
+		}
+	}
+	for k, v := range p.Attributes {
+		if !validName(k, MAX_ATTRIBUTE_KEY_LENGTH) {
+			return invalidArgument("attribute keys must be 1 to %d printable characters without spaces", MAX_ATTRIBUTE_KEY_LENGTH)
+		}
+		if len(v) > MAX_ATTRIBUTE_VALUE_LENGTH || !utf8.ValidString(v) {
+			return invalidArgument("attribute values must be at most %d bytes of UTF-8", MAX_ATTRIBUTE_VALUE_LENGTH)
+		}
+	}
+	return nil
+}
+
+// GetProfile returns the user of a session, like GetUserBySessionId.
+func (a *AuthManager) GetProfile(ctx context.Context, sessionId string) (User, error) {
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	return user, err
+}
+
+// UpdateProfile changes the profile of the user of a session and returns
+// the updated user. A new email address is unverified and gets a
+// verification token.
+func (a *AuthManager) UpdateProfile(ctx context.Context, sessionId string, p ProfileUpdate) (User, error) {
+	if err := p.validate(); err != nil {
+		return User{}, err
+	}
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return User{}, err
+	}
+	attributes := maps.Clone(user.Attributes)
+	maps.Copy(attributes, p.setAttributes())
+	for _, k := range p.removedAttributes() {
+		delete(attributes, k)
+	}
+	if len(attributes) > MAX_ATTRIBUTES {
+		return User{}, invalidArgument("users may have at most %d attributes", MAX_ATTRIBUTES)
+	}
+	user, err = a.users.UpdateProfile(ctx, user.TenantId, user.Username, p, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return User{}, err
+	}
+	slog.InfoContext(ctx, "Updated profile", "tenant_id", user.TenantId, "username", user.Username)
+	if p.Email != nil {
+		if err := a.sendVerification(ctx, user); err != nil {
+			return user, err
+		}
+	}
+	if err := a.cache.Del(ctx, a.composeUserKey(user.TenantId, user.Username)); err != nil {
+		return user, err
+	}
+	return user, a.dropCachedSessions(ctx, user)
+}
@@ -0,0 +1,112 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"strings"
+	"testing"
+)
+
+// newProfileTestAuthManager returns a manager and the session of a user.
+func newProfileTestAuthManager(t *testing.T, c AuthManagerConfig) (*AuthManager, *MemoryStore, Session) {
+	t.Helper()
+	ctx := context.Background()
+	a, store := newTestAuthManager(t, c)
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	return a, store, s
+}
+
+func TestUpdateProfile(t *testing.T) {
+	ctx := context.Background()
+	a, _, s := newProfileTestAuthManager(t, AuthManagerConfig{})
+	email := "alice@example.org"
+	name := "Alice Liddell"
+	user, err := a.UpdateProfile(ctx, s.Id, ProfileUpdate{
+		Email:       &email,
+		DisplayName: &name,
+		Attributes:  map[string]string{"team": "core", "locale": "en"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateProfile: %v", err)
+	}
+	user, err = a.UpdateProfile(ctx, s.Id, ProfileUpdate{Attributes: map[string]string{"team": "", "locale": "fr"}})
+	if err != nil {
+		t.Fatalf("UpdateProfile of attributes: %v", err)
+	}
+	want := map[string]string{"locale": "fr"}
+	if user.Email != email || user.DisplayName != name || !maps.Equal(user.Attributes, want) {
+		t.Fatalf("got profile %q %q %v, want %q %q %v", user.Email, user.DisplayName, user.Attributes, email, name, want)
+	}
+	profile, err := a.GetProfile(ctx, s.Id)
+	if err != nil || profile.Email != email || !maps.Equal(profile.Attributes, want) {
+		t.Fatalf("GetProfile: got %+v, %v", profile, err)
+	}
+}
+
+func TestUpdateProfileInvalid(t *testing.T) {
+	ctx := context.Background()
+	a, _, s := newProfileTestAuthManager(t, AuthManagerConfig{})
+	tooMany := map[string]string{}
+	for i := 0; i <= MAX_ATTRIBUTES; i++ {
+		tooMany[strings.Repeat("k", i+1)] = "v"
+	}
+	ptr := func(v string) *string { return &v }
+	tests := []struct {
+		name   string
+		update ProfileUpdate
+	}{
+		{"malformed email", ProfileUpdate{Email: ptr("alice")}},
+		{"email with a name", ProfileUpdate{Email: ptr("Alice <alice@example.com>")}},
+		{"too long email", ProfileUpdate{Email: ptr(strings.Repeat("a", MAX_EMAIL_LENGTH) + "@example.com")}},
+		{"too long display name", ProfileUpdate{DisplayName: ptr(strings.Repeat("é", MAX_DISPLAY_NAME_LENGTH+1))}},
+		{"unprintable display name", ProfileUpdate{DisplayName: ptr("Alice\n")}},
+		{"empty attribute key", ProfileUpdate{Attributes: map[string]string{"": "v"}}},
+		{"attribute key with a space", ProfileUpdate{Attributes: map[string]string{"a b": "v"}}},
+		{"too long attribute value", ProfileUpdate{Attributes: map[string]string{"k": strings.Repeat("v", MAX_ATTRIBUTE_VALUE_LENGTH+1)}}},
+		{"too many attributes", ProfileUpdate{Attributes: tooMany}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := a.UpdateProfile(ctx, s.Id, tt.update); !errors.Is(err, ErrInvalidArgument) {
+				t.Fatalf("UpdateProfile: got %v, want ErrInvalidArgument", err)
+			}
+		})
+	}
+	user, err := a.GetProfile(ctx, s.Id)
+	if err != nil || user.Email != "alice@example.com" || user.DisplayName != "" || len(user.Attributes) != 0 {
+		t.Fatalf("GetProfile after rejected updates: got %+v, %v", user, err)
+	}
+	if _, err := a.UpdateProfile(ctx, "unknown", ProfileUpdate{DisplayName: ptr("Alice")}); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("UpdateProfile of an unknown session: got %v, want ErrSessionNotFound", err)
+	}
+}
+
+func TestUpdateProfileUnverifiesEmail(t *testing.T) {
+	ctx := context.Background()
+	a, store, s := newProfileTestAuthManager(t, AuthManagerConfig{})
+	if _, err := store.VerifyEmail(ctx, DEFAULT_TENANT, "alice", "alice@example.com", s.CreatedAt); err != nil {
+		t.Fatalf("VerifyEmail: %v", err)
+	}
+	same := "alice@example.com"
+	name := "Alice"
+	user, err := a.UpdateProfile(ctx, s.Id, ProfileUpdate{Email: &same, DisplayName: &name})
+	if err != nil || !user.EmailVerified() {
+		t.Fatalf("UpdateProfile keeping the address: got verified %v, %v", user.EmailVerified(), err)
+	}
+	other := "alice@example.org"
+	user, err = a.UpdateProfile(ctx, s.Id, ProfileUpdate{Email: &other})
+	if err != nil || user.EmailVerified() {
+		t.Fatalf("UpdateProfile changing the address: got verified %v, %v", user.EmailVerified(), err)
+	}
+	user, err = a.GetProfile(ctx, s.Id)
+	if err != nil || user.Email != other || user.EmailVerified() {
+		t.Fatalf("GetProfile: got %q verified %v, %v", user.Email, user.EmailVerified(), err)
+	}
+}
@@ -0,0 +1,277 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	MAX_ROLE_NAME_LENGTH        = 64
+	MAX_ROLE_DESCRIPTION_LENGTH = 256
+	MAX_PERMISSION_LENGTH       = 128
+	MAX_RESOURCE_LENGTH         = 256
+
+	// ANY matches every permission or resource. A resource ending with it
+	// matches the resources it prefixes, e.g. "projects/42/*".
+	ANY = "*"
+)
+
+// Permission allows an action, e.g. "documents.read", on a resource.
+type Permission struct {
+	Name     string `json:"name"`
+	Resource string `json:"resource"`
+}
+
+// Role is a named set of permissions granted to users.
+type Role struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Permissions []Permission `json:"permissions"`
+	CreatedAt   time.Time    `json:"created_at"`
+}
+
+// allows tells whether the permission covers an action on the resource.
+func (p Permission) allows(permission, resource string) bool {
+	if p.Name != ANY && p.Name != permission {
+		return false
+	}
+	if p.Resource == ANY || p.Resource == resource {
+		return true
+	}
+	prefix, ok := strings.CutSuffix(p.Resource, ANY)
+	return ok && strings.HasPrefix(resource, prefix)
+}
+
+// validName checks role, permission and resource names, which follow the
+// syntax of scopes with their own length limits.
+func validName(name string, maxLength int) bool {
+	if name == "" || len(name) > maxLength {
+		return false
+	}
+	for _, c := range name {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+func validateRoleName(name string) error {
+	if !validName(name, MAX_ROLE_NAME_LENGTH) {
+		return invalidArgument("role name must be 1 to %d printable characters without spaces", MAX_ROLE_NAME_LENGTH)
+	}
+	return nil
+}
+
+// normalizePermission validates the permission, the resource defaulting to ANY.
+func normalizePermission(p Permission) (Permission, error) {
+	if p.Resource == "" {
+		p.Resource = ANY
+	}
+	if !validName(p.Name, MAX_PERMISSION_LENGTH) {
+		return p, invalidArgument("permission must be 1 to %d printable characters without spaces", MAX_PERMISSION_LENGTH)
+	}
+	if !validName(p.Resource, MAX_RESOURCE_LENGTH) {
+		return p, invalidArgument("resource must be 1 to %d printable characters without spaces", MAX_RESOURCE_LENGTH)
+	}
+	return p, nil
+}
+
+func (a *AuthManager) CreateRole(ctx context.Context, name, description string) (Role, error) {
+	if err := validateRoleName(name); err != nil {
+		return Role{}, err
+	}
+	if len(description) > MAX_ROLE_DESCRIPTION_LENGTH {
+		return Role{}, invalidArgument("role description must be at most %d characters", MAX_ROLE_DESCRIPTION_LENGTH)
+	}
+	r := Role{Name: name, Description: description, Permissions: []Permission{}, CreatedAt: utils.GetNowTz()}
+	err := a.roles.CreateRole(ctx, r)
+	if errors.Is(err, ErrAlreadyExists) {
+		return Role{}, ErrRoleExists
+	}
+	if err != nil {
+		return Role{}, err
+	}
+	slog.InfoContext(ctx, "Created role", "role", name)
+	return r, nil
+}
+
+// DeleteRole revokes the role from its users and deletes it.
+func (a *AuthManager) DeleteRole(ctx context.Context, name string) error {
+	members, err := a.roles.ListRoleMembers(ctx, name)
+	if err != nil {
+		return err
+	}
+	err = a.roles.DeleteRole(ctx, name)
+	if errors.Is(err, ErrNotFound) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Deleted role", "role", name, "members", len(members))
+	return a.dropCachedSessions(ctx, members...)
+}
+
+func (a *AuthManager) ListRoles(ctx context.Context) ([]Role, error) {
+	return a.roles.ListRoles(ctx)
+}
+
+// AddRolePermission lets the users of the role act on the resource, which
+// defaults to every resource.
+func (a *AuthManager) AddRolePermission(ctx context.Context, role string, p Permission) error {
+	p, err := normalizePermission(p)
+	if err != nil {
+		return err
+	}
+	err = a.roles.AddRolePermission(ctx, role, p)
+	if errors.Is(err, ErrNotFound) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Added role permission", "role", role, "permission", p.Name, "resource", p.Resource)
+	return a.dropRoleFromCache(ctx, role)
+}
+
+func (a *AuthManager) RemoveRolePermission(ctx context.Context, role string, p Permission) error {
+	p, err := normalizePermission(p)
+	if err != nil {
+		return err
+	}
+	err = a.roles.RemoveRolePermission(ctx, role, p)
+	if errors.Is(err, ErrNotFound) {
+		return ErrPermissionNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Removed role permission", "role", role, "permission", p.Name, "resource", p.Resource)
+	return a.dropRoleFromCache(ctx, role)
+}
+
+// GrantRole grants the role to the user; granting it again does nothing.
+func (a *AuthManager) GrantRole(ctx context.Context, tenantId, username, role string) error {
+	user, err := a.users.GetUser(ctx, tenantOrDefault(tenantId), username)
+	if errors.Is(err, ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	err = a.roles.GrantRole(ctx, user.TenantId, user.Username, role, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return ErrRoleNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Granted role", "tenant_id", user.TenantId, "username", username, "role", role)
+	return a.dropCachedSessions(ctx, user)
+}
+
+func (a *AuthManager) RevokeRole(ctx context.Context, tenantId, username, role string) error {
+	user := User{TenantId: tenantOrDefault(tenantId), Username: username}
+	err := a.roles.RevokeRole(ctx, user.TenantId, user.Username, role)
+	if errors.Is(err, ErrNotFound) {
+		return ErrRoleNotGranted
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Revoked role", "tenant_id", user.TenantId, "username", username, "role", role)
+	return a.dropCachedSessions(ctx, user)
+}
+
+func (a *AuthManager) GetUserRoles(ctx context.Context, tenantId, username string) ([]Role, error) {
+	return a.roles.GetUserRoles(ctx, tenantOrDefault(tenantId), username)
+}
+
+// dropRoleFromCache makes the sessions of the members of the role reload
+// their roles after the permissions of the role changed.
+func (a *AuthManager) dropRoleFromCache(ctx context.Context, role string) error {
+	members, err := a.roles.ListRoleMembers(ctx, role)
+	if err != nil {
+		return err
+	}
+	return a.dropCachedSessions(ctx, members...)
+}
+
+// dropCachedSessions deletes the cached active sessions of the users, along
+// with the profiles and roles cached in them.
+func (a *AuthManager) dropCachedSessions(ctx context.Context, users ...User) error {
+	now := utils.GetNowTz()
+	var keys []string
+	for _, u := range users {
+		sessions, err := a.sessions.ListSessions(ctx, u.TenantId, u.Username, now)
+		if err != nil {
+			return err
+		}
+		for _, s := range sessions {
+			keys = append(keys, a.composeSessionKey(s.Id))
+		}
+	}
+	if len(keys) == 0 {
+		return nil
+	}
+	return a.cache.Del(ctx, keys...)
+}
+
+// Authorization is the answer to an authorization request.
+type Authorization struct {
+	Allowed bool
+	// Role is the role granting the permission to a user, empty for services.
+	Role string
+}
+
+// Authorize tells whether the holder of a session id or a service token may
+// act on the resource. Users get the permissions of their roles; services
+// get the permissions named by the scopes of their token, on any resource.
+// Restricted sessions are denied everything. Like Authenticate, it extends
+// the idle deadline of sessions.
+func (a *AuthManager) Authorize(ctx context.Context, token, permission, resource string) (Authorization, error) {
+	if !validName(permission, MAX_PERMISSION_LENGTH) {
+		return Authorization{}, invalidArgument("malformed permission")
+	}
+	if !validName(resource, MAX_RESOURCE_LENGTH) {
+		return Authorization{}, invalidArgument("malformed resource")
+	}
+	if strings.HasPrefix(token, SERVICE_TOKEN_PREFIX) {
+		t, err := a.getServiceToken(ctx, token)
+		if err != nil {
+			return Authorization{}, err
+		}
+		return Authorization{Allowed: slices.Contains(t.Scopes, permission)}, nil
+	}
+	entry, err := a.getSessionEntry(ctx, token)
+	if err != nil {
+		return Authorization{}, err
+	}
+	if entry.Session.Restricted {
+		return Authorization{}, nil
+	}
+	now := utils.GetNowTz()
+	if now.Sub(entry.RolesLoadedAt) >= a.rbacConfig.RoleCacheTtl {
+		entry.Roles, err = a.roles.GetUserRoles(ctx, entry.User.TenantId, entry.User.Username)
+		if err != nil {
+			return Authorization{}, err
+		}
+		entry.RolesLoadedAt = now
+		a.cacheSetSession(ctx, entry)
+	}
+	for _, r := range entry.Roles {
+		for _, p := range r.Permissions {
+			if p.allows(permission, resource) {
+				return Authorization{Allowed: true, Role: r.Name}, nil
+			}
+		}
+	}
+	return Authorization{}, nil
+}
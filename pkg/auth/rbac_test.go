@@ -0,0 +1,86 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+func TestAuthorize(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	roles := map[string][]Permission{
+		"viewer": {{Name: "documents.read"}},
+		"editor": {{Name: "documents.write", Resource: "projects/42/*"}, {Name: "documents.delete", Resource: "projects/42/draft"}},
+		"admin":  {{Name: ANY, Resource: ANY}},
+	}
+	for name, permissions := range roles {
+		if _, err := a.CreateRole(ctx, name, ""); err != nil {
+			t.Fatalf("CreateRole: %v", err)
+		}
+		for _, p := range permissions {
+			if err := a.AddRolePermission(ctx, name, p); err != nil {
+				t.Fatalf("AddRolePermission: %v", err)
+			}
+		}
+	}
+	sessions := map[string]Session{}
+	for username, granted := range map[string][]string{"alice": {"viewer", "editor"}, "bob": nil, "carol": {"admin"}} {
+		if _, err := a.CreateUser(ctx, "", username, "secret", username+"@example.com"); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		for _, role := range granted {
+			if err := a.GrantRole(ctx, "", username, role); err != nil {
+				t.Fatalf("GrantRole: %v", err)
+			}
+		}
+		s, _, err := a.LoginUser(ctx, "", username, "secret", ClientInfo{})
+		if err != nil {
+			t.Fatalf("LoginUser: %v", err)
+		}
+		sessions[username] = s
+	}
+	_, service := issueServiceToken(t, a, []string{"documents.read"})
+
+	tests := []struct {
+		name        string
+		token       string
+		permission  string
+		resource    string
+		wantAllowed bool
+		wantRole    string
+		wantErr     error
+	}{
+		{"permission on any resource", sessions["alice"].Id, "documents.read", "projects/7/notes", true, "viewer", nil},
+		{"resource under a prefix", sessions["alice"].Id, "documents.write", "projects/42/notes", true, "editor", nil},
+		{"resource outside the prefix", sessions["alice"].Id, "documents.write", "projects/420/notes", false, "", nil},
+		{"exact resource", sessions["alice"].Id, "documents.delete", "projects/42/draft", true, "editor", nil},
+		{"other resource than the exact one", sessions["alice"].Id, "documents.delete", "projects/42/notes", false, "", nil},
+		{"permission of no role", sessions["alice"].Id, "users.delete", "users/bob", false, "", nil},
+		{"user without roles", sessions["bob"].Id, "documents.read", "projects/7/notes", false, "", nil},
+		{"any permission", sessions["carol"].Id, "users.delete", "users/bob", true, "admin", nil},
+		{"scope of a service token", service.Token, "documents.read", "projects/7/notes", true, "", nil},
+		{"other than the scopes of a service token", service.Token, "documents.write", "projects/42/notes", false, "", nil},
+		{"unknown session", "unknown", "documents.read", "projects/7/notes", false, "", ErrSessionNotFound},
+		{"malformed permission", sessions["alice"].Id, "documents read", "projects/7/notes", false, "", ErrInvalidArgument},
+		{"malformed resource", sessions["alice"].Id, "documents.read", "", false, "", ErrInvalidArgument},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := a.Authorize(ctx, tt.token, tt.permission, tt.resource)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Authorize: got %v, want %v", err, tt.wantErr)
+			}
+			if want := (Authorization{Allowed: tt.wantAllowed, Role: tt.wantRole}); got != want {
+				t.Fatalf("Authorize: got %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	if err := a.RevokeRole(ctx, "", "carol", "admin"); err != nil {
+		t.Fatalf("RevokeRole: %v", err)
+	}
+	if got, err := a.Authorize(ctx, sessions["carol"].Id, "users.delete", "users/bob"); err != nil || got.Allowed {
+		t.Fatalf("Authorize after RevokeRole: got %+v, %v, want denied", got, err)
+	}
+}
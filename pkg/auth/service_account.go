@@ -0,0 +1,235 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	MAX_SCOPES       = 20
+	MAX_SCOPE_LENGTH = 64
+
+	// SERVICE_TOKEN_PREFIX tells service tokens apart from session ids.
+	SERVICE_TOKEN_PREFIX = "svc_"
+)
+
+// Kinds of principals a token may belong to.
+const (
+	PRINCIPAL_USER    = "user"
+	PRINCIPAL_SERVICE = "service"
+)
+
+// ServiceAccount is the identity of a backend service, which gets service
+// tokens with the client credentials grant. Its tokens may carry any of
+// its scopes.
+type ServiceAccount struct {
+	Id         string
+	Name       string
+	SecretHash string
+	Scopes     []string
+	CreatedAt  time.Time
+}
+
+// ServiceToken is an opaque bearer token of a service account. It lives
+// in the cache only, and is void once the account is deleted.
+type ServiceToken struct {
+	Token     string    `json:"-"`
+	ClientId  string    `json:"client_id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	IssuedAt  time.Time `json:"issued_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Principal is whoever a token belongs to: a user through one of their
+// sessions, or a service account.
+type Principal struct {
+	Kind    string
+	User    User
+	Session Session
+	Service ServiceToken
+}
+
+func (a *AuthManager) composeServiceTokenKey(token string) string {
+	return fmt.Sprintf("service_token_%s", token)
+}
+
+// validScope checks the syntax of RFC 6749 scope tokens.
+func validScope(scope string) bool {
+	if scope == "" || len(scope) > MAX_SCOPE_LENGTH {
+		return false
+	}
+	for _, c := range scope {
+		if c < 0x21 || c > 0x7e || c == '"' || c == '\\' {
+			return false
+		}
+	}
+	return true
+}
+
+// normalizeScopes sorts the scopes and drops duplicates.
+func normalizeScopes(scopes []string) []string {
+	res := append([]string{}, scopes...)
+	slices.Sort(res)
+	return slices.Compact(res)
+}
+
+// CreateServiceAccount registers a service and returns it along with its
+// secret, which is not stored and cannot be retrieved later.
+func (a *AuthManager) CreateServiceAccount(ctx context.Context, name string, scopes []string) (ServiceAccount, string, error) {
+	if name == "" || len(name) > MAX_CLIENT_NAME_LENGTH {
+		return ServiceAccount{}, "", invalidArgument("service account name must be 1 to %d characters", MAX_CLIENT_NAME_LENGTH)
+	}
+	if len(scopes) > MAX_SCOPES {
+		return ServiceAccount{}, "", invalidArgument("a service account has at most %d scopes", MAX_SCOPES)
+	}
+	for _, scope := range scopes {
+		if !validScope(scope) {
+			return ServiceAccount{}, "", invalidArgument("malformed scope %q", scope)
+		}
+	}
+	id, secret, err := newClientCredentials()
+	if err != nil {
+		return ServiceAccount{}, "", err
+	}
+	sa := ServiceAccount{
+		Id:         id,
+		Name:       name,
+		SecretHash: hashClientSecret(secret),
+		Scopes:     normalizeScopes(scopes),
+		CreatedAt:  utils.GetNowTz(),
+	}
+	if err := a.serviceAccounts.CreateServiceAccount(ctx, sa); err != nil {
+		return ServiceAccount{}, "", err
+	}
+	slog.InfoContext(ctx, "Created service account", "client_id", sa.Id, "name", sa.Name, "scopes", sa.Scopes)
+	return sa, secret, nil
+}
+
+func (a *AuthManager) ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error) {
+	return a.serviceAccounts.ListServiceAccounts(ctx)
+}
+
+// DeleteServiceAccount stops the account from getting new tokens and voids
+// the tokens it holds.
+func (a *AuthManager) DeleteServiceAccount(ctx context.Context, id string) error {
+	err := a.serviceAccounts.DeleteServiceAccount(ctx, id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrServiceAccountNotFound
+	}
+	return err
+}
+
+// IssueServiceToken implements the client credentials grant. The token
+// carries the requested scopes, or all the scopes of the account if none
+// are requested; requesting others fails with ErrInvalidScope.
+func (a *AuthManager) IssueServiceToken(ctx context.Context, clientId, secret, scope string) (ServiceToken, error) {
+	sa, err := a.serviceAccounts.GetServiceAccount(ctx, clientId)
+	if errors.Is(err, ErrNotFound) {
+		return ServiceToken{}, ErrInvalidClient
+	}
+	if err != nil {
+		return ServiceToken{}, err
+	}
+	if subtle.ConstantTimeCompare([]byte(hashClientSecret(secret)), []byte(sa.SecretHash)) != 1 {
+		return ServiceToken{}, ErrInvalidClient
+	}
+	scopes := sa.Scopes
+	if requested := strings.Fields(scope); len(requested) > 0 {
+		for _, s := range requested {
+			if !slices.Contains(sa.Scopes, s) {
+				return ServiceToken{}, fmt.Errorf("%w: %q is not granted to the client", ErrInvalidScope, s)
+			}
+		}
+		scopes = normalizeScopes(requested)
+	}
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return ServiceToken{}, err
+	}
+	now := utils.GetNowTz()
+	t := ServiceToken{
+		Token:     SERVICE_TOKEN_PREFIX + base64.RawURLEncoding.EncodeToString(b),
+		ClientId:  sa.Id,
+		Name:      sa.Name,
+		Scopes:    scopes,
+		IssuedAt:  now,
+		ExpiresAt: now.Add(a.serviceTokenConfig.Ttl),
+	}
+	data, err := json.Marshal(t)
+	if err != nil {
+		return ServiceToken{}, err
+	}
+	if err := a.cache.Set(ctx, a.composeServiceTokenKey(t.Token), data, a.serviceTokenConfig.Ttl); err != nil {
+		return ServiceToken{}, err
+	}
+	slog.InfoContext(ctx, "Issued service token", "client_id", sa.Id, "scopes", scopes)
+	return t, nil
+}
+
+func (a *AuthManager) getServiceToken(ctx context.Context, token string) (ServiceToken, error) {
+	var t ServiceToken
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_SERVICE_TOKEN, a.composeServiceTokenKey(token), &t)
+	if errors.Is(err, ErrBackendUnavailable) {
+		return ServiceToken{}, err
+	}
+	if err != nil || !t.ExpiresAt.After(utils.GetNowTz()) {
+		return ServiceToken{}, ErrSessionNotFound
+	}
+	// Tokens are not tracked by account, so those of a deleted account
+	// are told by the account being gone.
+	_, err = a.serviceAccounts.GetServiceAccount(ctx, t.ClientId)
+	if errors.Is(err, ErrNotFound) {
+		return ServiceToken{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return ServiceToken{}, err
+	}
+	t.Token = token
+	return t, nil
+}
+
+// Authenticate resolves a session id, an access token or a service token.
+// Unknown and expired tokens fail with ErrSessionNotFound, like session ids.
+func (a *AuthManager) Authenticate(ctx context.Context, token string) (Principal, error) {
+	if strings.HasPrefix(token, SERVICE_TOKEN_PREFIX) {
+		t, err := a.getServiceToken(ctx, token)
+		if err != nil {
+			return Principal{}, err
+		}
+		return Principal{Kind: PRINCIPAL_SERVICE, Service: t}, nil
+	}
+	getUser := a.GetUserBySessionId
+	if isAccessToken(token) {
+		getUser = a.getUserByAccessToken
+	}
+	user, session, err := getUser(ctx, token)
+	if err != nil {
+		return Principal{}, err
+	}
+	return Principal{Kind: PRINCIPAL_USER, User: user, Session: session}, nil
+}
+
+// RevokeToken invalidates a session id or deletes a service token or an
+// access token issued to a relying party.
+func (a *AuthManager) RevokeToken(ctx context.Context, token string) error {
+	if strings.HasPrefix(token, SERVICE_TOKEN_PREFIX) {
+		return a.cache.Del(ctx, a.composeServiceTokenKey(token))
+	}
+	if strings.HasPrefix(token, OIDC_ACCESS_TOKEN_PREFIX) {
+		return a.cache.Del(ctx, a.composeOidcAccessTokenKey(token))
+	}
+	return a.InvalidateSession(ctx, token)
+}
@@ -0,0 +1,104 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIssueServiceToken(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	sa, secret, err := a.CreateServiceAccount(ctx, "billing", []string{"users:read", "users:write"})
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	tests := []struct {
+		name       string
+		clientId   string
+		secret     string
+		scope      string
+		wantScopes []string
+		wantErr    error
+	}{
+		{"all scopes", sa.Id, secret, "", []string{"users:read", "users:write"}, nil},
+		{"subset of the scopes", sa.Id, secret, "users:read users:read", []string{"users:read"}, nil},
+		{"scope not granted", sa.Id, secret, "users:read admin", nil, ErrInvalidScope},
+		{"wrong secret", sa.Id, "wrong", "", nil, ErrInvalidClient},
+		{"unknown client", "unknown", secret, "", nil, ErrInvalidClient},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token, err := a.IssueServiceToken(ctx, tt.clientId, tt.secret, tt.scope)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("IssueServiceToken: got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if !strings.HasPrefix(token.Token, SERVICE_TOKEN_PREFIX) || !slices.Equal(token.Scopes, tt.wantScopes) {
+				t.Fatalf("got token %q with scopes %v, want an %s token with %v", token.Token, token.Scopes, SERVICE_TOKEN_PREFIX, tt.wantScopes)
+			}
+		})
+	}
+}
+
+// issueServiceToken creates a service account and returns a token of it.
+func issueServiceToken(t *testing.T, a *AuthManager, scopes []string) (ServiceAccount, ServiceToken) {
+	t.Helper()
+	ctx := context.Background()
+	sa, secret, err := a.CreateServiceAccount(ctx, "billing", scopes)
+	if err != nil {
+		t.Fatalf("CreateServiceAccount: %v", err)
+	}
+	token, err := a.IssueServiceToken(ctx, sa.Id, secret, "")
+	if err != nil {
+		t.Fatalf("IssueServiceToken: %v", err)
+	}
+	return sa, token
+}
+
+func TestAuthenticateServiceToken(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{})
+	sa, token := issueServiceToken(t, a, []string{"users:read"})
+	p, err := a.Authenticate(ctx, token.Token)
+	if err != nil || p.Kind != PRINCIPAL_SERVICE || p.Service.ClientId != sa.Id {
+		t.Fatalf("Authenticate: got %+v, %v, want the service %s", p, err, sa.Id)
+	}
+
+	_, revoked := issueServiceToken(t, a, nil)
+	if err := a.RevokeToken(ctx, revoked.Token); err != nil {
+		t.Fatalf("RevokeToken: %v", err)
+	}
+	deleted, ofDeleted := issueServiceToken(t, a, nil)
+	if err := a.DeleteServiceAccount(ctx, deleted.Id); err != nil {
+		t.Fatalf("DeleteServiceAccount: %v", err)
+	}
+	expiring, _ := newTestAuthManager(t, AuthManagerConfig{ServiceToken: ServiceTokenConfig{Ttl: time.Nanosecond}})
+	_, expired := issueServiceToken(t, expiring, nil)
+
+	tests := []struct {
+		name  string
+		a     *AuthManager
+		token string
+	}{
+		{"unknown token", a, SERVICE_TOKEN_PREFIX + "unknown"},
+		{"revoked token", a, revoked.Token},
+		{"token of a deleted account", a, ofDeleted.Token},
+		{"expired token", expiring, expired.Token},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := tt.a.Authenticate(ctx, tt.token); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("Authenticate: got %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+	if _, err := a.Authenticate(ctx, token.Token); err != nil {
+		t.Fatalf("Authenticate with the token of another account: %v", err)
+	}
+}
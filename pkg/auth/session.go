@@ -1,9 +1,83 @@
 package auth
 
-import "time"
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"strings"
+	"time"
+)
+
+const (
+	maxClientIpLength   = 64
+	maxUserAgentLength  = 512
+	maxDeviceNameLength = 64
+)
 
 type Session struct {
-	Id           string    `db:"id"`
-	ValidThrough time.Time `db:"valid_through"`
-	Username     string    `db:"username"`
+	// Id is the id of the stored session, prefixed with the tenant and
+	// a dot outside the default tenant, so that the tenant of a session
+	// is known from its id alone.
+	Id       string `db:"id" json:"id"`
+	TenantId string `db:"tenant_id" json:"tenant_id"`
+	// ValidThrough is when the session expires unless extended by activity.
+	// It never exceeds ExpiresAt, the absolute end of the session.
+	ValidThrough time.Time `db:"valid_through" json:"valid_through"`
+	ExpiresAt    time.Time `db:"expires_at" json:"expires_at"`
+	Username     string    `db:"username" json:"username"`
+	CreatedAt    time.Time `db:"created_at" json:"created_at"`
+	LastSeenAt   time.Time `db:"last_seen_at" json:"last_seen_at"`
+	// Restricted is set when the session is read if its user has not
+	// verified their email address and the policy restricts them.
+	Restricted bool `db:"-" json:"-"`
+	ClientInfo
+}
+
+// Handle identifies the session without being a credential like its id,
+// for instance when listing the sessions of a user.
+func (s Session) Handle() string {
+	return sessionHandle(s.Id)
+}
+
+func sessionHandle(sessionId string) string {
+	sum := sha256.Sum256([]byte(sessionId))
+	return base64.RawURLEncoding.EncodeToString(sum[:])
+}
+
+// joinSessionId returns the session id of a stored session of the tenant.
+func joinSessionId(tenantId, id string) string {
+	if tenantId == DEFAULT_TENANT {
+		return id
+	}
+	return tenantId + "." + id
+}
+
+// splitSessionId returns the tenant of a session id and the id of the
+// stored session.
+func splitSessionId(sessionId string) (string, string) {
+	if tenantId, id, ok := strings.Cut(sessionId, "."); ok {
+		return tenantId, id
+	}
+	return DEFAULT_TENANT, sessionId
+}
+
+// ClientInfo describes the client a session was created from.
+type ClientInfo struct {
+	Ip         string `db:"client_ip" json:"client_ip"`
+	UserAgent  string `db:"user_agent" json:"user_agent"`
+	DeviceName string `db:"device_name" json:"device_name"`
+}
+
+func (c ClientInfo) normalize() ClientInfo {
+	c.Ip = truncate(c.Ip, maxClientIpLength)
+	c.UserAgent = truncate(c.UserAgent, maxUserAgentLength)
+	c.DeviceName = truncate(c.DeviceName, maxDeviceNameLength)
+	return c
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n])
 }
@@ -0,0 +1,229 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// Errors returned by stores. Other failures of the backend itself
+// are wrapped with ErrBackendUnavailable.
+var (
+	ErrNotFound      = errors.New("not found")
+	ErrAlreadyExists = errors.New("already exists")
+	ErrCacheMiss     = errors.New("cache miss")
+)
+
+// UserStore persists users.
+type UserStore interface {
+	// CreateUser returns ErrAlreadyExists if the username is taken in the
+	// tenant and ErrNotFound if the tenant does not exist or is disabled.
+	CreateUser(ctx context.Context, u User) error
+	// CreateUserWithSession atomically stores the user and its first session,
+	// returning the session with its generated id. It fails like CreateUser.
+	CreateUserWithSession(ctx context.Context, u User, s Session) (Session, error)
+	GetUser(ctx context.Context, tenantId, username string) (User, error)
+	UpdatePassword(ctx context.Context, tenantId, username, password string) error
+	// ReplacePassword updates the password only if the stored hash is still current.
+	ReplacePassword(ctx context.Context, tenantId, username, current, password string) error
+	// ListUsers returns at most limit users of the tenant whose username
+	// starts with prefix and sorts after the given one, in username order.
+	ListUsers(ctx context.Context, tenantId, prefix, after string, limit int) ([]User, error)
+	// DisableUser marks the user disabled unless they already are, expires
+	// their sessions and deletes their password reset tokens. It returns the
+	// ids of expired sessions; CreateSession no longer accepts the user.
+	DisableUser(ctx context.Context, tenantId, username string, now time.Time) ([]string, error)
+	EnableUser(ctx context.Context, tenantId, username string) error
+	// VerifyEmail marks email verified at now, unless it was already, and
+	// returns the user. Returns ErrNotFound if it is not the user's address.
+	VerifyEmail(ctx context.Context, tenantId, username, email string, now time.Time) (User, error)
+	// UpdateProfile applies the update at now and returns the updated user.
+	// Changing the email address makes it unverified.
+	UpdateProfile(ctx context.Context, tenantId, username string, p ProfileUpdate, now time.Time) (User, error)
+	// DeleteUser deletes the user and everything stored about them, and
+	// returns the ids of their sessions, expired or not.
+	DeleteUser(ctx context.Context, tenantId, username string) ([]string, error)
+	// Ping checks the store can be reached.
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// SessionStore persists sessions.
+type SessionStore interface {
+	// CreateSession stores the session and returns it with its generated id.
+	// Returns ErrNotFound if the user does not exist or either the user or
+	// the tenant is disabled.
+	CreateSession(ctx context.Context, s Session) (Session, error)
+	// GetSession returns a session, expired or not, together with its user.
+	GetSession(ctx context.Context, id string) (Session, User, error)
+	// ListSessions returns the sessions of the user valid at now, newest first.
+	ListSessions(ctx context.Context, tenantId, username string, now time.Time) ([]Session, error)
+	// CountActiveSessions returns the number of sessions valid at now.
+	CountActiveSessions(ctx context.Context, now time.Time) (int64, error)
+	// TouchSession records client activity on a session still valid at lastSeenAt
+	// and moves its expiration to validThrough. Returns ErrNotFound otherwise.
+	TouchSession(ctx context.Context, id string, lastSeenAt, validThrough time.Time) error
+	// InvalidateSession expires the session at now, unless it expired earlier.
+	InvalidateSession(ctx context.Context, id string, now time.Time) error
+	// InvalidateUserSessions expires every session of the user except the one
+	// with exceptId (if not empty) and returns the ids of expired sessions.
+	InvalidateUserSessions(ctx context.Context, tenantId, username, exceptId string, now time.Time) ([]string, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// MfaStore persists second factors and recovery codes.
+type MfaStore interface {
+	// GetMfa returns ErrNotFound if the user never started an enrollment.
+	GetMfa(ctx context.Context, tenantId, username string) (Mfa, error)
+	// SetMfa stores a pending enrollment, replacing any previous one
+	// that was not confirmed. Returns ErrAlreadyExists if MFA is enabled.
+	SetMfa(ctx context.Context, m Mfa) error
+	// EnableMfa confirms the pending enrollment, consuming the code of step,
+	// and stores the recovery code hashes. Returns ErrNotFound if there
+	// is no pending enrollment.
+	EnableMfa(ctx context.Context, tenantId, username string, step int64, codeHashes []string, now time.Time) error
+	// DeleteMfa removes the second factor and its recovery codes.
+	DeleteMfa(ctx context.Context, tenantId, username string) error
+	// UseMfaStep consumes the code of step, returning ErrNotFound if a code
+	// of that step or a later one was used already.
+	UseMfaStep(ctx context.Context, tenantId, username string, step int64) error
+	// ReplaceRecoveryCodes discards the recovery codes of the user
+	// and stores the given hashes instead.
+	ReplaceRecoveryCodes(ctx context.Context, tenantId, username string, codeHashes []string) error
+	// UseRecoveryCode consumes the recovery code with the given hash,
+	// returning ErrNotFound if it is unknown or used already.
+	UseRecoveryCode(ctx context.Context, tenantId, username, codeHash string, now time.Time) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// PasswordResetStore persists password reset tokens by their hash.
+type PasswordResetStore interface {
+	CreatePasswordResetToken(ctx context.Context, t PasswordResetToken) error
+	// ResetPassword atomically consumes the token, if unused and valid at now,
+	// sets the password of its user and discards the other tokens of the user.
+	// Returns the user, or ErrNotFound if the token cannot be used.
+	ResetPassword(ctx context.Context, tokenHash, password string, now time.Time) (User, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// SigningKeyStore persists signing keys. Keys are never deleted, only revoked.
+type SigningKeyStore interface {
+	ListSigningKeys(ctx context.Context) ([]SigningKeyRecord, error)
+	// CreateSigningKey stores the key provided the latest key to activate
+	// that is not revoked is still previousKid, empty if there is none.
+	// Returns ErrAlreadyExists otherwise, as another key was added meanwhile.
+	CreateSigningKey(ctx context.Context, k SigningKeyRecord, previousKid string) error
+	// RevokeSigningKey returns ErrNotFound if there is no such key.
+	RevokeSigningKey(ctx context.Context, kid string, now time.Time) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// ClientStore persists the OAuth clients registered with SAM.
+type ClientStore interface {
+	// CreateClient returns ErrAlreadyExists if the id is taken.
+	CreateClient(ctx context.Context, c OAuthClient) error
+	// GetClient returns ErrNotFound if there is no such client.
+	GetClient(ctx context.Context, id string) (OAuthClient, error)
+	// ListClients returns every client, oldest first.
+	ListClients(ctx context.Context) ([]OAuthClient, error)
+	// DeleteClient returns ErrNotFound if there is no such client.
+	DeleteClient(ctx context.Context, id string) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// ServiceAccountStore persists the service accounts of backend services.
+type ServiceAccountStore interface {
+	// CreateServiceAccount returns ErrAlreadyExists if the id is taken.
+	CreateServiceAccount(ctx context.Context, s ServiceAccount) error
+	// GetServiceAccount returns ErrNotFound if there is no such account.
+	GetServiceAccount(ctx context.Context, id string) (ServiceAccount, error)
+	// ListServiceAccounts returns every account, oldest first.
+	ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error)
+	// DeleteServiceAccount returns ErrNotFound if there is no such account.
+	DeleteServiceAccount(ctx context.Context, id string) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// RoleStore persists roles, their permissions and the roles granted to users.
+type RoleStore interface {
+	// CreateRole returns ErrAlreadyExists if the name is taken.
+	CreateRole(ctx context.Context, r Role) error
+	// DeleteRole revokes the role from its users. Returns ErrNotFound if
+	// there is no such role.
+	DeleteRole(ctx context.Context, name string) error
+	// ListRoles returns every role with its permissions, by name.
+	ListRoles(ctx context.Context) ([]Role, error)
+	// AddRolePermission does nothing if the role has the permission already.
+	// Returns ErrNotFound if there is no such role.
+	AddRolePermission(ctx context.Context, role string, p Permission) error
+	// RemoveRolePermission returns ErrNotFound if the role lacks the permission.
+	RemoveRolePermission(ctx context.Context, role string, p Permission) error
+	// GrantRole does nothing if the user has the role already. Returns
+	// ErrNotFound if there is no such user or role.
+	GrantRole(ctx context.Context, tenantId, username, role string, now time.Time) error
+	// RevokeRole returns ErrNotFound if the user does not have the role.
+	RevokeRole(ctx context.Context, tenantId, username, role string) error
+	// GetUserRoles returns the roles of the user with their permissions, by name.
+	GetUserRoles(ctx context.Context, tenantId, username string) ([]Role, error)
+	// ListRoleMembers returns the users the role is granted to, without
+	// their passwords.
+	ListRoleMembers(ctx context.Context, role string) ([]User, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// TenantStore persists tenants. Tenants are never deleted, only disabled.
+type TenantStore interface {
+	// CreateTenant returns ErrAlreadyExists if the id is taken.
+	CreateTenant(ctx context.Context, t Tenant) error
+	// GetTenant returns ErrNotFound if there is no such tenant.
+	GetTenant(ctx context.Context, id string) (Tenant, error)
+	// ListTenants returns every tenant, oldest first.
+	ListTenants(ctx context.Context) ([]Tenant, error)
+	// DisableTenant atomically disables the tenant, unless disabled already,
+	// and expires its sessions valid at now, returning their ids. Sessions
+	// cannot be created in the tenant afterwards. Returns ErrNotFound if
+	// there is no such tenant.
+	DisableTenant(ctx context.Context, id string, now time.Time) ([]string, error)
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// WebauthnStore persists the WebAuthn credentials of users. Credential
+// ids are unique per tenant.
+type WebauthnStore interface {
+	// CreateWebauthnCredential returns ErrAlreadyExists if the id is taken
+	// and ErrNotFound if the user does not exist.
+	CreateWebauthnCredential(ctx context.Context, c WebauthnCredential) error
+	ListWebauthnCredentials(ctx context.Context, tenantId, username string) ([]WebauthnCredential, error)
+	GetWebauthnCredential(ctx context.Context, tenantId string, id []byte) (WebauthnCredential, error)
+	// UseWebauthnCredential records a use at now with the sign counter of
+	// the authenticator. Returns ErrNotFound if the credential is gone or
+	// the counter did not grow, unless the authenticator keeps none and
+	// both are zero.
+	UseWebauthnCredential(ctx context.Context, tenantId string, id []byte, signCount uint32, now time.Time) error
+	// DeleteWebauthnCredential returns ErrNotFound unless the user has it.
+	DeleteWebauthnCredential(ctx context.Context, tenantId, username string, id []byte) error
+	Ping(ctx context.Context) error
+	Close()
+}
+
+// SessionCache is a key-value cache with per-key expiration.
+type SessionCache interface {
+	// Get returns ErrCacheMiss if the key is absent or expired.
+	Get(ctx context.Context, key string) ([]byte, error)
+	// Set stores the value; ttl of 0 means no expiration.
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	// Take gets and deletes the value atomically, so that only one caller
+	// gets it. Returns ErrCacheMiss like Get.
+	Take(ctx context.Context, key string) ([]byte, error)
+	Del(ctx context.Context, keys ...string) error
+	Ping(ctx context.Context) error
+	Close()
+}
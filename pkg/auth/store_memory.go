@@ -0,0 +1,309 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// MemoryStore implements every store in process memory.
+// It is meant for unit tests and local development.
+type MemoryStore struct {
+	mu              sync.RWMutex
+	tenants         map[string]Tenant
+	users           map[string]User // by userKey, like the maps of other user data
+	sessions        map[string]Session
+	mfa             map[string]Mfa
+	recoveryCodes   map[string]map[string]bool // user key -> code hash -> used
+	resetTokens     map[string]PasswordResetToken
+	signingKeys     map[string]SigningKeyRecord
+	clients         map[string]OAuthClient
+	serviceAccounts map[string]ServiceAccount
+	roles           map[string]Role
+	userRoles       map[string]map[string]time.Time // user key -> role -> granted at
+	webauthn        map[string]WebauthnCredential   // by webauthnKey
+}
+
+// NewMemoryStore returns an empty store with the default tenant.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		tenants: map[string]Tenant{
+			DEFAULT_TENANT: {Id: DEFAULT_TENANT, Name: "Default", CreatedAt: utils.GetNowTz()},
+		},
+		users:           make(map[string]User),
+		sessions:        make(map[string]Session),
+		mfa:             make(map[string]Mfa),
+		recoveryCodes:   make(map[string]map[string]bool),
+		resetTokens:     make(map[string]PasswordResetToken),
+		signingKeys:     make(map[string]SigningKeyRecord),
+		clients:         make(map[string]OAuthClient),
+		serviceAccounts: make(map[string]ServiceAccount),
+		roles:           make(map[string]Role),
+		userRoles:       make(map[string]map[string]time.Time),
+		webauthn:        make(map[string]WebauthnCredential),
+	}
+}
+
+func (m *MemoryStore) Ping(ctx context.Context) error { return nil }
+
+func (m *MemoryStore) Close() {}
+
+// enabledTenant tells whether users and sessions can be added to the tenant.
+func (m *MemoryStore) enabledTenant(id string) bool {
+	t, ok := m.tenants[id]
+	return ok && !t.Disabled()
+}
+
+func (m *MemoryStore) CreateUser(ctx context.Context, u User) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.enabledTenant(u.TenantId) {
+		return ErrNotFound
+	}
+	key := userKey(u.TenantId, u.Username)
+	if _, ok := m.users[key]; ok {
+		return ErrAlreadyExists
+	}
+	m.users[key] = cloneUser(u)
+	return nil
+}
+
+func (m *MemoryStore) CreateUserWithSession(ctx context.Context, u User, s Session) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if !m.enabledTenant(u.TenantId) {
+		return Session{}, ErrNotFound
+	}
+	key := userKey(u.TenantId, u.Username)
+	if _, ok := m.users[key]; ok {
+		return Session{}, ErrAlreadyExists
+	}
+	id, err := newUUID()
+	if err != nil {
+		return Session{}, err
+	}
+	s.Id = joinSessionId(s.TenantId, id)
+	m.users[key] = cloneUser(u)
+	m.sessions[s.Id] = s
+	return s, nil
+}
+
+func (m *MemoryStore) GetUser(ctx context.Context, tenantId, username string) (User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	u, ok := m.users[userKey(tenantId, username)]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	return cloneUser(u), nil
+}
+
+func (m *MemoryStore) UpdatePassword(ctx context.Context, tenantId, username, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok {
+		return nil
+	}
+	u.Password = password
+	m.users[key] = u
+	return nil
+}
+
+func (m *MemoryStore) ReplacePassword(ctx context.Context, tenantId, username, current, password string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok || u.Password != current {
+		return nil
+	}
+	u.Password = password
+	m.users[key] = u
+	return nil
+}
+
+func (m *MemoryStore) CreateSession(ctx context.Context, s Session) (Session, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if u, ok := m.users[userKey(s.TenantId, s.Username)]; !ok || u.Disabled() || !m.enabledTenant(s.TenantId) {
+		return Session{}, ErrNotFound
+	}
+	id, err := newUUID()
+	if err != nil {
+		return Session{}, err
+	}
+	s.Id = joinSessionId(s.TenantId, id)
+	m.sessions[s.Id] = s
+	return s, nil
+}
+
+func (m *MemoryStore) GetSession(ctx context.Context, id string) (Session, User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return Session{}, User{}, ErrNotFound
+	}
+	u, ok := m.users[userKey(s.TenantId, s.Username)]
+	if !ok {
+		return Session{}, User{}, ErrNotFound
+	}
+	return s, cloneUser(u), nil
+}
+
+func (m *MemoryStore) ListSessions(ctx context.Context, tenantId, username string, now time.Time) ([]Session, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var sessions []Session
+	for _, s := range m.sessions {
+		if s.TenantId == tenantId && s.Username == username && s.ValidThrough.After(now) {
+			sessions = append(sessions, s)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].CreatedAt.After(sessions[j].CreatedAt)
+	})
+	return sessions, nil
+}
+
+func (m *MemoryStore) CountActiveSessions(ctx context.Context, now time.Time) (int64, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var n int64
+	for _, s := range m.sessions {
+		if s.ValidThrough.After(now) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (m *MemoryStore) TouchSession(ctx context.Context, id string, lastSeenAt, validThrough time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok || !s.ValidThrough.After(lastSeenAt) {
+		return ErrNotFound
+	}
+	if validThrough.After(s.ExpiresAt) {
+		validThrough = s.ExpiresAt
+	}
+	s.LastSeenAt = lastSeenAt
+	s.ValidThrough = validThrough
+	m.sessions[id] = s
+	return nil
+}
+
+func (m *MemoryStore) InvalidateSession(ctx context.Context, id string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	s, ok := m.sessions[id]
+	if !ok {
+		return ErrNotFound
+	}
+	if s.ValidThrough.After(now) {
+		s.ValidThrough = now
+		m.sessions[id] = s
+	}
+	return nil
+}
+
+func (m *MemoryStore) InvalidateUserSessions(ctx context.Context, tenantId, username, exceptId string, now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var ids []string
+	for id, s := range m.sessions {
+		if s.TenantId != tenantId || s.Username != username || id == exceptId || !s.ValidThrough.After(now) {
+			continue
+		}
+		s.ValidThrough = now
+		m.sessions[id] = s
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// newUUID returns a random version 4 UUID.
+func newUUID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = (b[6] & 0x0f) | 0x40
+	b[8] = (b[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
+
+type memoryCacheItem struct {
+	value     []byte
+	expiresAt time.Time // zero means no expiration
+}
+
+// MemoryCache implements SessionCache in process memory.
+type MemoryCache struct {
+	mu    sync.Mutex
+	items map[string]memoryCacheItem
+}
+
+func NewMemoryCache() *MemoryCache {
+	return &MemoryCache{items: make(map[string]memoryCacheItem)}
+}
+
+func (m *MemoryCache) Ping(ctx context.Context) error { return nil }
+
+func (m *MemoryCache) Close() {}
+
+func (m *MemoryCache) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	if !item.expiresAt.IsZero() && !item.expiresAt.After(utils.GetNowTz()) {
+		delete(m.items, key)
+		return nil, ErrCacheMiss
+	}
+	return item.value, nil
+}
+
+func (m *MemoryCache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item := memoryCacheItem{value: value}
+	if ttl > 0 {
+		item.expiresAt = utils.GetNowTz().Add(ttl)
+	}
+	m.items[key] = item
+	return nil
+}
+
+func (m *MemoryCache) Take(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	item, ok := m.items[key]
+	if !ok {
+		return nil, ErrCacheMiss
+	}
+	delete(m.items, key)
+	if !item.expiresAt.IsZero() && !item.expiresAt.After(utils.GetNowTz()) {
+		return nil, ErrCacheMiss
+	}
+	return item.value, nil
+}
+
+func (m *MemoryCache) Del(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, key := range keys {
+		delete(m.items, key)
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+func (m *MemoryStore) CreateClient(ctx context.Context, c OAuthClient) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[c.Id]; ok {
+		return ErrAlreadyExists
+	}
+	c.RedirectUris = slices.Clone(c.RedirectUris)
+	m.clients[c.Id] = c
+	return nil
+}
+
+func (m *MemoryStore) GetClient(ctx context.Context, id string) (OAuthClient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.clients[id]
+	if !ok {
+		return OAuthClient{}, ErrNotFound
+	}
+	c.RedirectUris = slices.Clone(c.RedirectUris)
+	return c, nil
+}
+
+func (m *MemoryStore) ListClients(ctx context.Context) ([]OAuthClient, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	clients := make([]OAuthClient, 0, len(m.clients))
+	for _, c := range m.clients {
+		c.RedirectUris = slices.Clone(c.RedirectUris)
+		clients = append(clients, c)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		return clients[i].CreatedAt.Before(clients[j].CreatedAt)
+	})
+	return clients, nil
+}
+
+func (m *MemoryStore) DeleteClient(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.clients[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.clients, id)
+	return nil
+}
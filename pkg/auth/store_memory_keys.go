@@ -0,0 +1,64 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) ListSigningKeys(ctx context.Context) ([]SigningKeyRecord, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	keys := make([]SigningKeyRecord, 0, len(m.signingKeys))
+	for _, k := range m.signingKeys {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].ActivatesAt.Equal(keys[j].ActivatesAt) {
+			return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+		}
+		return keys[i].ActivatesAt.Before(keys[j].ActivatesAt)
+	})
+	return keys, nil
+}
+
+func (m *MemoryStore) CreateSigningKey(ctx context.Context, k SigningKeyRecord, previousKid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var latest *SigningKeyRecord
+	for _, other := range m.signingKeys {
+		if other.RevokedAt != nil {
+			continue
+		}
+		if latest == nil || other.ActivatesAt.After(latest.ActivatesAt) ||
+			other.ActivatesAt.Equal(latest.ActivatesAt) && other.CreatedAt.After(latest.CreatedAt) {
+			latest = &other
+		}
+	}
+	latestKid := ""
+	if latest != nil {
+		latestKid = latest.Kid
+	}
+	if latestKid != previousKid {
+		return ErrAlreadyExists
+	}
+	if _, ok := m.signingKeys[k.Kid]; ok {
+		return ErrAlreadyExists
+	}
+	m.signingKeys[k.Kid] = k
+	return nil
+}
+
+func (m *MemoryStore) RevokeSigningKey(ctx context.Context, kid string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	k, ok := m.signingKeys[kid]
+	if !ok {
+		return ErrNotFound
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = &now
+		m.signingKeys[kid] = k
+	}
+	return nil
+}
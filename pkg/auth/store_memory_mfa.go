@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+func (m *MemoryStore) GetMfa(ctx context.Context, tenantId, username string) (Mfa, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	mfa, ok := m.mfa[userKey(tenantId, username)]
+	if !ok {
+		return Mfa{}, ErrNotFound
+	}
+	return mfa, nil
+}
+
+func (m *MemoryStore) SetMfa(ctx context.Context, mfa Mfa) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(mfa.TenantId, mfa.Username)
+	if _, ok := m.users[key]; !ok {
+		return ErrNotFound
+	}
+	if current, ok := m.mfa[key]; ok && current.Enabled() {
+		return ErrAlreadyExists
+	}
+	mfa.LastUsedStep = 0
+	mfa.EnabledAt = nil
+	m.mfa[key] = mfa
+	return nil
+}
+
+func (m *MemoryStore) setRecoveryCodes(key string, codeHashes []string) {
+	codes := make(map[string]bool, len(codeHashes))
+	for _, hash := range codeHashes {
+		codes[hash] = false
+	}
+	m.recoveryCodes[key] = codes
+}
+
+func (m *MemoryStore) EnableMfa(ctx context.Context, tenantId, username string, step int64, codeHashes []string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	mfa, ok := m.mfa[key]
+	if !ok || mfa.Enabled() || mfa.LastUsedStep >= step {
+		return ErrNotFound
+	}
+	mfa.LastUsedStep = step
+	mfa.EnabledAt = &now
+	m.mfa[key] = mfa
+	m.setRecoveryCodes(key, codeHashes)
+	return nil
+}
+
+func (m *MemoryStore) DeleteMfa(ctx context.Context, tenantId, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	delete(m.mfa, key)
+	delete(m.recoveryCodes, key)
+	return nil
+}
+
+func (m *MemoryStore) UseMfaStep(ctx context.Context, tenantId, username string, step int64) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	mfa, ok := m.mfa[key]
+	if !ok || mfa.LastUsedStep >= step {
+		return ErrNotFound
+	}
+	mfa.LastUsedStep = step
+	m.mfa[key] = mfa
+	return nil
+}
+
+func (m *MemoryStore) ReplaceRecoveryCodes(ctx context.Context, tenantId, username string, codeHashes []string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	if _, ok := m.mfa[key]; !ok {
+		return ErrNotFound
+	}
+	m.setRecoveryCodes(key, codeHashes)
+	return nil
+}
+
+func (m *MemoryStore) UseRecoveryCode(ctx context.Context, tenantId, username, codeHash string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	used, ok := m.recoveryCodes[key][codeHash]
+	if !ok || used {
+		return ErrNotFound
+	}
+	m.recoveryCodes[key][codeHash] = true
+	return nil
+}
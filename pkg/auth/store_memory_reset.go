@@ -0,0 +1,41 @@
+package auth
+
+import (
+	"context"
+	"time"
+)
+
+func (m *MemoryStore) CreatePasswordResetToken(ctx context.Context, t PasswordResetToken) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userKey(t.TenantId, t.Username)]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.resetTokens[t.Hash]; ok {
+		return ErrAlreadyExists
+	}
+	m.resetTokens[t.Hash] = t
+	return nil
+}
+
+func (m *MemoryStore) ResetPassword(ctx context.Context, tokenHash, password string, now time.Time) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.resetTokens[tokenHash]
+	if !ok || !t.ExpiresAt.After(now) {
+		return User{}, ErrNotFound
+	}
+	key := userKey(t.TenantId, t.Username)
+	u, ok := m.users[key]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	u.Password = password
+	m.users[key] = u
+	for hash, other := range m.resetTokens {
+		if other.TenantId == u.TenantId && other.Username == u.Username {
+			delete(m.resetTokens, hash)
+		}
+	}
+	return cloneUser(u), nil
+}
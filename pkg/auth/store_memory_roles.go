@@ -0,0 +1,140 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sort"
+	"time"
+)
+
+// cloneRole copies the permissions so that callers cannot change the store.
+func cloneRole(r Role) Role {
+	r.Permissions = slices.Clone(r.Permissions)
+	return r
+}
+
+func sortRoles(roles []Role) {
+	sort.Slice(roles, func(i, j int) bool {
+		return roles[i].Name < roles[j].Name
+	})
+}
+
+func (m *MemoryStore) CreateRole(ctx context.Context, r Role) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.roles[r.Name]; ok {
+		return ErrAlreadyExists
+	}
+	m.roles[r.Name] = cloneRole(r)
+	return nil
+}
+
+func (m *MemoryStore) DeleteRole(ctx context.Context, name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.roles[name]; !ok {
+		return ErrNotFound
+	}
+	delete(m.roles, name)
+	for _, roles := range m.userRoles {
+		delete(roles, name)
+	}
+	return nil
+}
+
+func (m *MemoryStore) ListRoles(ctx context.Context) ([]Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	roles := make([]Role, 0, len(m.roles))
+	for _, r := range m.roles {
+		roles = append(roles, cloneRole(r))
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+func (m *MemoryStore) AddRolePermission(ctx context.Context, role string, p Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.roles[role]
+	if !ok {
+		return ErrNotFound
+	}
+	if !slices.Contains(r.Permissions, p) {
+		r.Permissions = append(slices.Clone(r.Permissions), p)
+		m.roles[role] = r
+	}
+	return nil
+}
+
+func (m *MemoryStore) RemoveRolePermission(ctx context.Context, role string, p Permission) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r, ok := m.roles[role]
+	if !ok {
+		return ErrNotFound
+	}
+	i := slices.Index(r.Permissions, p)
+	if i < 0 {
+		return ErrNotFound
+	}
+	r.Permissions = slices.Delete(slices.Clone(r.Permissions), i, i+1)
+	m.roles[role] = r
+	return nil
+}
+
+func (m *MemoryStore) GrantRole(ctx context.Context, tenantId, username, role string, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	if _, ok := m.users[key]; !ok {
+		return ErrNotFound
+	}
+	if _, ok := m.roles[role]; !ok {
+		return ErrNotFound
+	}
+	roles, ok := m.userRoles[key]
+	if !ok {
+		roles = make(map[string]time.Time)
+		m.userRoles[key] = roles
+	}
+	if _, ok := roles[role]; !ok {
+		roles[role] = now
+	}
+	return nil
+}
+
+func (m *MemoryStore) RevokeRole(ctx context.Context, tenantId, username, role string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	if _, ok := m.userRoles[key][role]; !ok {
+		return ErrNotFound
+	}
+	delete(m.userRoles[key], role)
+	return nil
+}
+
+func (m *MemoryStore) GetUserRoles(ctx context.Context, tenantId, username string) ([]Role, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	key := userKey(tenantId, username)
+	roles := make([]Role, 0, len(m.userRoles[key]))
+	for name := range m.userRoles[key] {
+		roles = append(roles, cloneRole(m.roles[name]))
+	}
+	sortRoles(roles)
+	return roles, nil
+}
+
+func (m *MemoryStore) ListRoleMembers(ctx context.Context, role string) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var members []User
+	for key, u := range m.users {
+		if _, ok := m.userRoles[key][role]; ok {
+			members = append(members, User{TenantId: u.TenantId, Username: u.Username})
+		}
+	}
+	return members, nil
+}
@@ -0,0 +1,53 @@
+package auth
+
+import (
+	"context"
+	"slices"
+	"sort"
+)
+
+func (m *MemoryStore) CreateServiceAccount(ctx context.Context, s ServiceAccount) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.serviceAccounts[s.Id]; ok {
+		return ErrAlreadyExists
+	}
+	s.Scopes = slices.Clone(s.Scopes)
+	m.serviceAccounts[s.Id] = s
+	return nil
+}
+
+func (m *MemoryStore) GetServiceAccount(ctx context.Context, id string) (ServiceAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	s, ok := m.serviceAccounts[id]
+	if !ok {
+		return ServiceAccount{}, ErrNotFound
+	}
+	s.Scopes = slices.Clone(s.Scopes)
+	return s, nil
+}
+
+func (m *MemoryStore) ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	accounts := make([]ServiceAccount, 0, len(m.serviceAccounts))
+	for _, s := range m.serviceAccounts {
+		s.Scopes = slices.Clone(s.Scopes)
+		accounts = append(accounts, s)
+	}
+	sort.Slice(accounts, func(i, j int) bool {
+		return accounts[i].CreatedAt.Before(accounts[j].CreatedAt)
+	})
+	return accounts, nil
+}
+
+func (m *MemoryStore) DeleteServiceAccount(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.serviceAccounts[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.serviceAccounts, id)
+	return nil
+}
@@ -0,0 +1,63 @@
+package auth
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (m *MemoryStore) CreateTenant(ctx context.Context, t Tenant) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tenants[t.Id]; ok {
+		return ErrAlreadyExists
+	}
+	m.tenants[t.Id] = t
+	return nil
+}
+
+func (m *MemoryStore) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	t, ok := m.tenants[id]
+	if !ok {
+		return Tenant{}, ErrNotFound
+	}
+	return t, nil
+}
+
+func (m *MemoryStore) ListTenants(ctx context.Context) ([]Tenant, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	tenants := make([]Tenant, 0, len(m.tenants))
+	for _, t := range m.tenants {
+		tenants = append(tenants, t)
+	}
+	sort.Slice(tenants, func(i, j int) bool {
+		return tenants[i].CreatedAt.Before(tenants[j].CreatedAt)
+	})
+	return tenants, nil
+}
+
+func (m *MemoryStore) DisableTenant(ctx context.Context, id string, now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	t, ok := m.tenants[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !t.Disabled() {
+		t.DisabledAt = &now
+		m.tenants[id] = t
+	}
+	var ids []string
+	for sessionId, s := range m.sessions {
+		if s.TenantId != id || !s.ValidThrough.After(now) {
+			continue
+		}
+		s.ValidThrough = now
+		m.sessions[sessionId] = s
+		ids = append(ids, sessionId)
+	}
+	return ids, nil
+}
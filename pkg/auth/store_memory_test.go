@@ -0,0 +1,174 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+func TestMemoryStoreUsers(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	if err := m.CreateUser(ctx, newUser(DEFAULT_TENANT, "alice", "hash", "")); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	tests := []struct {
+		name     string
+		tenantId string
+		username string
+		want     error
+	}{
+		{"taken username", DEFAULT_TENANT, "alice", ErrAlreadyExists},
+		{"unknown tenant", "acme", "bob", ErrNotFound},
+		{"new user", DEFAULT_TENANT, "bob", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.CreateUser(ctx, newUser(tt.tenantId, tt.username, "hash", ""))
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("CreateUser: got %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := m.GetUser(ctx, DEFAULT_TENANT, "carol"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetUser of unknown user: got %v, want ErrNotFound", err)
+	}
+	if err := m.ReplacePassword(ctx, DEFAULT_TENANT, "alice", "stale", "new"); err != nil {
+		t.Fatalf("ReplacePassword: %v", err)
+	}
+	if u, _ := m.GetUser(ctx, DEFAULT_TENANT, "alice"); u.Password != "hash" {
+		t.Fatalf("ReplacePassword replaced a hash that was not current")
+	}
+	if err := m.ReplacePassword(ctx, DEFAULT_TENANT, "alice", "hash", "new"); err != nil {
+		t.Fatalf("ReplacePassword: %v", err)
+	}
+	if u, _ := m.GetUser(ctx, DEFAULT_TENANT, "alice"); u.Password != "new" {
+		t.Fatalf("ReplacePassword: got password %q, want %q", u.Password, "new")
+	}
+}
+
+func TestMemoryStoreSessions(t *testing.T) {
+	ctx := context.Background()
+	m := NewMemoryStore()
+	now := utils.GetNowTz()
+	u := newUser(DEFAULT_TENANT, "alice", "hash", "")
+	first, err := m.CreateUserWithSession(ctx, u, Session{
+		TenantId: u.TenantId, Username: u.Username, CreatedAt: now.Add(-time.Minute),
+		ValidThrough: now.Add(time.Hour), ExpiresAt: now.Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateUserWithSession: %v", err)
+	}
+	if _, err := m.CreateUserWithSession(ctx, u, Session{TenantId: u.TenantId, Username: u.Username}); !errors.Is(err, ErrAlreadyExists) {
+		t.Fatalf("CreateUserWithSession of a taken username: got %v, want ErrAlreadyExists", err)
+	}
+	if _, err := m.CreateSession(ctx, Session{TenantId: DEFAULT_TENANT, Username: "bob"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("CreateSession of unknown user: got %v, want ErrNotFound", err)
+	}
+	second, err := m.CreateSession(ctx, Session{
+		TenantId: u.TenantId, Username: u.Username, CreatedAt: now,
+		ValidThrough: now.Add(time.Hour), ExpiresAt: now.Add(2 * time.Hour),
+	})
+	if err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	if first.Id == "" || first.Id == second.Id {
+		t.Fatalf("sessions got ids %q and %q", first.Id, second.Id)
+	}
+	sessions, err := m.ListSessions(ctx, u.TenantId, u.Username, now)
+	if err != nil {
+		t.Fatalf("ListSessions: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].Id != second.Id {
+		t.Fatalf("ListSessions: got %v, want newest first", sessions)
+	}
+
+	touchTests := []struct {
+		name         string
+		lastSeenAt   time.Time
+		validThrough time.Time
+		want         time.Time
+		wantErr      error
+	}{
+		{"extend", now, now.Add(90 * time.Minute), now.Add(90 * time.Minute), nil},
+		{"capped by absolute timeout", now, now.Add(3 * time.Hour), second.ExpiresAt, nil},
+		{"expired", now.Add(3 * time.Hour), now.Add(4 * time.Hour), time.Time{}, ErrNotFound},
+	}
+	for _, tt := range touchTests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := m.TouchSession(ctx, second.Id, tt.lastSeenAt, tt.validThrough)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("TouchSession: got %v, want %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			s, _, _ := m.GetSession(ctx, second.Id)
+			if !s.ValidThrough.Equal(tt.want) {
+				t.Fatalf("TouchSession: valid through %v, want %v", s.ValidThrough, tt.want)
+			}
+		})
+	}
+
+	ids, err := m.InvalidateUserSessions(ctx, u.TenantId, u.Username, second.Id, now)
+	if err != nil {
+		t.Fatalf("InvalidateUserSessions: %v", err)
+	}
+	if len(ids) != 1 || ids[0] != first.Id {
+		t.Fatalf("InvalidateUserSessions: got %v, want [%s]", ids, first.Id)
+	}
+	if n, _ := m.CountActiveSessions(ctx, now); n != 1 {
+		t.Fatalf("CountActiveSessions: got %d, want 1", n)
+	}
+	if _, _, err := m.GetSession(ctx, "unknown"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("GetSession of unknown session: got %v, want ErrNotFound", err)
+	}
+	if err := m.InvalidateSession(ctx, "unknown", now); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("InvalidateSession of unknown session: got %v, want ErrNotFound", err)
+	}
+}
+
+func TestMemoryCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewMemoryCache()
+	c.Set(ctx, "kept", []byte("v"), 0)
+	c.Set(ctx, "live", []byte("v"), time.Hour)
+	c.Set(ctx, "expired", []byte("v"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	tests := []struct {
+		key     string
+		wantErr error
+	}{
+		{"kept", nil},
+		{"live", nil},
+		{"expired", ErrCacheMiss},
+		{"unknown", ErrCacheMiss},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			v, err := c.Get(ctx, tt.key)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Get: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && string(v) != "v" {
+				t.Fatalf("Get: got %q, want %q", v, "v")
+			}
+		})
+	}
+
+	if _, err := c.Take(ctx, "live"); err != nil {
+		t.Fatalf("Take: %v", err)
+	}
+	if _, err := c.Take(ctx, "live"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("second Take: got %v, want ErrCacheMiss", err)
+	}
+	c.Del(ctx, "kept", "unknown")
+	if _, err := c.Get(ctx, "kept"); !errors.Is(err, ErrCacheMiss) {
+		t.Fatalf("Get after Del: got %v, want ErrCacheMiss", err)
+	}
+}
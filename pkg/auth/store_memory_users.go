@@ -0,0 +1,148 @@
+package auth
+
+import (
+	"context"
+	"maps"
+	"sort"
+	"strings"
+	"time"
+)
+
+// cloneUser copies the attributes so that callers cannot change the store.
+func cloneUser(u User) User {
+	u.Attributes = maps.Clone(u.Attributes)
+	return u
+}
+
+func (m *MemoryStore) ListUsers(ctx context.Context, tenantId, prefix, after string, limit int) ([]User, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var users []User
+	for _, u := range m.users {
+		if u.TenantId == tenantId && strings.HasPrefix(u.Username, prefix) && u.Username > after {
+			users = append(users, cloneUser(u))
+		}
+	}
+	sort.Slice(users, func(i, j int) bool {
+		return users[i].Username < users[j].Username
+	})
+	if len(users) > limit {
+		users = users[:limit]
+	}
+	return users, nil
+}
+
+func (m *MemoryStore) DisableUser(ctx context.Context, tenantId, username string, now time.Time) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if !u.Disabled() {
+		u.DisabledAt = &now
+		m.users[key] = u
+	}
+	for hash, t := range m.resetTokens {
+		if t.TenantId == tenantId && t.Username == username {
+			delete(m.resetTokens, hash)
+		}
+	}
+	var ids []string
+	for id, s := range m.sessions {
+		if s.TenantId == tenantId && s.Username == username && s.ValidThrough.After(now) {
+			s.ValidThrough = now
+			m.sessions[id] = s
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+func (m *MemoryStore) EnableUser(ctx context.Context, tenantId, username string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok {
+		return ErrNotFound
+	}
+	u.DisabledAt = nil
+	m.users[key] = u
+	return nil
+}
+
+func (m *MemoryStore) UpdateProfile(ctx context.Context, tenantId, username string, p ProfileUpdate, now time.Time) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok {
+		return User{}, ErrNotFound
+	}
+	if p.Email != nil && *p.Email != u.Email {
+		u.Email = *p.Email
+		u.EmailVerifiedAt = nil
+	}
+	if p.DisplayName != nil {
+		u.DisplayName = *p.DisplayName
+	}
+	u.Attributes = maps.Clone(u.Attributes)
+	if u.Attributes == nil {
+		u.Attributes = map[string]string{}
+	}
+	maps.Copy(u.Attributes, p.setAttributes())
+	for _, k := range p.removedAttributes() {
+		delete(u.Attributes, k)
+	}
+	u.UpdatedAt = now
+	m.users[key] = u
+	return cloneUser(u), nil
+}
+
+func (m *MemoryStore) VerifyEmail(ctx context.Context, tenantId, username, email string, now time.Time) (User, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	u, ok := m.users[key]
+	if !ok || u.Email != email {
+		return User{}, ErrNotFound
+	}
+	if !u.EmailVerified() {
+		u.EmailVerifiedAt = &now
+		m.users[key] = u
+	}
+	return cloneUser(u), nil
+}
+
+func (m *MemoryStore) DeleteUser(ctx context.Context, tenantId, username string) ([]string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := userKey(tenantId, username)
+	if _, ok := m.users[key]; !ok {
+		return nil, ErrNotFound
+	}
+	var ids []string
+	for id, s := range m.sessions {
+		if s.TenantId == tenantId && s.Username == username {
+			delete(m.sessions, id)
+			ids = append(ids, id)
+		}
+	}
+	for hash, t := range m.resetTokens {
+		if t.TenantId == tenantId && t.Username == username {
+			delete(m.resetTokens, hash)
+		}
+	}
+	delete(m.users, key)
+	delete(m.mfa, key)
+	delete(m.recoveryCodes, key)
+	delete(m.userRoles, key)
+	for k, c := range m.webauthn {
+		if c.TenantId == tenantId && c.Username == username {
+			delete(m.webauthn, k)
+		}
+	}
+	return ids, nil
+}
@@ -0,0 +1,75 @@
+package auth
+
+import (
+	"context"
+	"encoding/hex"
+	"sort"
+	"time"
+)
+
+func webauthnKey(tenantId string, id []byte) string {
+	return tenantId + ":" + hex.EncodeToString(id)
+}
+
+func (m *MemoryStore) CreateWebauthnCredential(ctx context.Context, c WebauthnCredential) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.users[userKey(c.TenantId, c.Username)]; !ok {
+		return ErrNotFound
+	}
+	key := webauthnKey(c.TenantId, c.Id)
+	if _, ok := m.webauthn[key]; ok {
+		return ErrAlreadyExists
+	}
+	m.webauthn[key] = c
+	return nil
+}
+
+func (m *MemoryStore) ListWebauthnCredentials(ctx context.Context, tenantId, username string) ([]WebauthnCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	var res []WebauthnCredential
+	for _, c := range m.webauthn {
+		if c.TenantId == tenantId && c.Username == username {
+			res = append(res, c)
+		}
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i].CreatedAt.Before(res[j].CreatedAt) })
+	return res, nil
+}
+
+func (m *MemoryStore) GetWebauthnCredential(ctx context.Context, tenantId string, id []byte) (WebauthnCredential, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	c, ok := m.webauthn[webauthnKey(tenantId, id)]
+	if !ok {
+		return WebauthnCredential{}, ErrNotFound
+	}
+	return c, nil
+}
+
+func (m *MemoryStore) UseWebauthnCredential(ctx context.Context, tenantId string, id []byte, signCount uint32, now time.Time) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := webauthnKey(tenantId, id)
+	c, ok := m.webauthn[key]
+	if !ok || (signCount <= c.SignCount && (signCount != 0 || c.SignCount != 0)) {
+		return ErrNotFound
+	}
+	c.SignCount = signCount
+	c.LastUsedAt = &now
+	m.webauthn[key] = c
+	return nil
+}
+
+func (m *MemoryStore) DeleteWebauthnCredential(ctx context.Context, tenantId, username string, id []byte) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := webauthnKey(tenantId, id)
+	c, ok := m.webauthn[key]
+	if !ok || c.Username != username {
+		return ErrNotFound
+	}
+	delete(m.webauthn, key)
+	return nil
+}
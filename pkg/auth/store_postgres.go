@@ -0,0 +1,291 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	pgUniqueViolation           = "23505"
+	pgForeignKeyViolation       = "23503" // e.g. granting a role to an unknown user
+	pgInvalidTextRepresentation = "22P02" // e.g. a malformed uuid
+)
+
+// sessionColumns lists the columns read by scanSession, in order.
+const sessionColumns = `s.id, s.tenant_id, s.valid_through, s.expires_at, s.username, s.created_at, s.last_seen_at,
+	s.client_ip, s.user_agent, s.device_name`
+
+// scanSession reads sessionColumns followed by any extra columns into extra.
+func scanSession(row pgx.Row, extra ...any) (Session, error) {
+	var s Session
+	dest := []any{&s.Id, &s.TenantId, &s.ValidThrough, &s.ExpiresAt, &s.Username, &s.CreatedAt, &s.LastSeenAt,
+		&s.Ip, &s.UserAgent, &s.DeviceName}
+	err := row.Scan(append(dest, extra...)...)
+	s.Id = joinSessionId(s.TenantId, s.Id)
+	return s, err
+}
+
+// userColumns lists the columns read into userDest, in order.
+const userColumns = `u.tenant_id, u.username, u.password, u.email, u.email_verified_at, u.display_name,
+	u.attributes, u.created_at, u.updated_at, u.disabled_at`
+
+// userDest returns the scan destinations of userColumns.
+func userDest(u *User) []any {
+	return []any{&u.TenantId, &u.Username, &u.Password, &u.Email, &u.EmailVerifiedAt, &u.DisplayName,
+		&u.Attributes, &u.CreatedAt, &u.UpdatedAt, &u.DisabledAt}
+}
+
+// collectSessionIds reads the ids of sessions of the tenant returned by a query.
+func collectSessionIds(rows pgx.Rows, tenantId string) ([]string, error) {
+	defer rows.Close()
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			continue
+		}
+		ids = append(ids, joinSessionId(tenantId, id))
+	}
+	return ids, rows.Err()
+}
+
+// postgresStore implements UserStore and SessionStore on top of a pgx pool.
+type postgresStore struct {
+	dbpool *pgxpool.Pool
+}
+
+func newPostgresStore(dbpool *pgxpool.Pool) *postgresStore {
+	return &postgresStore{dbpool}
+}
+
+func (p *postgresStore) Ping(ctx context.Context) error {
+	return p.mapError(p.dbpool.Ping(ctx))
+}
+
+func (p *postgresStore) Close() {
+	p.dbpool.Close()
+}
+
+// mapError translates pgx errors into store errors. Errors not raised by
+// the server itself mean Postgres could not be reached. Store errors
+// returned from within transactions are kept as is.
+func (p *postgresStore) mapError(err error) error {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return err
+	}
+	if errors.Is(err, ErrNotFound) || errors.Is(err, ErrAlreadyExists) {
+		return err
+	}
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ErrNotFound
+	}
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		switch pgErr.Code {
+		case pgUniqueViolation:
+			return ErrAlreadyExists
+		case pgInvalidTextRepresentation, pgForeignKeyViolation:
+			return ErrNotFound
+		}
+		return err
+	}
+	return backendUnavailable(err)
+}
+
+// insertUser is the statement storing a user, whose arguments are given by
+// insertUserArgs. It stores nothing if the tenant is disabled, and locks it
+// so that DisableTenant cannot miss the user.
+const insertUser = `INSERT INTO users (tenant_id, username, password, email, display_name, attributes, created_at, updated_at)
+	SELECT t.id, $2, $3, $4, $5, $6, $7, $8
+	FROM tenants t
+	WHERE t.id = $1 AND t.disabled_at IS NULL
+	FOR SHARE
+	RETURNING username`
+
+// execInsertUser stores the user. Like the other queries expecting a row,
+// it fails with pgx.ErrNoRows if the tenant is missing or disabled.
+func execInsertUser(ctx context.Context, q querier, u User) error {
+	var username string
+	return q.QueryRow(ctx, insertUser, insertUserArgs(u)...).Scan(&username)
+}
+
+func insertUserArgs(u User) []any {
+	return []any{u.TenantId, u.Username, u.Password, u.Email, u.DisplayName, u.Attributes, u.CreatedAt, u.UpdatedAt}
+}
+
+func (p *postgresStore) CreateUser(ctx context.Context, u User) error {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	return p.mapError(execInsertUser(queryCtx, p.dbpool, u))
+}
+
+func (p *postgresStore) CreateUserWithSession(ctx context.Context, u User, s Session) (Session, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		if err := execInsertUser(queryCtx, tx, u); err != nil {
+			return err
+		}
+		var err error
+		s, err = p.insertSession(queryCtx, tx, s)
+		return err
+	})
+	if err != nil {
+		return Session{}, p.mapError(err)
+	}
+	return s, nil
+}
+
+func (p *postgresStore) GetUser(ctx context.Context, tenantId, username string) (User, error) {
+	var u User
+	query := `SELECT ` + userColumns + ` FROM users u WHERE u.tenant_id = $1 AND u.username = $2`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := p.dbpool.QueryRow(queryCtx, query, tenantId, username).Scan(userDest(&u)...)
+	if err != nil {
+		return User{}, p.mapError(err)
+	}
+	return u, nil
+}
+
+func (p *postgresStore) UpdatePassword(ctx context.Context, tenantId, username, password string) error {
+	query := "UPDATE users SET password = $1 WHERE tenant_id = $2 AND username = $3"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, password, tenantId, username)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) ReplacePassword(ctx context.Context, tenantId, username, current, password string) error {
+	query := "UPDATE users SET password = $1 WHERE tenant_id = $2 AND username = $3 AND password = $4"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, password, tenantId, username, current)
+	return p.mapError(err)
+}
+
+// querier is satisfied by both the pool and a transaction.
+type querier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// insertSession stores the session unless its user or tenant is disabled.
+// Both are locked so that DisableUser and DisableTenant cannot miss the
+// session.
+func (p *postgresStore) insertSession(ctx context.Context, q querier, s Session) (Session, error) {
+	query := `INSERT INTO sessions
+		(tenant_id, valid_through, expires_at, username, created_at, last_seen_at, client_ip, user_agent, device_name)
+		SELECT t.id, $2, $3, u.username, $5, $6, $7, $8, $9
+		FROM tenants t JOIN users u ON u.tenant_id = t.id
+		WHERE t.id = $1 AND t.disabled_at IS NULL AND u.username = $4 AND u.disabled_at IS NULL
+		FOR SHARE
+		RETURNING id`
+	var id string
+	err := q.QueryRow(ctx, query,
+		s.TenantId, s.ValidThrough, s.ExpiresAt, s.Username, s.CreatedAt, s.LastSeenAt, s.Ip, s.UserAgent, s.DeviceName,
+	).Scan(&id)
+	s.Id = joinSessionId(s.TenantId, id)
+	return s, err
+}
+
+func (p *postgresStore) CreateSession(ctx context.Context, s Session) (Session, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	s, err := p.insertSession(queryCtx, p.dbpool, s)
+	if err != nil {
+		return Session{}, p.mapError(err)
+	}
+	return s, nil
+}
+
+func (p *postgresStore) GetSession(ctx context.Context, id string) (Session, User, error) {
+	tenantId, id := splitSessionId(id)
+	query := `SELECT ` + sessionColumns + `, ` + userColumns + `
+		FROM users u JOIN sessions s
+		ON u.tenant_id = s.tenant_id AND u.username = s.username
+		WHERE s.id = $1 AND s.tenant_id = $2`
+	var u User
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	s, err := scanSession(p.dbpool.QueryRow(queryCtx, query, id, tenantId), userDest(&u)...)
+	if err != nil {
+		return Session{}, User{}, p.mapError(err)
+	}
+	return s, u, nil
+}
+
+func (p *postgresStore) ListSessions(ctx context.Context, tenantId, username string, now time.Time) ([]Session, error) {
+	query := `SELECT ` + sessionColumns + `
+		FROM sessions s
+		WHERE s.tenant_id = $1 AND s.username = $2 AND s.valid_through > $3
+		ORDER BY s.created_at DESC`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, tenantId, username, now)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	sessions, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Session, error) {
+		return scanSession(row)
+	})
+	return sessions, p.mapError(err)
+}
+
+func (p *postgresStore) CountActiveSessions(ctx context.Context, now time.Time) (int64, error) {
+	query := "SELECT count(*) FROM sessions WHERE valid_through > $1"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	var n int64
+	err := p.dbpool.QueryRow(queryCtx, query, now).Scan(&n)
+	return n, p.mapError(err)
+}
+
+func (p *postgresStore) TouchSession(ctx context.Context, id string, lastSeenAt, validThrough time.Time) error {
+	tenantId, id := splitSessionId(id)
+	query := `UPDATE sessions SET last_seen_at = $1, valid_through = LEAST($2, expires_at)
+		WHERE id = $3 AND tenant_id = $4 AND valid_through > $1`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, lastSeenAt, validThrough, id, tenantId)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) InvalidateSession(ctx context.Context, id string, now time.Time) error {
+	tenantId, id := splitSessionId(id)
+	query := "UPDATE sessions SET valid_through = LEAST(valid_through, $1) WHERE id = $2 AND tenant_id = $3"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, now, id, tenantId)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) InvalidateUserSessions(ctx context.Context, tenantId, username, exceptId string, now time.Time) ([]string, error) {
+	_, exceptId = splitSessionId(exceptId)
+	query := `UPDATE sessions SET valid_through = $1
+		WHERE tenant_id = $2 AND username = $3 AND valid_through > $1 AND id::text <> $4
+		RETURNING id`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, now, tenantId, username, exceptId)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return collectSessionIds(rows, tenantId)
+}
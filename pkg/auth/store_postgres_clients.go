@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const clientColumns = "id, name, COALESCE(secret_hash, ''), redirect_uris, created_at"
+
+func scanClient(row pgx.Row) (OAuthClient, error) {
+	var c OAuthClient
+	err := row.Scan(&c.Id, &c.Name, &c.SecretHash, &c.RedirectUris, &c.CreatedAt)
+	return c, err
+}
+
+func (p *postgresStore) CreateClient(ctx context.Context, c OAuthClient) error {
+	query := `INSERT INTO oauth_clients (id, name, secret_hash, redirect_uris, created_at)
+		VALUES ($1, $2, NULLIF($3, ''), $4, $5)`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, c.Id, c.Name, c.SecretHash, c.RedirectUris, c.CreatedAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) GetClient(ctx context.Context, id string) (OAuthClient, error) {
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients WHERE id = $1`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	c, err := scanClient(p.dbpool.QueryRow(queryCtx, query, id))
+	return c, p.mapError(err)
+}
+
+func (p *postgresStore) ListClients(ctx context.Context) ([]OAuthClient, error) {
+	query := `SELECT ` + clientColumns + ` FROM oauth_clients ORDER BY created_at`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	clients, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (OAuthClient, error) {
+		return scanClient(row)
+	})
+	return clients, p.mapError(err)
+}
+
+func (p *postgresStore) DeleteClient(ctx context.Context, id string) error {
+	query := "DELETE FROM oauth_clients WHERE id = $1"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, id)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
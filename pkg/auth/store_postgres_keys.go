@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// signingKeysLock serializes key rotations across instances.
+const signingKeysLock = "signing_keys"
+
+func (p *postgresStore) ListSigningKeys(ctx context.Context) ([]SigningKeyRecord, error) {
+	query := `SELECT kid, algorithm, encrypted_key, created_at, activates_at, revoked_at
+		FROM signing_keys
+		ORDER BY activates_at, created_at`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	keys, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (SigningKeyRecord, error) {
+		var k SigningKeyRecord
+		err := row.Scan(&k.Kid, &k.Algorithm, &k.EncryptedKey, &k.CreatedAt, &k.ActivatesAt, &k.RevokedAt)
+		return k, err
+	})
+	return keys, p.mapError(err)
+}
+
+func (p *postgresStore) CreateSigningKey(ctx context.Context, k SigningKeyRecord, previousKid string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(queryCtx, "SELECT pg_advisory_xact_lock(hashtext($1))", signingKeysLock); err != nil {
+			return err
+		}
+		query := `SELECT kid FROM signing_keys WHERE revoked_at IS NULL
+			ORDER BY activates_at DESC, created_at DESC LIMIT 1`
+		var latest string
+		err := tx.QueryRow(queryCtx, query).Scan(&latest)
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return err
+		}
+		if latest != previousKid {
+			return ErrAlreadyExists
+		}
+		query = `INSERT INTO signing_keys (kid, algorithm, encrypted_key, created_at, activates_at)
+			VALUES ($1, $2, $3, $4, $5)`
+		_, err = tx.Exec(queryCtx, query, k.Kid, k.Algorithm, k.EncryptedKey, k.CreatedAt, k.ActivatesAt)
+		return err
+	})
+	return p.mapError(err)
+}
+
+func (p *postgresStore) RevokeSigningKey(ctx context.Context, kid string, now time.Time) error {
+	query := "UPDATE signing_keys SET revoked_at = COALESCE(revoked_at, $1) WHERE kid = $2"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, now, kid)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *postgresStore) GetMfa(ctx context.Context, tenantId, username string) (Mfa, error) {
+	m := Mfa{TenantId: tenantId, Username: username}
+	query := `SELECT secret, last_used_step, created_at, enabled_at FROM user_mfa
+		WHERE tenant_id = $1 AND username = $2`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := p.dbpool.QueryRow(queryCtx, query, tenantId, username).Scan(&m.Secret, &m.LastUsedStep, &m.CreatedAt, &m.EnabledAt)
+	if err != nil {
+		return Mfa{}, p.mapError(err)
+	}
+	return m, nil
+}
+
+func (p *postgresStore) SetMfa(ctx context.Context, m Mfa) error {
+	query := `INSERT INTO user_mfa (tenant_id, username, secret, created_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT (tenant_id, username) DO UPDATE
+		SET secret = EXCLUDED.secret, last_used_step = 0, created_at = EXCLUDED.created_at
+		WHERE user_mfa.enabled_at IS NULL`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, m.TenantId, m.Username, m.Secret, m.CreatedAt)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrAlreadyExists
+	}
+	return nil
+}
+
+// insertRecoveryCodes replaces the recovery codes of the user.
+func (p *postgresStore) insertRecoveryCodes(ctx context.Context, tx pgx.Tx, tenantId, username string, codeHashes []string) error {
+	query := "DELETE FROM mfa_recovery_codes WHERE tenant_id = $1 AND username = $2"
+	if _, err := tx.Exec(ctx, query, tenantId, username); err != nil {
+		return err
+	}
+	query = `INSERT INTO mfa_recovery_codes (tenant_id, username, code_hash)
+		SELECT $1, $2, unnest($3::text[])`
+	_, err := tx.Exec(ctx, query, tenantId, username, codeHashes)
+	return err
+}
+
+func (p *postgresStore) EnableMfa(ctx context.Context, tenantId, username string, step int64, codeHashes []string, now time.Time) error {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		query := `UPDATE user_mfa SET enabled_at = $1, last_used_step = $2
+			WHERE tenant_id = $3 AND username = $4 AND enabled_at IS NULL AND last_used_step < $2`
+		tag, err := tx.Exec(queryCtx, query, now, step, tenantId, username)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		return p.insertRecoveryCodes(queryCtx, tx, tenantId, username, codeHashes)
+	})
+	return p.mapError(err)
+}
+
+func (p *postgresStore) DeleteMfa(ctx context.Context, tenantId, username string) error {
+	query := "DELETE FROM user_mfa WHERE tenant_id = $1 AND username = $2"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, tenantId, username)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) UseMfaStep(ctx context.Context, tenantId, username string, step int64) error {
+	query := `UPDATE user_mfa SET last_used_step = $1
+		WHERE tenant_id = $2 AND username = $3 AND last_used_step < $1`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, step, tenantId, username)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) ReplaceRecoveryCodes(ctx context.Context, tenantId, username string, codeHashes []string) error {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		return p.insertRecoveryCodes(queryCtx, tx, tenantId, username, codeHashes)
+	})
+	return p.mapError(err)
+}
+
+func (p *postgresStore) UseRecoveryCode(ctx context.Context, tenantId, username, codeHash string, now time.Time) error {
+	query := `UPDATE mfa_recovery_codes SET used_at = $1
+		WHERE tenant_id = $2 AND username = $3 AND code_hash = $4 AND used_at IS NULL`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, now, tenantId, username, codeHash)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
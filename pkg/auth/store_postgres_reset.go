@@ -0,0 +1,42 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *postgresStore) CreatePasswordResetToken(ctx context.Context, t PasswordResetToken) error {
+	query := `INSERT INTO password_reset_tokens (token_hash, tenant_id, username, created_at, expires_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, t.Hash, t.TenantId, t.Username, t.CreatedAt, t.ExpiresAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) ResetPassword(ctx context.Context, tokenHash, password string, now time.Time) (User, error) {
+	u := User{Password: password}
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		query := `UPDATE password_reset_tokens SET used_at = $1
+			WHERE token_hash = $2 AND used_at IS NULL AND expires_at > $1
+			RETURNING tenant_id, username`
+		if err := tx.QueryRow(queryCtx, query, now, tokenHash).Scan(&u.TenantId, &u.Username); err != nil {
+			return err
+		}
+		query = "UPDATE users SET password = $1 WHERE tenant_id = $2 AND username = $3"
+		if _, err := tx.Exec(queryCtx, query, password, u.TenantId, u.Username); err != nil {
+			return err
+		}
+		query = "DELETE FROM password_reset_tokens WHERE tenant_id = $1 AND username = $2 AND token_hash <> $3"
+		_, err := tx.Exec(queryCtx, query, u.TenantId, u.Username, tokenHash)
+		return err
+	})
+	if err != nil {
+		return User{}, p.mapError(err)
+	}
+	return u, nil
+}
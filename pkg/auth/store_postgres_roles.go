@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// roleSelect reads roles with their permissions, aggregated in two arrays
+// of the same length. It is completed with a WHERE clause if needed and
+// "GROUP BY r.name ORDER BY r.name".
+const roleSelect = `SELECT r.name, r.description, r.created_at,
+		COALESCE(array_agg(p.permission ORDER BY p.permission, p.resource) FILTER (WHERE p.role IS NOT NULL), '{}'),
+		COALESCE(array_agg(p.resource ORDER BY p.permission, p.resource) FILTER (WHERE p.role IS NOT NULL), '{}')
+	FROM roles r LEFT JOIN role_permissions p ON p.role = r.name`
+
+func scanRole(row pgx.Row) (Role, error) {
+	var r Role
+	var names, resources []string
+	if err := row.Scan(&r.Name, &r.Description, &r.CreatedAt, &names, &resources); err != nil {
+		return Role{}, err
+	}
+	r.Permissions = make([]Permission, len(names))
+	for i := range names {
+		r.Permissions[i] = Permission{Name: names[i], Resource: resources[i]}
+	}
+	return r, nil
+}
+
+func (p *postgresStore) queryRoles(ctx context.Context, query string, args ...any) ([]Role, error) {
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, args...)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	roles, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Role, error) {
+		return scanRole(row)
+	})
+	return roles, p.mapError(err)
+}
+
+func (p *postgresStore) CreateRole(ctx context.Context, r Role) error {
+	query := "INSERT INTO roles (name, description, created_at) VALUES ($1, $2, $3)"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, r.Name, r.Description, r.CreatedAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) DeleteRole(ctx context.Context, name string) error {
+	query := "DELETE FROM roles WHERE name = $1"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, name)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) ListRoles(ctx context.Context) ([]Role, error) {
+	return p.queryRoles(ctx, roleSelect+" GROUP BY r.name ORDER BY r.name")
+}
+
+func (p *postgresStore) AddRolePermission(ctx context.Context, role string, perm Permission) error {
+	query := `INSERT INTO role_permissions (role, permission, resource) VALUES ($1, $2, $3)
+		ON CONFLICT DO NOTHING`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, role, perm.Name, perm.Resource)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) RemoveRolePermission(ctx context.Context, role string, perm Permission) error {
+	query := "DELETE FROM role_permissions WHERE role = $1 AND permission = $2 AND resource = $3"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, role, perm.Name, perm.Resource)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) GrantRole(ctx context.Context, tenantId, username, role string, now time.Time) error {
+	query := `INSERT INTO user_roles (tenant_id, username, role, granted_at) VALUES ($1, $2, $3, $4)
+		ON CONFLICT DO NOTHING`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, tenantId, username, role, now)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) RevokeRole(ctx context.Context, tenantId, username, role string) error {
+	query := "DELETE FROM user_roles WHERE tenant_id = $1 AND username = $2 AND role = $3"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, tenantId, username, role)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) GetUserRoles(ctx context.Context, tenantId, username string) ([]Role, error) {
+	query := roleSelect + ` JOIN user_roles u ON u.role = r.name
+		WHERE u.tenant_id = $1 AND u.username = $2
+		GROUP BY r.name ORDER BY r.name`
+	return p.queryRoles(ctx, query, tenantId, username)
+}
+
+func (p *postgresStore) ListRoleMembers(ctx context.Context, role string) ([]User, error) {
+	query := "SELECT tenant_id, username FROM user_roles WHERE role = $1"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, role)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	members, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (User, error) {
+		var u User
+		err := row.Scan(&u.TenantId, &u.Username)
+		return u, err
+	})
+	return members, p.mapError(err)
+}
@@ -0,0 +1,60 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const serviceAccountColumns = "id, name, secret_hash, scopes, created_at"
+
+func scanServiceAccount(row pgx.Row) (ServiceAccount, error) {
+	var s ServiceAccount
+	err := row.Scan(&s.Id, &s.Name, &s.SecretHash, &s.Scopes, &s.CreatedAt)
+	return s, err
+}
+
+func (p *postgresStore) CreateServiceAccount(ctx context.Context, s ServiceAccount) error {
+	query := `INSERT INTO service_accounts (id, name, secret_hash, scopes, created_at)
+		VALUES ($1, $2, $3, $4, $5)`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, s.Id, s.Name, s.SecretHash, s.Scopes, s.CreatedAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) GetServiceAccount(ctx context.Context, id string) (ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts WHERE id = $1`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	s, err := scanServiceAccount(p.dbpool.QueryRow(queryCtx, query, id))
+	return s, p.mapError(err)
+}
+
+func (p *postgresStore) ListServiceAccounts(ctx context.Context) ([]ServiceAccount, error) {
+	query := `SELECT ` + serviceAccountColumns + ` FROM service_accounts ORDER BY created_at`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	accounts, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (ServiceAccount, error) {
+		return scanServiceAccount(row)
+	})
+	return accounts, p.mapError(err)
+}
+
+func (p *postgresStore) DeleteServiceAccount(ctx context.Context, id string) error {
+	query := "DELETE FROM service_accounts WHERE id = $1"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, id)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
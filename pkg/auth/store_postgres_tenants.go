@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const tenantColumns = "id, name, created_at, disabled_at"
+
+func scanTenant(row pgx.Row) (Tenant, error) {
+	var t Tenant
+	err := row.Scan(&t.Id, &t.Name, &t.CreatedAt, &t.DisabledAt)
+	return t, err
+}
+
+func (p *postgresStore) CreateTenant(ctx context.Context, t Tenant) error {
+	query := "INSERT INTO tenants (id, name, created_at) VALUES ($1, $2, $3)"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, t.Id, t.Name, t.CreatedAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) GetTenant(ctx context.Context, id string) (Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants WHERE id = $1`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	t, err := scanTenant(p.dbpool.QueryRow(queryCtx, query, id))
+	return t, p.mapError(err)
+}
+
+func (p *postgresStore) ListTenants(ctx context.Context) ([]Tenant, error) {
+	query := `SELECT ` + tenantColumns + ` FROM tenants ORDER BY created_at`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	tenants, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (Tenant, error) {
+		return scanTenant(row)
+	})
+	return tenants, p.mapError(err)
+}
+
+// DisableTenant locks the tenant before expiring its sessions; insertSession
+// waits for the lock and then sees the tenant disabled.
+func (p *postgresStore) DisableTenant(ctx context.Context, id string, now time.Time) ([]string, error) {
+	var ids []string
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		query := "UPDATE tenants SET disabled_at = COALESCE(disabled_at, $1) WHERE id = $2"
+		tag, err := tx.Exec(queryCtx, query, now, id)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		query = `UPDATE sessions SET valid_through = $1
+			WHERE tenant_id = $2 AND valid_through > $1
+			RETURNING id`
+		rows, err := tx.Query(queryCtx, query, now, id)
+		if err != nil {
+			return err
+		}
+		ids, err = collectSessionIds(rows, id)
+		return err
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,142 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+func (p *postgresStore) ListUsers(ctx context.Context, tenantId, prefix, after string, limit int) ([]User, error) {
+	query := `SELECT ` + userColumns + ` FROM users u
+		WHERE u.tenant_id = $1 AND starts_with(u.username, $2) AND u.username > $3
+		ORDER BY u.username
+		LIMIT $4`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, tenantId, prefix, after, limit)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	users, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (User, error) {
+		var u User
+		err := row.Scan(userDest(&u)...)
+		return u, err
+	})
+	return users, p.mapError(err)
+}
+
+// DisableUser locks the user before expiring their sessions; insertSession
+// waits for the lock and then sees the user disabled.
+func (p *postgresStore) DisableUser(ctx context.Context, tenantId, username string, now time.Time) ([]string, error) {
+	var ids []string
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		query := "UPDATE users SET disabled_at = COALESCE(disabled_at, $1) WHERE tenant_id = $2 AND username = $3"
+		tag, err := tx.Exec(queryCtx, query, now, tenantId, username)
+		if err != nil {
+			return err
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrNotFound
+		}
+		query = "DELETE FROM password_reset_tokens WHERE tenant_id = $1 AND username = $2"
+		if _, err := tx.Exec(queryCtx, query, tenantId, username); err != nil {
+			return err
+		}
+		query = `UPDATE sessions SET valid_through = $1
+			WHERE tenant_id = $2 AND username = $3 AND valid_through > $1
+			RETURNING id`
+		rows, err := tx.Query(queryCtx, query, now, tenantId, username)
+		if err != nil {
+			return err
+		}
+		ids, err = collectSessionIds(rows, tenantId)
+		return err
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return ids, nil
+}
+
+func (p *postgresStore) EnableUser(ctx context.Context, tenantId, username string) error {
+	query := "UPDATE users SET disabled_at = NULL WHERE tenant_id = $1 AND username = $2"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, tenantId, username)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// UpdateProfile merges the attributes in the query, so that concurrent
+// updates of different attributes are all kept.
+func (p *postgresStore) UpdateProfile(ctx context.Context, tenantId, username string, pu ProfileUpdate, now time.Time) (User, error) {
+	query := `UPDATE users u SET email = COALESCE($3, u.email),
+			email_verified_at = CASE WHEN COALESCE($3, u.email) = u.email THEN u.email_verified_at END,
+			display_name = COALESCE($4, u.display_name),
+			attributes = (u.attributes || $5::jsonb) - $6::text[], updated_at = $7
+		WHERE u.tenant_id = $1 AND u.username = $2
+		RETURNING ` + userColumns
+	var u User
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := p.dbpool.QueryRow(queryCtx, query,
+		tenantId, username, pu.Email, pu.DisplayName, pu.setAttributes(), pu.removedAttributes(), now,
+	).Scan(userDest(&u)...)
+	if err != nil {
+		return User{}, p.mapError(err)
+	}
+	return u, nil
+}
+
+func (p *postgresStore) VerifyEmail(ctx context.Context, tenantId, username, email string, now time.Time) (User, error) {
+	query := `UPDATE users u SET email_verified_at = COALESCE(u.email_verified_at, $4)
+		WHERE u.tenant_id = $1 AND u.username = $2 AND u.email = $3
+		RETURNING ` + userColumns
+	var u User
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := p.dbpool.QueryRow(queryCtx, query, tenantId, username, email, now).Scan(userDest(&u)...)
+	if err != nil {
+		return User{}, p.mapError(err)
+	}
+	return u, nil
+}
+
+// DeleteUser deletes the sessions first, as they do not cascade with the
+// user unlike other user data.
+func (p *postgresStore) DeleteUser(ctx context.Context, tenantId, username string) ([]string, error) {
+	var ids []string
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	err := pgx.BeginFunc(queryCtx, p.dbpool, func(tx pgx.Tx) error {
+		// Locking the user first keeps insertSession from adding a session.
+		query := "SELECT 1 FROM users WHERE tenant_id = $1 AND username = $2 FOR UPDATE"
+		var one int
+		if err := tx.QueryRow(queryCtx, query, tenantId, username).Scan(&one); err != nil {
+			return err
+		}
+		query = "DELETE FROM sessions WHERE tenant_id = $1 AND username = $2 RETURNING id"
+		rows, err := tx.Query(queryCtx, query, tenantId, username)
+		if err != nil {
+			return err
+		}
+		if ids, err = collectSessionIds(rows, tenantId); err != nil {
+			return err
+		}
+		query = "DELETE FROM users WHERE tenant_id = $1 AND username = $2"
+		_, err = tx.Exec(queryCtx, query, tenantId, username)
+		return err
+	})
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	return ids, nil
+}
@@ -0,0 +1,82 @@
+package auth
+
+import (
+	"context"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+)
+
+const webauthnColumns = `tenant_id, id, username, name, public_key, sign_count, attestation_format,
+	created_at, last_used_at`
+
+func scanWebauthnCredential(row pgx.Row) (WebauthnCredential, error) {
+	var c WebauthnCredential
+	var signCount int64
+	err := row.Scan(&c.TenantId, &c.Id, &c.Username, &c.Name, &c.PublicKey, &signCount, &c.AttestationFormat,
+		&c.CreatedAt, &c.LastUsedAt)
+	c.SignCount = uint32(signCount)
+	return c, err
+}
+
+func (p *postgresStore) CreateWebauthnCredential(ctx context.Context, c WebauthnCredential) error {
+	query := `INSERT INTO webauthn_credentials (` + webauthnColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	_, err := p.dbpool.Exec(queryCtx, query, c.TenantId, c.Id, c.Username, c.Name, c.PublicKey, int64(c.SignCount),
+		c.AttestationFormat, c.CreatedAt, c.LastUsedAt)
+	return p.mapError(err)
+}
+
+func (p *postgresStore) ListWebauthnCredentials(ctx context.Context, tenantId, username string) ([]WebauthnCredential, error) {
+	query := `SELECT ` + webauthnColumns + ` FROM webauthn_credentials
+		WHERE tenant_id = $1 AND username = $2 ORDER BY created_at`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	rows, err := p.dbpool.Query(queryCtx, query, tenantId, username)
+	if err != nil {
+		return nil, p.mapError(err)
+	}
+	credentials, err := pgx.CollectRows(rows, func(row pgx.CollectableRow) (WebauthnCredential, error) {
+		return scanWebauthnCredential(row)
+	})
+	return credentials, p.mapError(err)
+}
+
+func (p *postgresStore) GetWebauthnCredential(ctx context.Context, tenantId string, id []byte) (WebauthnCredential, error) {
+	query := `SELECT ` + webauthnColumns + ` FROM webauthn_credentials WHERE tenant_id = $1 AND id = $2`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	c, err := scanWebauthnCredential(p.dbpool.QueryRow(queryCtx, query, tenantId, id))
+	return c, p.mapError(err)
+}
+
+func (p *postgresStore) UseWebauthnCredential(ctx context.Context, tenantId string, id []byte, signCount uint32, now time.Time) error {
+	query := `UPDATE webauthn_credentials SET sign_count = $3, last_used_at = $4
+		WHERE tenant_id = $1 AND id = $2 AND (sign_count < $3 OR (sign_count = 0 AND $3 = 0))`
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, tenantId, id, int64(signCount), now)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (p *postgresStore) DeleteWebauthnCredential(ctx context.Context, tenantId, username string, id []byte) error {
+	query := "DELETE FROM webauthn_credentials WHERE tenant_id = $1 AND username = $2 AND id = $3"
+	queryCtx, cancel := context.WithTimeout(ctx, QUERY_TIMEOUT)
+	defer cancel()
+	tag, err := p.dbpool.Exec(queryCtx, query, tenantId, username, id)
+	if err != nil {
+		return p.mapError(err)
+	}
+	if tag.RowsAffected() == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
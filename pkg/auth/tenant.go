@@ -0,0 +1,127 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	// DEFAULT_TENANT holds the users of requests naming no tenant.
+	DEFAULT_TENANT = "default"
+
+	MAX_TENANT_ID_LENGTH   = 32
+	MAX_TENANT_NAME_LENGTH = 64
+)
+
+// Tenant is an organization with its own users. Usernames are unique
+// within a tenant only.
+type Tenant struct {
+	Id         string     `json:"id"`
+	Name       string     `json:"name"`
+	CreatedAt  time.Time  `json:"created_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+}
+
+func (t Tenant) Disabled() bool {
+	return t.DisabledAt != nil
+}
+
+func (a *AuthManager) composeTenantKey(id string) string {
+	return fmt.Sprintf("tenant_%s", id)
+}
+
+// tenantOrDefault returns DEFAULT_TENANT for requests naming no tenant.
+func tenantOrDefault(id string) string {
+	if id == "" {
+		return DEFAULT_TENANT
+	}
+	return id
+}
+
+// validateTenantId allows lowercase letters, digits and dashes, which keeps
+// session ids and cache keys built from tenant ids unambiguous.
+func validateTenantId(id string) error {
+	if id == "" || len(id) > MAX_TENANT_ID_LENGTH {
+		return invalidArgument("tenant id must be 1 to %d characters long", MAX_TENANT_ID_LENGTH)
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '-') {
+			return invalidArgument("tenant id may only contain lowercase letters, digits and dashes")
+		}
+	}
+	return nil
+}
+
+// getTenant returns an enabled tenant, reporting a disabled one with
+// ErrTenantDisabled.
+func (a *AuthManager) getTenant(ctx context.Context, id string) (Tenant, error) {
+	var t Tenant
+	err := a.cacheGet(ctx, metrics.CACHE_PATH_TENANT, a.composeTenantKey(id), &t)
+	if err != nil {
+		t, err = a.tenants.GetTenant(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			return Tenant{}, ErrTenantNotFound
+		}
+		if err != nil {
+			return Tenant{}, err
+		}
+		if data, err := json.Marshal(t); err == nil {
+			a.cache.Set(ctx, a.composeTenantKey(id), data, 0)
+		}
+	}
+	if t.Disabled() {
+		return Tenant{}, ErrTenantDisabled
+	}
+	return t, nil
+}
+
+func (a *AuthManager) CreateTenant(ctx context.Context, id, name string) (Tenant, error) {
+	if err := validateTenantId(id); err != nil {
+		return Tenant{}, err
+	}
+	if name == "" || len(name) > MAX_TENANT_NAME_LENGTH {
+		return Tenant{}, invalidArgument("tenant name must be 1 to %d characters long", MAX_TENANT_NAME_LENGTH)
+	}
+	t := Tenant{Id: id, Name: name, CreatedAt: utils.GetNowTz()}
+	err := a.tenants.CreateTenant(ctx, t)
+	if errors.Is(err, ErrAlreadyExists) {
+		return Tenant{}, ErrTenantExists
+	}
+	if err != nil {
+		return Tenant{}, err
+	}
+	slog.InfoContext(ctx, "Created tenant", "tenant_id", id)
+	return t, nil
+}
+
+func (a *AuthManager) ListTenants(ctx context.Context) ([]Tenant, error) {
+	return a.tenants.ListTenants(ctx)
+}
+
+// DisableTenant stops the users of the tenant from signing up and in, and
+// revokes their sessions. Disabling it again does nothing.
+func (a *AuthManager) DisableTenant(ctx context.Context, id string) error {
+	if id == DEFAULT_TENANT {
+		return invalidArgument("the default tenant cannot be disabled")
+	}
+	ids, err := a.tenants.DisableTenant(ctx, id, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return ErrTenantNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Disabled tenant", "tenant_id", id, "revoked_sessions", len(ids))
+	keys := []string{a.composeTenantKey(id)}
+	for _, sessionId := range ids {
+		keys = append(keys, a.composeSessionKey(sessionId))
+	}
+	return a.cache.Del(ctx, keys...)
+}
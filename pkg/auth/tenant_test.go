@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// newTenantTestAuthManager returns a manager with the acme and globex
+// tenants, each with a user alice of its own password.
+func newTenantTestAuthManager(t *testing.T, c AuthManagerConfig) (*AuthManager, *MemoryStore) {
+	t.Helper()
+	ctx := context.Background()
+	a, store := newTestAuthManager(t, c)
+	for _, id := range []string{"acme", "globex"} {
+		if _, err := a.CreateTenant(ctx, id, strings.ToUpper(id)); err != nil {
+			t.Fatalf("CreateTenant %s: %v", id, err)
+		}
+		if _, err := a.CreateUser(ctx, id, "alice", id+"-secret", "alice@"+id+".example"); err != nil {
+			t.Fatalf("CreateUser in %s: %v", id, err)
+		}
+	}
+	return a, store
+}
+
+func TestTenantIsolation(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTenantTestAuthManager(t, AuthManagerConfig{})
+	if _, err := a.CreateUser(ctx, "acme", "alice", "other", "alice@acme.example"); !errors.Is(err, ErrUserExists) {
+		t.Fatalf("CreateUser of a taken username: got %v, want ErrUserExists", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "acme", "alice", "globex-secret", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("LoginUser with the password of the other tenant: got %v, want ErrInvalidCredentials", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "", "alice", "acme-secret", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("LoginUser in the default tenant: got %v, want ErrInvalidCredentials", err)
+	}
+
+	acme, _, err := a.LoginUser(ctx, "acme", "alice", "acme-secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser in acme: %v", err)
+	}
+	globex, _, err := a.LoginUser(ctx, "globex", "alice", "globex-secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser in globex: %v", err)
+	}
+	user, _, err := a.GetUserBySessionId(ctx, acme.Id)
+	if err != nil || user.TenantId != "acme" || user.Email != "alice@acme.example" {
+		t.Fatalf("GetUserBySessionId of the acme session: got %+v, %v", user, err)
+	}
+	sessions, err := a.ListSessions(ctx, globex.Id)
+	if err != nil || len(sessions) != 1 {
+		t.Fatalf("ListSessions in globex: got %d sessions, %v", len(sessions), err)
+	}
+
+	_, id, _ := strings.Cut(acme.Id, ".")
+	tests := []struct {
+		name      string
+		sessionId string
+	}{
+		{"acme session in globex", "globex." + id},
+		{"acme session in the default tenant", id},
+		{"acme session in an unknown tenant", "initech." + id},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := a.GetUserBySessionId(ctx, tt.sessionId); !errors.Is(err, ErrSessionNotFound) {
+				t.Fatalf("GetUserBySessionId: got %v, want ErrSessionNotFound", err)
+			}
+		})
+	}
+
+	if err := a.RevokeAllSessions(ctx, acme.Id, false); err != nil {
+		t.Fatalf("RevokeAllSessions in acme: %v", err)
+	}
+	if _, _, err := a.GetUserBySessionId(ctx, globex.Id); err != nil {
+		t.Fatalf("GetUserBySessionId in globex after revoking acme sessions: %v", err)
+	}
+}
+
+func TestTenantLoginLimits(t *testing.T) {
+	ctx := context.Background()
+	a, _ := newTenantTestAuthManager(t, AuthManagerConfig{
+		Limiter:  LimiterConfig{LockoutThreshold: 2},
+		Notifier: &recordingNotifier{},
+	})
+	for i := 0; i < 2; i++ {
+		if _, _, err := a.LoginUser(ctx, "acme", "alice", "wrong", ClientInfo{}); !errors.Is(err, ErrInvalidCredentials) {
+			t.Fatalf("LoginUser with wrong password: got %v", err)
+		}
+	}
+	if _, _, err := a.LoginUser(ctx, "acme", "alice", "acme-secret", ClientInfo{}); !errors.Is(err, ErrRateLimited) {
+		t.Fatalf("LoginUser of the locked account: got %v, want ErrRateLimited", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "globex", "alice", "globex-secret", ClientInfo{}); err != nil {
+		t.Fatalf("LoginUser of the namesake in globex: %v", err)
+	}
+}
+
+func TestDisableTenant(t *testing.T) {
+	ctx := context.Background()
+	a, store := newTenantTestAuthManager(t, AuthManagerConfig{})
+	s, _, err := a.LoginUser(ctx, "acme", "alice", "acme-secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	if err := a.DisableTenant(ctx, "acme"); err != nil {
+		t.Fatalf("DisableTenant: %v", err)
+	}
+	if err := a.DisableTenant(ctx, "acme"); err != nil {
+		t.Fatalf("DisableTenant again: %v", err)
+	}
+	if _, _, err := a.GetUserBySessionId(ctx, s.Id); !errors.Is(err, ErrSessionExpired) {
+		t.Fatalf("GetUserBySessionId of a session of the disabled tenant: got %v, want ErrSessionExpired", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "acme", "alice", "acme-secret", ClientInfo{}); !errors.Is(err, ErrTenantDisabled) {
+		t.Fatalf("LoginUser: got %v, want ErrTenantDisabled", err)
+	}
+	if _, err := a.SignupAndLogin(ctx, "acme", "bob", "secret", "bob@acme.example", ClientInfo{}); !errors.Is(err, ErrTenantDisabled) {
+		t.Fatalf("SignupAndLogin: got %v, want ErrTenantDisabled", err)
+	}
+	if _, err := a.CreateUser(ctx, "acme", "bob", "secret", "bob@acme.example"); !errors.Is(err, ErrTenantDisabled) {
+		t.Fatalf("CreateUser: got %v, want ErrTenantDisabled", err)
+	}
+	if err := store.CreateUser(ctx, newUser("acme", "bob", "hash", "bob@acme.example")); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("MemoryStore.CreateUser: got %v, want ErrNotFound", err)
+	}
+	if _, _, err := a.LoginUser(ctx, "globex", "alice", "globex-secret", ClientInfo{}); err != nil {
+		t.Fatalf("LoginUser in another tenant: %v", err)
+	}
+	if err := a.DisableTenant(ctx, DEFAULT_TENANT); !errors.Is(err, ErrInvalidArgument) {
+		t.Fatalf("DisableTenant of the default tenant: got %v, want ErrInvalidArgument", err)
+	}
+}
@@ -0,0 +1,139 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// TOTP parameters as defined by RFC 6238, the defaults understood by
+// every authenticator app.
+const (
+	TOTP_PERIOD      = 30 * time.Second
+	TOTP_DIGITS      = 6
+	TOTP_SECRET_SIZE = 20 // bytes, the size of a SHA-1 block hash
+	// TOTP_SKEW is how many periods a code may lag or lead the server clock.
+	TOTP_SKEW = 1
+
+	RECOVERY_CODE_COUNT  = 10
+	RECOVERY_CODE_LENGTH = 10
+	// recoveryCodeAlphabet leaves out characters easily mistaken for others.
+	recoveryCodeAlphabet = "abcdefghjkmnpqrstuvwxyz23456789"
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+func newTotpSecret() (string, error) {
+	b := make([]byte, TOTP_SECRET_SIZE)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// totpUri returns the otpauth:// URI that authenticator apps import,
+// usually from a QR code.
+func totpUri(issuer, username, secret string) string {
+	label := url.PathEscape(issuer + ":" + username)
+	q := url.Values{}
+	q.Set("secret", secret)
+	q.Set("issuer", issuer)
+	q.Set("algorithm", "SHA1")
+	q.Set("digits", fmt.Sprint(TOTP_DIGITS))
+	q.Set("period", fmt.Sprint(int(TOTP_PERIOD.Seconds())))
+	return "otpauth://totp/" + label + "?" + q.Encode()
+}
+
+func totpStep(t time.Time) int64 {
+	return t.Unix() / int64(TOTP_PERIOD.Seconds())
+}
+
+// totpCode computes the code of the secret for the given time step.
+func totpCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", err
+	}
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	mod := uint32(1)
+	for i := 0; i < TOTP_DIGITS; i++ {
+		mod *= 10
+	}
+	return fmt.Sprintf("%0*d", TOTP_DIGITS, value%mod), nil
+}
+
+// verifyTotp checks the code against the steps around now, returning the
+// matched step so that callers can refuse to accept it twice.
+func verifyTotp(secret, code string, now time.Time) (int64, bool) {
+	code = strings.TrimSpace(code)
+	if len(code) != TOTP_DIGITS {
+		return 0, false
+	}
+	current := totpStep(now)
+	for step := current - TOTP_SKEW; step <= current+TOTP_SKEW; step++ {
+		expected, err := totpCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// newRecoveryCodes returns codes formatted for display, e.g. "abcde-fghjk".
+func newRecoveryCodes() ([]string, error) {
+	codes := make([]string, RECOVERY_CODE_COUNT)
+	for i := range codes {
+		code, err := randomString(recoveryCodeAlphabet, RECOVERY_CODE_LENGTH)
+		if err != nil {
+			return nil, err
+		}
+		half := RECOVERY_CODE_LENGTH / 2
+		codes[i] = code[:half] + "-" + code[half:]
+	}
+	return codes, nil
+}
+
+// randomString draws n characters uniformly from the alphabet, which must
+// be shorter than 256 characters.
+func randomString(alphabet string, n int) (string, error) {
+	limit := 256 - 256%len(alphabet)
+	res := make([]byte, 0, n)
+	b := make([]byte, n)
+	for len(res) < n {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < limit && len(res) < n {
+				res = append(res, alphabet[int(c)%len(alphabet)])
+			}
+		}
+	}
+	return string(res), nil
+}
+
+// hashRecoveryCode hashes a code as typed by the user. Codes are random
+// enough for a fast hash to be sufficient.
+func hashRecoveryCode(code string) string {
+	code = strings.ToLower(code)
+	code = strings.NewReplacer("-", "", " ", "").Replace(code)
+	sum := sha256.Sum256([]byte(code))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,6 +1,162 @@
 package auth
 
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+const (
+	DEFAULT_USER_PAGE_SIZE = 50
+	MAX_USER_PAGE_SIZE     = 500
+)
+
 type User struct {
+	TenantId string `json:"tenant_id"`
 	Username string `json:"username"`
 	Password string `json:"password"`
+	// Profile fields, shared with downstream services.
+	Email string `json:"email"`
+	// EmailVerifiedAt is set once the user proved they own Email.
+	EmailVerifiedAt *time.Time        `json:"email_verified_at,omitempty"`
+	DisplayName     string            `json:"display_name"`
+	Attributes      map[string]string `json:"attributes"`
+	CreatedAt       time.Time         `json:"created_at"`
+	UpdatedAt       time.Time         `json:"updated_at"`
+	// DisabledAt is set while an administrator keeps the user from signing in.
+	DisabledAt *time.Time `json:"disabled_at,omitempty"`
+}
+
+// newUser prepares a user created now with an unverified email address.
+func newUser(tenantId, username, passwordHash, email string) User {
+	now := utils.GetNowTz()
+	return User{
+		TenantId:   tenantId,
+		Username:   username,
+		Password:   passwordHash,
+		Email:      email,
+		Attributes: map[string]string{},
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+}
+
+func (u User) Disabled() bool {
+	return u.DisabledAt != nil
+}
+
+func (u User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Subject identifies the user across tenants, as the subject of tokens.
+// It is the bare username in the default tenant.
+func (u User) Subject() string {
+	return subject(u.TenantId, u.Username)
+}
+
+func subject(tenantId, username string) string {
+	if tenantId == DEFAULT_TENANT {
+		return username
+	}
+	return userKey(tenantId, username)
+}
+
+// userKey identifies a user across tenants in cache and limiter keys.
+// Tenant ids cannot contain a colon, so keys are unambiguous.
+func userKey(tenantId, username string) string {
+	return fmt.Sprintf("%s:%s", tenantId, username)
+}
+
+// ListUsers returns a page of the users of the tenant whose username starts
+// with prefix, in username order, along with the token of the next page,
+// empty after the last one. A page size of 0 means DEFAULT_USER_PAGE_SIZE.
+func (a *AuthManager) ListUsers(ctx context.Context, tenantId, prefix string, pageSize int, pageToken string) ([]User, string, error) {
+	if pageSize < 0 || pageSize > MAX_USER_PAGE_SIZE {
+		return nil, "", invalidArgument("page size must be at most %d", MAX_USER_PAGE_SIZE)
+	}
+	if pageSize == 0 {
+		pageSize = DEFAULT_USER_PAGE_SIZE
+	}
+	after, err := base64.RawURLEncoding.DecodeString(pageToken)
+	if err != nil {
+		return nil, "", invalidArgument("malformed page token")
+	}
+	users, err := a.users.ListUsers(ctx, tenantOrDefault(tenantId), prefix, string(after), pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+	var next string
+	if len(users) > pageSize {
+		users = users[:pageSize]
+		next = base64.RawURLEncoding.EncodeToString([]byte(users[pageSize-1].Username))
+	}
+	return users, next, nil
+}
+
+// GetUser returns a user for administrators, unlike getUserByUsername
+// reporting an unknown user with ErrUserNotFound.
+func (a *AuthManager) GetUser(ctx context.Context, tenantId, username string) (User, error) {
+	u, err := a.users.GetUser(ctx, tenantOrDefault(tenantId), username)
+	if errors.Is(err, ErrNotFound) {
+		return User{}, ErrUserNotFound
+	}
+	return u, err
+}
+
+// DisableUser keeps the user from signing in and revokes their sessions and
+// password reset tokens. Disabling them again does nothing.
+func (a *AuthManager) DisableUser(ctx context.Context, tenantId, username string) error {
+	tenantId = tenantOrDefault(tenantId)
+	ids, err := a.users.DisableUser(ctx, tenantId, username, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Disabled user", "tenant_id", tenantId, "username", username, "revoked_sessions", len(ids))
+	return a.dropUserFromCache(ctx, tenantId, username, ids)
+}
+
+func (a *AuthManager) EnableUser(ctx context.Context, tenantId, username string) error {
+	tenantId = tenantOrDefault(tenantId)
+	err := a.users.EnableUser(ctx, tenantId, username)
+	if errors.Is(err, ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Enabled user", "tenant_id", tenantId, "username", username)
+	return a.dropUserFromCache(ctx, tenantId, username, nil)
+}
+
+// DeleteUser deletes the user along with their sessions, second factor,
+// password reset tokens and role grants.
+func (a *AuthManager) DeleteUser(ctx context.Context, tenantId, username string) error {
+	tenantId = tenantOrDefault(tenantId)
+	ids, err := a.users.DeleteUser(ctx, tenantId, username)
+	if errors.Is(err, ErrNotFound) {
+		return ErrUserNotFound
+	}
+	if err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "Deleted user", "tenant_id", tenantId, "username", username, "deleted_sessions", len(ids))
+	return a.dropUserFromCache(ctx, tenantId, username, ids)
+}
+
+// dropUserFromCache deletes the cached user and the given sessions of theirs.
+func (a *AuthManager) dropUserFromCache(ctx context.Context, tenantId, username string, sessionIds []string) error {
+	keys := []string{a.composeUserKey(tenantId, username)}
+	for _, id := range sessionIds {
+		keys = append(keys, a.composeSessionKey(id))
+	}
+	return a.cache.Del(ctx, keys...)
 }
@@ -0,0 +1,403 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+	"unicode/utf8"
+
+	"github.com/JustDean/sam/pkg/metrics"
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// Types of WebAuthn ceremonies, as found in client data.
+const (
+	WEBAUTHN_CREATE = "webauthn.create"
+	WEBAUTHN_GET    = "webauthn.get"
+)
+
+const (
+	DEFAULT_PASSKEY_NAME  = "Passkey"
+	MAX_PASSKEY_NAME_SIZE = 64
+)
+
+// WebauthnCredential is a passkey of a user. Only its public key is known.
+type WebauthnCredential struct {
+	Id       []byte
+	TenantId string
+	Username string
+	Name     string
+	// PublicKey is the COSE_Key of the credential.
+	PublicKey []byte
+	// SignCount is the last signature counter of the authenticator; a
+	// counter that does not grow reveals a cloned authenticator.
+	SignCount         uint32
+	AttestationFormat string
+	CreatedAt         time.Time
+	LastUsedAt        *time.Time
+}
+
+// WebauthnChallenge starts a ceremony. Options are the JSON encoded
+// PublicKeyCredentialCreationOptions or PublicKeyCredentialRequestOptions
+// for navigator.credentials, with binary values base64url encoded.
+type WebauthnChallenge struct {
+	Options   []byte
+	ExpiresAt time.Time
+}
+
+// WebauthnAssertion is the response of an authenticator to a login
+// challenge, with binary values as returned by the browser.
+type WebauthnAssertion struct {
+	CredentialId      []byte
+	ClientDataJson    []byte
+	AuthenticatorData []byte
+	Signature         []byte
+	UserHandle        []byte // optional
+}
+
+// webauthnChallengeEntry is the payload cached under a challenge key. The
+// username is empty for logins with a discoverable credential.
+type webauthnChallengeEntry struct {
+	Ceremony string `json:"ceremony"`
+	TenantId string `json:"tenant_id"`
+	Username string `json:"username"`
+}
+
+func (a *AuthManager) composeWebauthnChallengeKey(challenge string) string {
+	return fmt.Sprintf("webauthn_challenge_%s", challenge)
+}
+
+// webauthnUserHandle is the user id given to authenticators. It is opaque
+// so that authenticators do not hold the username twice.
+func webauthnUserHandle(u User) []byte {
+	sum := sha256.Sum256([]byte(u.Subject()))
+	return sum[:]
+}
+
+type webauthnDescriptor struct {
+	Type string `json:"type"`
+	Id   string `json:"id"`
+}
+
+func webauthnDescriptors(credentials []WebauthnCredential) []webauthnDescriptor {
+	res := make([]webauthnDescriptor, 0, len(credentials))
+	for _, c := range credentials {
+		res = append(res, webauthnDescriptor{Type: "public-key", Id: base64.RawURLEncoding.EncodeToString(c.Id)})
+	}
+	return res
+}
+
+func (a *AuthManager) webauthnUserVerification() string {
+	if a.webauthnConfig.RequireUserVerification {
+		return "required"
+	}
+	return "preferred"
+}
+
+// newWebauthnChallenge caches a fresh challenge for the ceremony and
+// returns it base64url encoded.
+func (a *AuthManager) newWebauthnChallenge(ctx context.Context, entry webauthnChallengeEntry) (string, time.Time, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	challenge := base64.RawURLEncoding.EncodeToString(b)
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	ttl := a.webauthnConfig.ChallengeTtl
+	if err := a.cache.Set(ctx, a.composeWebauthnChallengeKey(challenge), data, ttl); err != nil {
+		return "", time.Time{}, err
+	}
+	return challenge, utils.GetNowTz().Add(ttl), nil
+}
+
+// takeWebauthnChallenge consumes the challenge of client data, so that
+// every challenge is answered once.
+func (a *AuthManager) takeWebauthnChallenge(ctx context.Context, c collectedClientData) (webauthnChallengeEntry, error) {
+	var entry webauthnChallengeEntry
+	data, err := a.cache.Take(ctx, a.composeWebauthnChallengeKey(c.Challenge))
+	if errors.Is(err, ErrCacheMiss) {
+		metrics.CacheMiss(metrics.CACHE_PATH_WEBAUTHN_CHALLENGE)
+		return entry, ErrWebauthnChallengeNotFound
+	}
+	if err != nil {
+		return entry, err
+	}
+	metrics.CacheHit(metrics.CACHE_PATH_WEBAUTHN_CHALLENGE)
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return entry, err
+	}
+	if entry.Ceremony != c.Type {
+		return entry, ErrWebauthnChallengeNotFound
+	}
+	return entry, nil
+}
+
+// BeginWebauthnRegistration starts the registration of a passkey for the
+// user of a session.
+func (a *AuthManager) BeginWebauthnRegistration(ctx context.Context, sessionId string) (WebauthnChallenge, error) {
+	if !a.webauthnConfig.Enabled {
+		return WebauthnChallenge{}, ErrWebauthnDisabled
+	}
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	existing, err := a.webauthn.ListWebauthnCredentials(ctx, user.TenantId, user.Username)
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	challenge, expiresAt, err := a.newWebauthnChallenge(ctx, webauthnChallengeEntry{
+		Ceremony: WEBAUTHN_CREATE,
+		TenantId: user.TenantId,
+		Username: user.Username,
+	})
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	type param struct {
+		Type string `json:"type"`
+		Alg  int64  `json:"alg"`
+	}
+	params := make([]param, 0, len(coseAlgorithms))
+	for _, alg := range coseAlgorithms {
+		params = append(params, param{Type: "public-key", Alg: alg})
+	}
+	displayName := user.DisplayName
+	if displayName == "" {
+		displayName = user.Username
+	}
+	options, err := json.Marshal(map[string]any{
+		"challenge": challenge,
+		"rp":        map[string]string{"id": a.webauthnConfig.RpId, "name": a.webauthnConfig.RpName},
+		"user": map[string]string{
+			"id":          base64.RawURLEncoding.EncodeToString(webauthnUserHandle(user)),
+			"name":        user.Username,
+			"displayName": displayName,
+		},
+		"pubKeyCredParams":   params,
+		"timeout":            a.webauthnConfig.ChallengeTtl.Milliseconds(),
+		"excludeCredentials": webauthnDescriptors(existing),
+		"authenticatorSelection": map[string]string{
+			"residentKey":      "preferred",
+			"userVerification": a.webauthnUserVerification(),
+		},
+		"attestation": "direct",
+	})
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	return WebauthnChallenge{Options: options, ExpiresAt: expiresAt}, nil
+}
+
+// FinishWebauthnRegistration verifies the response of the authenticator to
+// BeginWebauthnRegistration, with "none" or "packed" attestation, and
+// stores the new passkey under the given name.
+func (a *AuthManager) FinishWebauthnRegistration(ctx context.Context, sessionId, name string, clientDataJson, attestation []byte) (WebauthnCredential, error) {
+	if !a.webauthnConfig.Enabled {
+		return WebauthnCredential{}, ErrWebauthnDisabled
+	}
+	if name == "" {
+		name = DEFAULT_PASSKEY_NAME
+	}
+	if utf8.RuneCountInString(name) > MAX_PASSKEY_NAME_SIZE {
+		return WebauthnCredential{}, invalidArgument("passkey names have at most %d characters", MAX_PASSKEY_NAME_SIZE)
+	}
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	clientData, err := parseClientData(clientDataJson, WEBAUTHN_CREATE, a.webauthnConfig.Origins)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	entry, err := a.takeWebauthnChallenge(ctx, clientData)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	if entry.TenantId != user.TenantId || entry.Username != user.Username {
+		return WebauthnCredential{}, ErrWebauthnChallengeNotFound
+	}
+	o, err := parseAttestationObject(attestation)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	d, err := parseAuthenticatorData(o.AuthData)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	if err := d.check(a.webauthnConfig.RpId, a.webauthnConfig.RequireUserVerification); err != nil {
+		return WebauthnCredential{}, err
+	}
+	if d.CredentialId == nil {
+		return WebauthnCredential{}, invalidWebauthn("missing attested credential data")
+	}
+	key, err := parseCoseKey(d.PublicKey)
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	clientDataHash := sha256.Sum256(clientDataJson)
+	if err := verifyAttestation(o, d, key, clientDataHash[:]); err != nil {
+		return WebauthnCredential{}, err
+	}
+	c := WebauthnCredential{
+		Id:                d.CredentialId,
+		TenantId:          user.TenantId,
+		Username:          user.Username,
+		Name:              name,
+		PublicKey:         d.PublicKey,
+		SignCount:         d.SignCount,
+		AttestationFormat: o.Format,
+		CreatedAt:         utils.GetNowTz(),
+	}
+	err = a.webauthn.CreateWebauthnCredential(ctx, c)
+	if errors.Is(err, ErrAlreadyExists) {
+		return WebauthnCredential{}, ErrWebauthnCredentialExists
+	}
+	if errors.Is(err, ErrNotFound) {
+		return WebauthnCredential{}, ErrSessionNotFound
+	}
+	if err != nil {
+		return WebauthnCredential{}, err
+	}
+	slog.InfoContext(ctx, "Registered passkey", "tenant_id", user.TenantId, "username", user.Username, "attestation", o.Format)
+	return c, nil
+}
+
+// BeginWebauthnLogin starts a login with a passkey. Without a username,
+// the authenticator offers the discoverable passkeys it holds for the RP.
+func (a *AuthManager) BeginWebauthnLogin(ctx context.Context, tenantId, username string) (WebauthnChallenge, error) {
+	if !a.webauthnConfig.Enabled {
+		return WebauthnChallenge{}, ErrWebauthnDisabled
+	}
+	tenantId = tenantOrDefault(tenantId)
+	var allowed []WebauthnCredential
+	if username != "" {
+		var err error
+		allowed, err = a.webauthn.ListWebauthnCredentials(ctx, tenantId, username)
+		if err != nil {
+			return WebauthnChallenge{}, err
+		}
+	}
+	challenge, expiresAt, err := a.newWebauthnChallenge(ctx, webauthnChallengeEntry{
+		Ceremony: WEBAUTHN_GET,
+		TenantId: tenantId,
+		Username: username,
+	})
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	options, err := json.Marshal(map[string]any{
+		"challenge":        challenge,
+		"rpId":             a.webauthnConfig.RpId,
+		"timeout":          a.webauthnConfig.ChallengeTtl.Milliseconds(),
+		"allowCredentials": webauthnDescriptors(allowed),
+		"userVerification": a.webauthnUserVerification(),
+	})
+	if err != nil {
+		return WebauthnChallenge{}, err
+	}
+	return WebauthnChallenge{Options: options, ExpiresAt: expiresAt}, nil
+}
+
+// FinishWebauthnLogin verifies the assertion of a passkey in response to
+// BeginWebauthnLogin and, like LoginUser, returns a session or an MFA
+// challenge. Bad signatures count towards a lockout.
+func (a *AuthManager) FinishWebauthnLogin(ctx context.Context, assertion WebauthnAssertion, client ClientInfo) (Session, *MfaChallenge, error) {
+	if !a.webauthnConfig.Enabled {
+		return Session{}, nil, ErrWebauthnDisabled
+	}
+	clientData, err := parseClientData(assertion.ClientDataJson, WEBAUTHN_GET, a.webauthnConfig.Origins)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	entry, err := a.takeWebauthnChallenge(ctx, clientData)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	c, err := a.webauthn.GetWebauthnCredential(ctx, entry.TenantId, assertion.CredentialId)
+	if errors.Is(err, ErrNotFound) {
+		return Session{}, nil, invalidWebauthn("unknown credential")
+	}
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if entry.Username != "" && entry.Username != c.Username {
+		return Session{}, nil, invalidWebauthn("credential of another user")
+	}
+	if err := a.allowAttempt(ctx, LIMIT_WEBAUTHN, c.TenantId, c.Username, client.Ip); err != nil {
+		return Session{}, nil, err
+	}
+	user, err := a.getUserByUsername(ctx, c.TenantId, c.Username)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if len(assertion.UserHandle) > 0 && !bytes.Equal(assertion.UserHandle, webauthnUserHandle(user)) {
+		return Session{}, nil, invalidWebauthn("credential of another user")
+	}
+	d, err := parseAuthenticatorData(assertion.AuthenticatorData)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	if err := d.check(a.webauthnConfig.RpId, a.webauthnConfig.RequireUserVerification); err != nil {
+		return Session{}, nil, err
+	}
+	key, err := parseCoseKey(c.PublicKey)
+	if err != nil {
+		return Session{}, nil, err
+	}
+	clientDataHash := sha256.Sum256(assertion.ClientDataJson)
+	if !key.verify(slices.Concat(assertion.AuthenticatorData, clientDataHash[:]), assertion.Signature) {
+		a.recordFailure(ctx, LIMIT_WEBAUTHN, c.TenantId, c.Username)
+		return Session{}, nil, invalidWebauthn("invalid signature")
+	}
+	err = a.webauthn.UseWebauthnCredential(ctx, c.TenantId, c.Id, d.SignCount, utils.GetNowTz())
+	if errors.Is(err, ErrNotFound) {
+		slog.WarnContext(ctx, "Passkey signature counter did not grow, the authenticator may be cloned",
+			"tenant_id", c.TenantId, "username", c.Username, "sign_count", d.SignCount, "stored_sign_count", c.SignCount)
+		return Session{}, nil, invalidWebauthn("signature counter did not grow")
+	}
+	if err != nil {
+		return Session{}, nil, err
+	}
+	a.resetFailures(ctx, LIMIT_WEBAUTHN, c.TenantId, c.Username)
+	return a.completeLogin(ctx, user, client)
+}
+
+// ListWebauthnCredentials returns the passkeys of the user of a session.
+func (a *AuthManager) ListWebauthnCredentials(ctx context.Context, sessionId string) ([]WebauthnCredential, error) {
+	if !a.webauthnConfig.Enabled {
+		return nil, ErrWebauthnDisabled
+	}
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return nil, err
+	}
+	return a.webauthn.ListWebauthnCredentials(ctx, user.TenantId, user.Username)
+}
+
+// DeleteWebauthnCredential removes a passkey of the user of a session.
+func (a *AuthManager) DeleteWebauthnCredential(ctx context.Context, sessionId string, id []byte) error {
+	if !a.webauthnConfig.Enabled {
+		return ErrWebauthnDisabled
+	}
+	user, _, err := a.GetUserBySessionId(ctx, sessionId)
+	if err != nil {
+		return err
+	}
+	err = a.webauthn.DeleteWebauthnCredential(ctx, user.TenantId, user.Username, id)
+	if errors.Is(err, ErrNotFound) {
+		return ErrWebauthnCredentialNotFound
+	}
+	return err
+}
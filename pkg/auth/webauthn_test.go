@@ -0,0 +1,265 @@
+package auth
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"slices"
+	"testing"
+)
+
+const (
+	testRpId   = "example.com"
+	testOrigin = "https://example.com"
+)
+
+// softAuthenticator is a P-256 authenticator without attestation
+// certificate, for the none and packed self attestation formats.
+type softAuthenticator struct {
+	key       *ecdsa.PrivateKey
+	id        []byte
+	rpId      string
+	signCount uint32
+}
+
+func newSoftAuthenticator(t *testing.T) *softAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating the credential key: %v", err)
+	}
+	id := make([]byte, 16)
+	rand.Read(id)
+	return &softAuthenticator{key: key, id: id, rpId: testRpId}
+}
+
+func (s *softAuthenticator) coseKey() []byte {
+	return encodeCbor(cborPairs{
+		{1, 2},
+		{3, COSE_ALG_ES256},
+		{-1, 1},
+		{-2, s.key.X.FillBytes(make([]byte, 32))},
+		{-3, s.key.Y.FillBytes(make([]byte, 32))},
+	})
+}
+
+// authenticatorData counts a signature and returns the data it covers, with
+// the attested credential on registration.
+func (s *softAuthenticator) authenticatorData(attested bool) []byte {
+	s.signCount++
+	rpIdHash := sha256.Sum256([]byte(s.rpId))
+	flags := byte(webauthnFlagUserPresent | webauthnFlagUserVerified)
+	if attested {
+		flags |= webauthnFlagAttestedData
+	}
+	data := binary.BigEndian.AppendUint32(append(rpIdHash[:], flags), s.signCount)
+	if attested {
+		data = append(data, make([]byte, 16)...) // AAGUID
+		data = binary.BigEndian.AppendUint16(data, uint16(len(s.id)))
+		data = slices.Concat(data, s.id, s.coseKey())
+	}
+	return data
+}
+
+func (s *softAuthenticator) sign(authData, clientDataJson []byte) []byte {
+	clientDataHash := sha256.Sum256(clientDataJson)
+	digest := sha256.Sum256(slices.Concat(authData, clientDataHash[:]))
+	sig, _ := ecdsa.SignASN1(rand.Reader, s.key, digest[:])
+	return sig
+}
+
+func clientDataJson(ceremony, challenge, origin string) []byte {
+	data, _ := json.Marshal(collectedClientData{Type: ceremony, Challenge: challenge, Origin: origin})
+	return data
+}
+
+// register answers creation options with an attestation object.
+func (s *softAuthenticator) register(options []byte, origin, format string) ([]byte, []byte) {
+	clientData := clientDataJson(WEBAUTHN_CREATE, challengeOf(options), origin)
+	authData := s.authenticatorData(true)
+	stmt := cborPairs{}
+	if format == ATTESTATION_PACKED {
+		stmt = cborPairs{{"alg", COSE_ALG_ES256}, {"sig", s.sign(authData, clientData)}}
+	}
+	return clientData, encodeCbor(cborPairs{{"fmt", format}, {"attStmt", stmt}, {"authData", authData}})
+}
+
+// assert answers request options.
+func (s *softAuthenticator) assert(options []byte, origin string) WebauthnAssertion {
+	clientData := clientDataJson(WEBAUTHN_GET, challengeOf(options), origin)
+	authData := s.authenticatorData(false)
+	return WebauthnAssertion{
+		CredentialId:      s.id,
+		ClientDataJson:    clientData,
+		AuthenticatorData: authData,
+		Signature:         s.sign(authData, clientData),
+	}
+}
+
+func challengeOf(options []byte) string {
+	var o struct {
+		Challenge string `json:"challenge"`
+	}
+	json.Unmarshal(options, &o)
+	return o.Challenge
+}
+
+// newWebauthnTestAuthManager returns a manager with passkeys enabled and
+// the session of a user.
+func newWebauthnTestAuthManager(t *testing.T) (*AuthManager, Session) {
+	t.Helper()
+	ctx := context.Background()
+	a, _ := newTestAuthManager(t, AuthManagerConfig{
+		Webauthn: WebauthnConfig{Enabled: true, RpId: testRpId, Origins: []string{testOrigin}},
+	})
+	if _, err := a.CreateUser(ctx, "", "alice", "secret", "alice@example.com"); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	s, _, err := a.LoginUser(ctx, "", "alice", "secret", ClientInfo{})
+	if err != nil {
+		t.Fatalf("LoginUser: %v", err)
+	}
+	return a, s
+}
+
+func TestWebauthnRegistration(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name    string
+		answer  func(s *softAuthenticator, options []byte) ([]byte, []byte)
+		wantErr error
+	}{
+		{"none attestation", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			return s.register(options, testOrigin, ATTESTATION_NONE)
+		}, nil},
+		{"packed self attestation", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			return s.register(options, testOrigin, ATTESTATION_PACKED)
+		}, nil},
+		{"unsupported attestation", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			return s.register(options, testOrigin, "fido-u2f")
+		}, ErrInvalidWebauthnResponse},
+		{"bad self attestation signature", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			clientData, _ := s.register(options, testOrigin, ATTESTATION_NONE)
+			authData := s.authenticatorData(true)
+			stmt := cborPairs{{"alg", COSE_ALG_ES256}, {"sig", s.sign(authData, []byte("other"))}}
+			return clientData, encodeCbor(cborPairs{{"fmt", ATTESTATION_PACKED}, {"attStmt", stmt}, {"authData", authData}})
+		}, ErrInvalidWebauthnResponse},
+		{"other origin", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			return s.register(options, "https://evil.example", ATTESTATION_NONE)
+		}, ErrInvalidWebauthnResponse},
+		{"other RP id", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			s.rpId = "evil.example"
+			return s.register(options, testOrigin, ATTESTATION_NONE)
+		}, ErrInvalidWebauthnResponse},
+		{"unknown challenge", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			return s.register([]byte(`{"challenge":"unknown"}`), testOrigin, ATTESTATION_NONE)
+		}, ErrWebauthnChallengeNotFound},
+		{"malformed attestation object", func(s *softAuthenticator, options []byte) ([]byte, []byte) {
+			clientData, attestation := s.register(options, testOrigin, ATTESTATION_NONE)
+			return clientData, attestation[:len(attestation)-1]
+		}, ErrInvalidWebauthnResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, s := newWebauthnTestAuthManager(t)
+			challenge, err := a.BeginWebauthnRegistration(ctx, s.Id)
+			if err != nil {
+				t.Fatalf("BeginWebauthnRegistration: %v", err)
+			}
+			clientData, attestation := tt.answer(newSoftAuthenticator(t), challenge.Options)
+			c, err := a.FinishWebauthnRegistration(ctx, s.Id, "", clientData, attestation)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FinishWebauthnRegistration: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && c.Name != DEFAULT_PASSKEY_NAME {
+				t.Fatalf("got passkey name %q, want %q", c.Name, DEFAULT_PASSKEY_NAME)
+			}
+		})
+	}
+}
+
+func TestWebauthnLogin(t *testing.T) {
+	ctx := context.Background()
+	a, s := newWebauthnTestAuthManager(t)
+	passkey := newSoftAuthenticator(t)
+	challenge, err := a.BeginWebauthnRegistration(ctx, s.Id)
+	if err != nil {
+		t.Fatalf("BeginWebauthnRegistration: %v", err)
+	}
+	clientData, attestation := passkey.register(challenge.Options, testOrigin, ATTESTATION_PACKED)
+	if _, err := a.FinishWebauthnRegistration(ctx, s.Id, "Laptop", clientData, attestation); err != nil {
+		t.Fatalf("FinishWebauthnRegistration: %v", err)
+	}
+	if _, err := a.FinishWebauthnRegistration(ctx, s.Id, "Laptop", clientData, attestation); !errors.Is(err, ErrWebauthnChallengeNotFound) {
+		t.Fatalf("FinishWebauthnRegistration replayed: got %v, want ErrWebauthnChallengeNotFound", err)
+	}
+
+	tests := []struct {
+		name     string
+		username string
+		answer   func(options []byte) WebauthnAssertion
+		wantErr  error
+	}{
+		{"passkey of the user", "alice", func(options []byte) WebauthnAssertion {
+			return passkey.assert(options, testOrigin)
+		}, nil},
+		{"discoverable passkey", "", func(options []byte) WebauthnAssertion {
+			return passkey.assert(options, testOrigin)
+		}, nil},
+		{"other origin", "alice", func(options []byte) WebauthnAssertion {
+			return passkey.assert(options, "https://evil.example")
+		}, ErrInvalidWebauthnResponse},
+		{"replayed counter", "alice", func(options []byte) WebauthnAssertion {
+			credentials, _ := a.ListWebauthnCredentials(ctx, s.Id)
+			passkey.signCount = credentials[0].SignCount - 1
+			return passkey.assert(options, testOrigin)
+		}, ErrInvalidWebauthnResponse},
+		{"signature of another key", "alice", func(options []byte) WebauthnAssertion {
+			other := newSoftAuthenticator(t)
+			other.id = passkey.id
+			return other.assert(options, testOrigin)
+		}, ErrInvalidWebauthnResponse},
+		{"unknown credential", "alice", func(options []byte) WebauthnAssertion {
+			return newSoftAuthenticator(t).assert(options, testOrigin)
+		}, ErrInvalidWebauthnResponse},
+		{"credential of another user", "bob", func(options []byte) WebauthnAssertion {
+			return passkey.assert(options, testOrigin)
+		}, ErrInvalidWebauthnResponse},
+		{"other user handle", "", func(options []byte) WebauthnAssertion {
+			assertion := passkey.assert(options, testOrigin)
+			assertion.UserHandle = []byte("bob")
+			return assertion
+		}, ErrInvalidWebauthnResponse},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			challenge, err := a.BeginWebauthnLogin(ctx, "", tt.username)
+			if err != nil {
+				t.Fatalf("BeginWebauthnLogin: %v", err)
+			}
+			session, _, err := a.FinishWebauthnLogin(ctx, tt.answer(challenge.Options), ClientInfo{})
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("FinishWebauthnLogin: got %v, want %v", err, tt.wantErr)
+			}
+			if err == nil && session.Username != "alice" {
+				t.Fatalf("got a session of %q, want alice", session.Username)
+			}
+		})
+	}
+
+	credentials, err := a.ListWebauthnCredentials(ctx, s.Id)
+	if err != nil || len(credentials) != 1 || credentials[0].Name != "Laptop" || credentials[0].LastUsedAt == nil {
+		t.Fatalf("ListWebauthnCredentials: got %+v, %v", credentials, err)
+	}
+	if err := a.DeleteWebauthnCredential(ctx, s.Id, passkey.id); err != nil {
+		t.Fatalf("DeleteWebauthnCredential: %v", err)
+	}
+	if err := a.DeleteWebauthnCredential(ctx, s.Id, passkey.id); !errors.Is(err, ErrWebauthnCredentialNotFound) {
+		t.Fatalf("DeleteWebauthnCredential twice: got %v, want ErrWebauthnCredentialNotFound", err)
+	}
+}
@@ -0,0 +1,306 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ecdh"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"slices"
+)
+
+// Flags of authenticator data.
+const (
+	webauthnFlagUserPresent   = 0x01
+	webauthnFlagUserVerified  = 0x04
+	webauthnFlagAttestedData  = 0x40
+	webauthnFlagExtensionData = 0x80
+)
+
+// COSE algorithms accepted for credentials, in order of preference.
+const (
+	COSE_ALG_ES256 = -7
+	COSE_ALG_EDDSA = -8
+	COSE_ALG_RS256 = -257
+)
+
+var coseAlgorithms = []int64{COSE_ALG_ES256, COSE_ALG_EDDSA, COSE_ALG_RS256}
+
+// Attestation statement formats SAM verifies.
+const (
+	ATTESTATION_NONE   = "none"
+	ATTESTATION_PACKED = "packed"
+)
+
+// webauthnMaxCredentialIdLength is the longest credential id allowed by
+// the WebAuthn spec.
+const webauthnMaxCredentialIdLength = 1023
+
+// oidAaguid is the attestation certificate extension holding the AAGUID.
+var oidAaguid = asn1.ObjectIdentifier{1, 3, 6, 1, 4, 1, 45724, 1, 1, 4}
+
+func invalidWebauthn(format string, args ...any) error {
+	return fmt.Errorf("%w: %s", ErrInvalidWebauthnResponse, fmt.Sprintf(format, args...))
+}
+
+// collectedClientData is the clientDataJSON built by the browser.
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// parseClientData checks the type and origin of clientDataJSON.
+func parseClientData(data []byte, ceremony string, origins []string) (collectedClientData, error) {
+	var c collectedClientData
+	if err := json.Unmarshal(data, &c); err != nil {
+		return c, invalidWebauthn("malformed client data")
+	}
+	if c.Type != ceremony {
+		return c, invalidWebauthn("unexpected client data type %q", c.Type)
+	}
+	if !slices.Contains(origins, c.Origin) || c.CrossOrigin {
+		return c, invalidWebauthn("unexpected origin %q", c.Origin)
+	}
+	return c, nil
+}
+
+// authenticatorData is parsed from the binary authenticator data. The
+// attested credential is only there on registration.
+type authenticatorData struct {
+	RpIdHash     []byte
+	Flags        byte
+	SignCount    uint32
+	Aaguid       []byte
+	CredentialId []byte
+	PublicKey    []byte // COSE_Key, as encoded by the authenticator
+}
+
+func parseAuthenticatorData(data []byte) (authenticatorData, error) {
+	var d authenticatorData
+	if len(data) < 37 {
+		return d, invalidWebauthn("authenticator data too short")
+	}
+	d.RpIdHash, d.Flags, d.SignCount = data[:32], data[32], binary.BigEndian.Uint32(data[33:37])
+	rest := data[37:]
+	if d.Flags&webauthnFlagAttestedData != 0 {
+		if len(rest) < 18 {
+			return d, invalidWebauthn("attested credential data too short")
+		}
+		d.Aaguid = rest[:16]
+		n := int(binary.BigEndian.Uint16(rest[16:18]))
+		rest = rest[18:]
+		if n > webauthnMaxCredentialIdLength || n > len(rest) {
+			return d, invalidWebauthn("invalid credential id length")
+		}
+		d.CredentialId, rest = rest[:n], rest[n:]
+		_, after, err := decodeCbor(rest)
+		if err != nil {
+			return d, invalidWebauthn("malformed credential public key")
+		}
+		d.PublicKey, rest = rest[:len(rest)-len(after)], after
+	}
+	if d.Flags&webauthnFlagExtensionData != 0 {
+		_, after, err := decodeCbor(rest)
+		if err != nil {
+			return d, invalidWebauthn("malformed extensions")
+		}
+		rest = after
+	}
+	if len(rest) > 0 {
+		return d, invalidWebauthn("trailing authenticator data")
+	}
+	return d, nil
+}
+
+// check verifies the RP id hash and the user presence, and user
+// verification if required.
+func (d authenticatorData) check(rpId string, requireUserVerification bool) error {
+	want := sha256.Sum256([]byte(rpId))
+	if !bytes.Equal(d.RpIdHash, want[:]) {
+		return invalidWebauthn("unexpected RP id hash")
+	}
+	if d.Flags&webauthnFlagUserPresent == 0 {
+		return invalidWebauthn("user not present")
+	}
+	if requireUserVerification && d.Flags&webauthnFlagUserVerified == 0 {
+		return invalidWebauthn("user not verified")
+	}
+	return nil
+}
+
+// coseKey is a credential public key along with its algorithm.
+type coseKey struct {
+	Alg int64
+	Key crypto.PublicKey
+}
+
+// parseCoseKey accepts P-256 ECDSA, Ed25519 and RSA keys of at least 2048
+// bits, for the algorithms of coseAlgorithms.
+func parseCoseKey(raw []byte) (coseKey, error) {
+	item, rest, err := decodeCbor(raw)
+	if err != nil || len(rest) > 0 {
+		return coseKey{}, invalidWebauthn("malformed credential public key")
+	}
+	m, ok := item.(map[any]any)
+	if !ok {
+		return coseKey{}, invalidWebauthn("malformed credential public key")
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	switch {
+	case kty == 2 && alg == COSE_ALG_ES256:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		y, _ := m[int64(-3)].([]byte)
+		if crv != 1 || len(x) != 32 || len(y) != 32 {
+			return coseKey{}, invalidWebauthn("unsupported EC2 key")
+		}
+		// ecdh checks that the point is on the curve.
+		if _, err := ecdh.P256().NewPublicKey(slices.Concat([]byte{4}, x, y)); err != nil {
+			return coseKey{}, invalidWebauthn("invalid EC2 key")
+		}
+		key := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		return coseKey{Alg: alg, Key: key}, nil
+	case kty == 1 && alg == COSE_ALG_EDDSA:
+		crv, _ := m[int64(-1)].(int64)
+		x, _ := m[int64(-2)].([]byte)
+		if crv != 6 || len(x) != ed25519.PublicKeySize {
+			return coseKey{}, invalidWebauthn("unsupported OKP key")
+		}
+		return coseKey{Alg: alg, Key: ed25519.PublicKey(x)}, nil
+	case kty == 3 && alg == COSE_ALG_RS256:
+		n, _ := m[int64(-1)].([]byte)
+		e, _ := m[int64(-2)].([]byte)
+		if len(e) == 0 || len(e) > 4 {
+			return coseKey{}, invalidWebauthn("unsupported RSA key")
+		}
+		key := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if key.N.BitLen() < 2048 || key.E < 3 {
+			return coseKey{}, invalidWebauthn("unsupported RSA key")
+		}
+		return coseKey{Alg: alg, Key: key}, nil
+	}
+	return coseKey{}, invalidWebauthn("unsupported key type %d with algorithm %d", kty, alg)
+}
+
+// verify checks a signature of data made with the key.
+func (k coseKey) verify(data, sig []byte) bool {
+	digest := sha256.Sum256(data)
+	switch key := k.Key.(type) {
+	case *ecdsa.PublicKey:
+		return ecdsa.VerifyASN1(key, digest[:], sig)
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, data, sig)
+	case *rsa.PublicKey:
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
+
+// x509SignatureAlgorithms map COSE algorithms for attestation certificates.
+var x509SignatureAlgorithms = map[int64]x509.SignatureAlgorithm{
+	COSE_ALG_ES256: x509.ECDSAWithSHA256,
+	COSE_ALG_EDDSA: x509.PureEd25519,
+	COSE_ALG_RS256: x509.SHA256WithRSA,
+}
+
+// attestationObject is the CBOR map returned by the authenticator on
+// registration.
+type attestationObject struct {
+	Format   string
+	Stmt     map[any]any
+	AuthData []byte
+}
+
+func parseAttestationObject(raw []byte) (attestationObject, error) {
+	var o attestationObject
+	item, rest, err := decodeCbor(raw)
+	if err != nil || len(rest) > 0 {
+		return o, invalidWebauthn("malformed attestation object")
+	}
+	m, ok := item.(map[any]any)
+	if !ok {
+		return o, invalidWebauthn("malformed attestation object")
+	}
+	o.Format, _ = m["fmt"].(string)
+	o.Stmt, _ = m["attStmt"].(map[any]any)
+	o.AuthData, _ = m["authData"].([]byte)
+	if o.Format == "" || o.Stmt == nil || o.AuthData == nil {
+		return o, invalidWebauthn("malformed attestation object")
+	}
+	return o, nil
+}
+
+// verifyAttestation checks the attestation statement of a new credential.
+// Packed attestation certificates are checked for the requirements of the
+// format, not chained to trusted roots: SAM does not restrict which
+// authenticators may be registered.
+func verifyAttestation(o attestationObject, d authenticatorData, key coseKey, clientDataHash []byte) error {
+	switch o.Format {
+	case ATTESTATION_NONE:
+		if len(o.Stmt) != 0 {
+			return invalidWebauthn("unexpected statement for none attestation")
+		}
+		return nil
+	case ATTESTATION_PACKED:
+		return verifyPackedAttestation(o, d, key, clientDataHash)
+	}
+	return invalidWebauthn("unsupported attestation format %q", o.Format)
+}
+
+func verifyPackedAttestation(o attestationObject, d authenticatorData, key coseKey, clientDataHash []byte) error {
+	alg, _ := o.Stmt["alg"].(int64)
+	sig, _ := o.Stmt["sig"].([]byte)
+	if len(sig) == 0 {
+		return invalidWebauthn("missing packed attestation signature")
+	}
+	signed := slices.Concat(o.AuthData, clientDataHash)
+	x5c, ok := o.Stmt["x5c"].([]any)
+	if !ok {
+		// Self attestation, signed with the credential key itself.
+		if alg != key.Alg || !key.verify(signed, sig) {
+			return invalidWebauthn("invalid self attestation signature")
+		}
+		return nil
+	}
+	if len(x5c) == 0 {
+		return invalidWebauthn("empty attestation certificate chain")
+	}
+	der, _ := x5c[0].([]byte)
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return invalidWebauthn("malformed attestation certificate")
+	}
+	sigAlg, ok := x509SignatureAlgorithms[alg]
+	if !ok {
+		return invalidWebauthn("unsupported attestation algorithm %d", alg)
+	}
+	if err := cert.CheckSignature(sigAlg, signed, sig); err != nil {
+		return invalidWebauthn("invalid attestation signature")
+	}
+	if cert.Version != 3 || !slices.Contains(cert.Subject.OrganizationalUnit, "Authenticator Attestation") ||
+		!cert.BasicConstraintsValid || cert.IsCA {
+		return invalidWebauthn("attestation certificate does not meet the packed format requirements")
+	}
+	for _, ext := range cert.Extensions {
+		if !ext.Id.Equal(oidAaguid) {
+			continue
+		}
+		var aaguid []byte
+		if _, err := asn1.Unmarshal(ext.Value, &aaguid); err != nil || ext.Critical || !bytes.Equal(aaguid, d.Aaguid) {
+			return invalidWebauthn("attestation certificate AAGUID does not match")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package health
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+const CHECK_TIMEOUT = 5 * time.Second
+
+// Checker periodically runs a dependency check and tracks whether the
+// service is ready to serve. Listeners are told about every change.
+type Checker struct {
+	check    func(ctx context.Context) error
+	interval time.Duration
+
+	mu        sync.Mutex
+	ready     bool
+	stopped   bool
+	listeners []func(ready bool)
+}
+
+func NewChecker(check func(ctx context.Context) error, interval time.Duration) *Checker {
+	return &Checker{check: check, interval: interval}
+}
+
+func (c *Checker) Ready() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.ready
+}
+
+// OnChange registers f to be called with the new state on every change.
+// f is called right away with the current state.
+func (c *Checker) OnChange(f func(ready bool)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.listeners = append(c.listeners, f)
+	f(c.ready)
+}
+
+func (c *Checker) set(ready bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stopped {
+		ready = false
+	}
+	if c.ready == ready {
+		return
+	}
+	c.ready = ready
+	for _, f := range c.listeners {
+		f(ready)
+	}
+}
+
+// Shutdown marks the service as not ready for good, so that probes fail
+// while in-flight requests are drained.
+func (c *Checker) Shutdown() {
+	c.mu.Lock()
+	c.stopped = true
+	c.mu.Unlock()
+	c.set(false)
+}
+
+func (c *Checker) probe(ctx context.Context) {
+	checkCtx, cancel := context.WithTimeout(ctx, CHECK_TIMEOUT)
+	defer cancel()
+	err := c.check(checkCtx)
+	if err != nil && c.Ready() {
+		slog.Error("Health check failed", "error", err)
+	} else if err == nil && !c.Ready() {
+		slog.Info("Health check passed")
+	}
+	c.set(err == nil)
+}
+
+func (c *Checker) Run(ctx context.Context) {
+	c.probe(ctx)
+	ticker := time.NewTicker(c.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			c.Shutdown()
+			return
+		case <-ticker.C:
+			c.probe(ctx)
+		}
+	}
+}
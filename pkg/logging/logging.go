@@ -0,0 +1,155 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const (
+	FORMAT_JSON = "json"
+	FORMAT_TEXT = "text"
+
+	REDACTED = "[REDACTED]"
+	// sessionIdPrefixLength is how much of a session id is kept in logs.
+	sessionIdPrefixLength = 8
+)
+
+// secretKeys are attribute keys whose values are never logged.
+var secretKeys = map[string]bool{
+	"password":         true,
+	"current_password": true,
+	"new_password":     true,
+	"secret":           true,
+	"token":            true,
+	"code":             true,
+	"challenge":        true,
+	"authorization":    true,
+	"body":             true, // of notifications, carrying tokens
+}
+
+// sessionIdKeys are attribute keys whose values are logged truncated.
+var sessionIdKeys = map[string]bool{
+	"session_id":        true,
+	"except_session_id": true,
+}
+
+// credentialPrefixes start bearer tokens, which are logged truncated
+// whatever their key.
+var credentialPrefixes = []string{
+	"svc_",  // service tokens
+	"oidc_", // access tokens of relying parties
+}
+
+// sessionIdPattern matches session ids, which are uuids prefixed with the
+// tenant and a dot outside the default tenant, whatever their key.
+var sessionIdPattern = regexp.MustCompile(`^([a-z0-9-]+\.)?[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}$`)
+
+type Config struct {
+	Format string // json or text
+	Level  string // debug, info, warn or error
+}
+
+// Setup builds a logger from the config and makes it the slog default.
+func Setup(c Config) (*slog.Logger, error) {
+	logger, err := New(c, os.Stderr)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+	return logger, nil
+}
+
+func New(c Config, w io.Writer) (*slog.Logger, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(c.Level)); err != nil {
+		return nil, fmt.Errorf("unknown log level %q", c.Level)
+	}
+	opts := &slog.HandlerOptions{Level: level, ReplaceAttr: redact}
+	var h slog.Handler
+	switch strings.ToLower(c.Format) {
+	case FORMAT_JSON:
+		h = slog.NewJSONHandler(w, opts)
+	case FORMAT_TEXT:
+		h = slog.NewTextHandler(w, opts)
+	default:
+		return nil, fmt.Errorf("unknown log format %q", c.Format)
+	}
+	return slog.New(contextHandler{h}), nil
+}
+
+// redact hides secrets and shortens session ids wherever they are logged.
+func redact(groups []string, a slog.Attr) slog.Attr {
+	key := strings.ToLower(a.Key)
+	if secretKeys[key] {
+		return slog.String(a.Key, REDACTED)
+	}
+	if sessionIdKeys[key] || a.Value.Kind() == slog.KindString && isCredential(a.Value.String()) {
+		return slog.String(a.Key, MaskSessionId(a.Value.String()))
+	}
+	return a
+}
+
+// isCredential tells values granting access, so that they are masked even
+// when logged under a key not known to hold secrets.
+func isCredential(v string) bool {
+	for _, prefix := range credentialPrefixes {
+		if strings.HasPrefix(v, prefix) {
+			return true
+		}
+	}
+	return sessionIdPattern.MatchString(v)
+}
+
+// MaskSessionId keeps a prefix of the id, enough to correlate log lines
+// but not to use the session.
+func MaskSessionId(id string) string {
+	if len(id) <= sessionIdPrefixLength {
+		return strings.Repeat("*", len(id))
+	}
+	return id[:sessionIdPrefixLength] + "..."
+}
+
+type requestIdKey struct{}
+
+// WithRequestId returns a context whose log lines carry the request id.
+func WithRequestId(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIdKey{}, id)
+}
+
+func RequestId(ctx context.Context) string {
+	id, _ := ctx.Value(requestIdKey{}).(string)
+	return id
+}
+
+func NewRequestId() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// contextHandler adds the request id found in the context to every record.
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h contextHandler) Handle(ctx context.Context, r slog.Record) error {
+	if id := RequestId(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,47 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	requestId := NewRequestId()
+	tests := []struct {
+		key   string
+		value any
+		want  string
+	}{
+		{"password", "hunter2", REDACTED},
+		{"Token", "abc", REDACTED},
+		{"body", "https://example.com/reset?token=abc", REDACTED},
+		{"session_id", "0f8fad5b-d9cb-469f-a165-70867728950e", "0f8fad5b..."},
+		{"session_id", "short", "*****"},
+		{"value", "0f8fad5b-d9cb-469f-a165-70867728950e", "0f8fad5b..."},
+		{"value", "acme.0f8fad5b-d9cb-469f-a165-70867728950e", "acme.0f8..."},
+		{"value", "svc_c2VjcmV0IHRva2Vu", "svc_c2Vj..."},
+		{"value", "oidc_c2VjcmV0IHRva2Vu", "oidc_c2V..."},
+		{"username", "alice", "alice"},
+		{"request_id", requestId, requestId},
+		{"count", 3, "3"},
+	}
+	for _, tt := range tests {
+		t.Run(fmt.Sprintf("%s=%v", tt.key, tt.value), func(t *testing.T) {
+			var buf bytes.Buffer
+			logger, err := New(Config{Format: FORMAT_JSON, Level: "info"}, &buf)
+			if err != nil {
+				t.Fatalf("New: %v", err)
+			}
+			logger.Info("test", tt.key, tt.value)
+			var record map[string]any
+			if err := json.Unmarshal(buf.Bytes(), &record); err != nil {
+				t.Fatalf("decoding %q: %v", buf.String(), err)
+			}
+			if got := fmt.Sprint(record[tt.key]); got != tt.want {
+				t.Fatalf("got %s=%q, want %q", tt.key, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,139 @@
+package metrics
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/redis/go-redis/v9"
+)
+
+const COLLECT_TIMEOUT = 5 * time.Second
+
+func desc(subsystem, name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(NAMESPACE, subsystem, name), help, nil, nil)
+}
+
+type pgxPoolCollector struct {
+	pool            *pgxpool.Pool
+	acquiredConns   *prometheus.Desc
+	idleConns       *prometheus.Desc
+	totalConns      *prometheus.Desc
+	maxConns        *prometheus.Desc
+	acquires        *prometheus.Desc
+	acquireDuration *prometheus.Desc
+	emptyAcquires   *prometheus.Desc
+	canceledAcquire *prometheus.Desc
+}
+
+// NewPgxPoolCollector exposes the statistics of a Postgres connection pool.
+func NewPgxPoolCollector(pool *pgxpool.Pool) prometheus.Collector {
+	return &pgxPoolCollector{
+		pool:            pool,
+		acquiredConns:   desc("pgxpool", "acquired_conns", "Connections currently in use."),
+		idleConns:       desc("pgxpool", "idle_conns", "Idle connections."),
+		totalConns:      desc("pgxpool", "total_conns", "Open connections."),
+		maxConns:        desc("pgxpool", "max_conns", "Maximum size of the pool."),
+		acquires:        desc("pgxpool", "acquires_total", "Successful connection acquires."),
+		acquireDuration: desc("pgxpool", "acquire_duration_seconds_total", "Time spent acquiring connections."),
+		emptyAcquires:   desc("pgxpool", "empty_acquires_total", "Acquires that waited for a connection."),
+		canceledAcquire: desc("pgxpool", "canceled_acquires_total", "Acquires canceled by their context."),
+	}
+}
+
+func (c *pgxPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.acquiredConns
+	ch <- c.idleConns
+	ch <- c.totalConns
+	ch <- c.maxConns
+	ch <- c.acquires
+	ch <- c.acquireDuration
+	ch <- c.emptyAcquires
+	ch <- c.canceledAcquire
+}
+
+func (c *pgxPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.pool.Stat()
+	ch <- prometheus.MustNewConstMetric(c.acquiredConns, prometheus.GaugeValue, float64(s.AcquiredConns()))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns()))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns()))
+	ch <- prometheus.MustNewConstMetric(c.maxConns, prometheus.GaugeValue, float64(s.MaxConns()))
+	ch <- prometheus.MustNewConstMetric(c.acquires, prometheus.CounterValue, float64(s.AcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.acquireDuration, prometheus.CounterValue, s.AcquireDuration().Seconds())
+	ch <- prometheus.MustNewConstMetric(c.emptyAcquires, prometheus.CounterValue, float64(s.EmptyAcquireCount()))
+	ch <- prometheus.MustNewConstMetric(c.canceledAcquire, prometheus.CounterValue, float64(s.CanceledAcquireCount()))
+}
+
+type redisPoolCollector struct {
+	client     *redis.Client
+	hits       *prometheus.Desc
+	misses     *prometheus.Desc
+	timeouts   *prometheus.Desc
+	totalConns *prometheus.Desc
+	idleConns  *prometheus.Desc
+	staleConns *prometheus.Desc
+}
+
+// NewRedisPoolCollector exposes the statistics of a Redis connection pool.
+func NewRedisPoolCollector(client *redis.Client) prometheus.Collector {
+	return &redisPoolCollector{
+		client:     client,
+		hits:       desc("redis_pool", "hits_total", "Times a free connection was found in the pool."),
+		misses:     desc("redis_pool", "misses_total", "Times a free connection was not found in the pool."),
+		timeouts:   desc("redis_pool", "timeouts_total", "Times a wait for a connection timed out."),
+		totalConns: desc("redis_pool", "total_conns", "Open connections."),
+		idleConns:  desc("redis_pool", "idle_conns", "Idle connections."),
+		staleConns: desc("redis_pool", "stale_conns_total", "Stale connections removed from the pool."),
+	}
+}
+
+func (c *redisPoolCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.hits
+	ch <- c.misses
+	ch <- c.timeouts
+	ch <- c.totalConns
+	ch <- c.idleConns
+	ch <- c.staleConns
+}
+
+func (c *redisPoolCollector) Collect(ch chan<- prometheus.Metric) {
+	s := c.client.PoolStats()
+	ch <- prometheus.MustNewConstMetric(c.hits, prometheus.CounterValue, float64(s.Hits))
+	ch <- prometheus.MustNewConstMetric(c.misses, prometheus.CounterValue, float64(s.Misses))
+	ch <- prometheus.MustNewConstMetric(c.timeouts, prometheus.CounterValue, float64(s.Timeouts))
+	ch <- prometheus.MustNewConstMetric(c.totalConns, prometheus.GaugeValue, float64(s.TotalConns))
+	ch <- prometheus.MustNewConstMetric(c.idleConns, prometheus.GaugeValue, float64(s.IdleConns))
+	ch <- prometheus.MustNewConstMetric(c.staleConns, prometheus.CounterValue, float64(s.StaleConns))
+}
+
+type activeSessionsCollector struct {
+	count func(ctx context.Context) (int64, error)
+	desc  *prometheus.Desc
+}
+
+// NewActiveSessionsCollector exposes the number of unexpired sessions,
+// counted with count on every scrape.
+func NewActiveSessionsCollector(count func(ctx context.Context) (int64, error)) prometheus.Collector {
+	return &activeSessionsCollector{
+		count: count,
+		desc:  desc("", "active_sessions", "Sessions that have not expired."),
+	}
+}
+
+func (c *activeSessionsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- c.desc
+}
+
+func (c *activeSessionsCollector) Collect(ch chan<- prometheus.Metric) {
+	ctx, cancel := context.WithTimeout(context.Background(), COLLECT_TIMEOUT)
+	defer cancel()
+	n, err := c.count(ctx)
+	if err != nil {
+		slog.Warn("Error counting active sessions", "error", err)
+		ch <- prometheus.NewInvalidMetric(c.desc, err)
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(c.desc, prometheus.GaugeValue, float64(n))
+}
@@ -0,0 +1,67 @@
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+)
+
+const NAMESPACE = "sam"
+
+const (
+	CACHE_PATH_SESSION            = "session"
+	CACHE_PATH_SESSION_HANDLE     = "session_handle"
+	CACHE_PATH_USER               = "user"
+	CACHE_PATH_MFA_CHALLENGE      = "mfa_challenge"
+	CACHE_PATH_SERVICE_TOKEN      = "service_token"
+	CACHE_PATH_OIDC_ACCESS_TOKEN  = "oidc_access_token"
+	CACHE_PATH_TENANT             = "tenant"
+	CACHE_PATH_PASSWORDLESS       = "passwordless"
+	CACHE_PATH_WEBAUTHN_CHALLENGE = "webauthn_challenge"
+)
+
+// Registry holds every metric exposed by the service.
+var Registry = prometheus.NewRegistry()
+
+var (
+	rpcRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "rpc_requests_total",
+		Help:      "Handled RPCs by method and status code.",
+	}, []string{"method", "code"})
+	rpcDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: NAMESPACE,
+		Name:      "rpc_duration_seconds",
+		Help:      "Latency of handled RPCs by method and status code.",
+		Buckets:   []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10},
+	}, []string{"method", "code"})
+	cacheRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: NAMESPACE,
+		Name:      "cache_requests_total",
+		Help:      "Cache lookups by path and result.",
+	}, []string{"path", "result"})
+)
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		rpcRequests,
+		rpcDuration,
+		cacheRequests,
+	)
+}
+
+func ObserveRpc(method, code string, duration time.Duration) {
+	rpcRequests.WithLabelValues(method, code).Inc()
+	rpcDuration.WithLabelValues(method, code).Observe(duration.Seconds())
+}
+
+func CacheHit(path string) {
+	cacheRequests.WithLabelValues(path, "hit").Inc()
+}
+
+func CacheMiss(path string) {
+	cacheRequests.WithLabelValues(path, "miss").Inc()
+}
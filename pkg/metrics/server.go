@@ -0,0 +1,58 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const SHUTDOWN_TIMEOUT = 5 * time.Second
+
+type Config struct {
+	Host string
+	Port string
+}
+
+func (c *Config) url() string {
+	return fmt.Sprintf("%s:%s", c.Host, c.Port)
+}
+
+// SetServer prepares the HTTP listener serving /metrics.
+func SetServer(c Config) (*Server, error) {
+	lis, err := net.Listen("tcp", c.url())
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry}))
+	return &Server{
+		l: lis,
+		s: &http.Server{Handler: mux, ReadHeaderTimeout: 10 * time.Second},
+	}, nil
+}
+
+type Server struct {
+	l net.Listener
+	s *http.Server
+}
+
+func (s *Server) Run(ctx context.Context) {
+	slog.Info("Starting Metrics Server", "addr", s.l.Addr().String())
+	go func() {
+		if err := s.s.Serve(s.l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Metrics Server failed", "error", err)
+		}
+	}()
+	<-ctx.Done()
+	slog.Info("Stopping Metrics Server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+	defer cancel()
+	s.s.Shutdown(shutdownCtx)
+	slog.Info("Metrics Server is stopped")
+}
@@ -0,0 +1,78 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/JustDean/sam/pkg/utils"
+)
+
+// LogNotifier logs that messages were sent instead of delivering them. It
+// is meant for local development. Bodies carry tokens, so only their size
+// is logged; the file backend keeps them.
+type LogNotifier struct{}
+
+func NewLogNotifier() *LogNotifier {
+	return &LogNotifier{}
+}
+
+func (n *LogNotifier) Notify(ctx context.Context, m Message) error {
+	slog.InfoContext(ctx, "Notification", "username", m.Username, "email", m.Email, "subject", m.Subject, "body_size", len(m.Body))
+	return nil
+}
+
+// FileNotifier appends messages to a file, one JSON object per line.
+type FileNotifier struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileNotifier checks the file can be opened for writing.
+func NewFileNotifier(path string) (*FileNotifier, error) {
+	if path == "" {
+		return nil, fmt.Errorf("file notifier needs a path")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return nil, err
+	}
+	f.Close()
+	return &FileNotifier{path: path}, nil
+}
+
+type fileRecord struct {
+	Time     time.Time `json:"time"`
+	Username string    `json:"username"`
+	Email    string    `json:"email,omitempty"`
+	Subject  string    `json:"subject"`
+	Body     string    `json:"body"`
+}
+
+func (n *FileNotifier) Notify(ctx context.Context, m Message) error {
+	line, err := json.Marshal(fileRecord{
+		Time:     utils.GetNowTz(),
+		Username: m.Username,
+		Email:    m.Email,
+		Subject:  m.Subject,
+		Body:     m.Body,
+	})
+	if err != nil {
+		return err
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
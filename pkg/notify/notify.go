@@ -0,0 +1,63 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+)
+
+const (
+	BACKEND_NONE = "none" // send no messages
+	BACKEND_LOG  = "log"  // log messages without their bodies, for local development
+	BACKEND_FILE = "file" // append messages to a file as JSON lines
+	BACKEND_SMTP = "smtp" // send messages as emails
+)
+
+// Message is addressed to a user; backends work out how to reach them.
+type Message struct {
+	Username string
+	// Email is the address of the user if known, preferred by backends
+	// sending emails.
+	Email   string
+	Subject string
+	Body    string
+}
+
+// Notifier delivers messages to users.
+type Notifier interface {
+	Notify(ctx context.Context, m Message) error
+}
+
+type Config struct {
+	Backend string
+	File    string // path of the file used by the file backend
+	Smtp    SmtpConfig
+}
+
+// ErrNoNotifier is returned by NoNotifier.
+var ErrNoNotifier = errors.New("no notifier is configured")
+
+// NoNotifier refuses every message, for deployments sending none.
+type NoNotifier struct{}
+
+func (n NoNotifier) Notify(ctx context.Context, m Message) error {
+	return ErrNoNotifier
+}
+
+// SetNotifier returns the notifier of the backend. The log backend must be
+// chosen explicitly; by default no messages are sent.
+func SetNotifier(c Config) (Notifier, error) {
+	switch c.Backend {
+	case BACKEND_NONE, "":
+		return NoNotifier{}, nil
+	case BACKEND_LOG:
+		slog.Warn("The log notifier is meant for local development, messages are not delivered")
+		return NewLogNotifier(), nil
+	case BACKEND_FILE:
+		return NewFileNotifier(c.File)
+	case BACKEND_SMTP:
+		return NewSmtpNotifier(c.Smtp)
+	}
+	return nil, fmt.Errorf("unknown notifier backend %q", c.Backend)
+}
@@ -0,0 +1,165 @@
+// Package notifytest provides a fake SMTP server to test notifiers against.
+package notifytest
+
+import (
+	"bufio"
+	"encoding/base64"
+	"io"
+	"net"
+	"net/textproto"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// SmtpMessage is a message accepted by SmtpServer.
+type SmtpMessage struct {
+	// Username is the one the client authenticated with, if it did.
+	Username string
+	From     string
+	To       []string
+	// Data is the message as sent, headers included, with LF line endings.
+	Data string
+}
+
+// SmtpServer accepts every message sent to it, without TLS. It offers
+// PLAIN authentication and accepts any credentials.
+type SmtpServer struct {
+	listener net.Listener
+	wg       sync.WaitGroup
+	mu       sync.Mutex
+	messages []SmtpMessage
+}
+
+// NewSmtpServer listens on a random local port until the test ends.
+func NewSmtpServer(t testing.TB) *SmtpServer {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening: %v", err)
+	}
+	s := &SmtpServer{listener: l}
+	s.wg.Add(1)
+	go s.serve()
+	t.Cleanup(func() {
+		l.Close()
+		s.wg.Wait()
+	})
+	return s
+}
+
+// Host and Port are where the server listens.
+func (s *SmtpServer) Host() string {
+	host, _, _ := net.SplitHostPort(s.listener.Addr().String())
+	return host
+}
+
+func (s *SmtpServer) Port() string {
+	_, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	return port
+}
+
+// Messages returns the messages accepted so far.
+func (s *SmtpServer) Messages() []SmtpMessage {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]SmtpMessage(nil), s.messages...)
+}
+
+func (s *SmtpServer) serve() {
+	defer s.wg.Done()
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			defer conn.Close()
+			s.handle(textproto.NewConn(conn))
+		}()
+	}
+}
+
+func (s *SmtpServer) handle(c *textproto.Conn) {
+	var m SmtpMessage
+	reply := func(line string) bool {
+		return c.PrintfLine("%s", line) == nil
+	}
+	if !reply("220 localhost ESMTP fake") {
+		return
+	}
+	for {
+		line, err := c.ReadLine()
+		if err != nil {
+			return
+		}
+		verb, arg, _ := strings.Cut(line, " ")
+		switch strings.ToUpper(verb) {
+		case "EHLO", "HELO":
+			reply("250-localhost")
+			reply("250 AUTH PLAIN")
+		case "AUTH":
+			mechanism, initial, _ := strings.Cut(arg, " ")
+			creds, err := base64.StdEncoding.DecodeString(initial)
+			parts := strings.Split(string(creds), "\x00")
+			if mechanism != "PLAIN" || err != nil || len(parts) != 3 {
+				reply("535 authentication failed")
+				continue
+			}
+			m.Username = parts[1]
+			reply("235 authenticated")
+		case "MAIL":
+			m.From = address(arg)
+			reply("250 ok")
+		case "RCPT":
+			m.To = append(m.To, address(arg))
+			reply("250 ok")
+		case "DATA":
+			reply("354 go ahead")
+			data, err := io.ReadAll(c.DotReader())
+			if err != nil {
+				return
+			}
+			m.Data = string(data)
+			s.mu.Lock()
+			s.messages = append(s.messages, m)
+			s.mu.Unlock()
+			m = SmtpMessage{Username: m.Username}
+			reply("250 accepted")
+		case "RSET", "NOOP":
+			reply("250 ok")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+// address returns the address of a MAIL or RCPT argument such as
+// "FROM:<sam@example.com>".
+func address(arg string) string {
+	_, addr, _ := strings.Cut(arg, ":")
+	addr, _, _ = strings.Cut(addr, " ")
+	return strings.Trim(addr, "<>")
+}
+
+// Body returns the body of the message.
+func (m SmtpMessage) Body() string {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(m.Data)))
+	if _, err := r.ReadMIMEHeader(); err != nil {
+		return ""
+	}
+	body, _ := io.ReadAll(r.R)
+	return string(body)
+}
+
+// Header returns the value of a header of the message.
+func (m SmtpMessage) Header(key string) string {
+	r := textproto.NewReader(bufio.NewReader(strings.NewReader(m.Data)))
+	h, _ := r.ReadMIMEHeader()
+	return h.Get(key)
+}
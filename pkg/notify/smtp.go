@@ -0,0 +1,126 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+const SMTP_TIMEOUT = 10 * time.Second
+
+type SmtpConfig struct {
+	Host string
+	Port string
+	// Username and Password enable PLAIN authentication, which net/smtp
+	// only performs over TLS or to localhost.
+	Username string
+	Password string
+	From     string
+	// Domain completes usernames that are not email addresses.
+	Domain string
+}
+
+// SmtpNotifier sends messages as plain text emails, upgrading the
+// connection with STARTTLS when the server offers it.
+type SmtpNotifier struct {
+	config SmtpConfig
+}
+
+func NewSmtpNotifier(c SmtpConfig) (*SmtpNotifier, error) {
+	if c.Host == "" || c.Port == "" || c.From == "" {
+		return nil, fmt.Errorf("smtp notifier needs a host, a port and a sender")
+	}
+	return &SmtpNotifier{config: c}, nil
+}
+
+// address returns the email address of the recipient of the message.
+func (n *SmtpNotifier) address(m Message) (string, error) {
+	if m.Email != "" {
+		if strings.ContainsAny(m.Email, "\r\n<>") {
+			return "", fmt.Errorf("invalid email address for user %q", m.Username)
+		}
+		return m.Email, nil
+	}
+	username := m.Username
+	if strings.ContainsAny(username, "\r\n<>") {
+		return "", fmt.Errorf("invalid email address for user %q", username)
+	}
+	if strings.Contains(username, "@") {
+		return username, nil
+	}
+	if n.config.Domain == "" {
+		return "", fmt.Errorf("no email address for user %q", username)
+	}
+	return username + "@" + n.config.Domain, nil
+}
+
+func (n *SmtpNotifier) compose(to string, m Message) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", n.config.From)
+	fmt.Fprintf(&b, "To: %s\r\n", to)
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", m.Subject))
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	b.WriteString("\r\n")
+	return b.Bytes()
+}
+
+func (n *SmtpNotifier) Notify(ctx context.Context, m Message) error {
+	to, err := n.address(m)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, SMTP_TIMEOUT)
+	defer cancel()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", net.JoinHostPort(n.config.Host, n.config.Port))
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+	c, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if ok, _ := c.Extension("STARTTLS"); ok {
+		if err := c.StartTLS(&tls.Config{ServerName: n.config.Host}); err != nil {
+			return err
+		}
+	}
+	if n.config.Username != "" {
+		auth := smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+		if err := c.Auth(auth); err != nil {
+			return err
+		}
+	}
+	if err := c.Mail(n.config.From); err != nil {
+		return err
+	}
+	if err := c.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(n.compose(to, m)); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
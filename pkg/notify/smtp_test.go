@@ -0,0 +1,114 @@
+package notify
+
+import (
+	"context"
+	"mime"
+	"reflect"
+	"testing"
+
+	"github.com/JustDean/sam/pkg/notify/notifytest"
+)
+
+func TestSmtpNotifier(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   SmtpConfig
+		message  Message
+		wantTo   string
+		wantUser string
+		wantErr  bool
+	}{
+		{
+			name:    "email address",
+			message: Message{Username: "alice", Email: "alice@example.com", Subject: "Password reset", Body: "token\n"},
+			wantTo:  "alice@example.com",
+		},
+		{
+			name:    "username completed with the domain",
+			config:  SmtpConfig{Domain: "example.org"},
+			message: Message{Username: "bob", Subject: "Password reset", Body: "token\n"},
+			wantTo:  "bob@example.org",
+		},
+		{
+			name:    "username without domain",
+			message: Message{Username: "bob", Subject: "Password reset", Body: "token\n"},
+			wantErr: true,
+		},
+		{
+			name:    "header injection",
+			message: Message{Username: "bob", Email: "bob@example.com\r\nBcc: eve@example.com", Subject: "Password reset"},
+			wantErr: true,
+		},
+		{
+			name:     "authentication",
+			config:   SmtpConfig{Username: "sam", Password: "secret"},
+			message:  Message{Username: "alice", Email: "alice@example.com", Subject: "Réinitialisation", Body: "token\n"},
+			wantTo:   "alice@example.com",
+			wantUser: "sam",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := notifytest.NewSmtpServer(t)
+			c := tt.config
+			c.Host, c.Port, c.From = server.Host(), server.Port(), "sam@example.com"
+			n, err := NewSmtpNotifier(c)
+			if err != nil {
+				t.Fatalf("NewSmtpNotifier: %v", err)
+			}
+			err = n.Notify(context.Background(), tt.message)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Notify: got %v, want error %v", err, tt.wantErr)
+			}
+			messages := server.Messages()
+			if tt.wantErr {
+				if len(messages) != 0 {
+					t.Fatalf("got %d messages, want none", len(messages))
+				}
+				return
+			}
+			if len(messages) != 1 {
+				t.Fatalf("got %d messages, want 1", len(messages))
+			}
+			m := messages[0]
+			if m.From != "sam@example.com" || len(m.To) != 1 || m.To[0] != tt.wantTo {
+				t.Fatalf("got envelope from %s to %v, want to %s", m.From, m.To, tt.wantTo)
+			}
+			if m.Username != tt.wantUser {
+				t.Fatalf("authenticated as %q, want %q", m.Username, tt.wantUser)
+			}
+			subject, err := new(mime.WordDecoder).DecodeHeader(m.Header("Subject"))
+			if err != nil || subject != tt.message.Subject {
+				t.Fatalf("got subject %q, want %q", subject, tt.message.Subject)
+			}
+			if m.Body() != tt.message.Body+"\n" {
+				t.Fatalf("got body %q, want %q", m.Body(), tt.message.Body+"\n")
+			}
+		})
+	}
+}
+
+func TestSetNotifier(t *testing.T) {
+	tests := []struct {
+		backend string
+		want    Notifier
+		wantErr bool
+	}{
+		{"", NoNotifier{}, false},
+		{BACKEND_NONE, NoNotifier{}, false},
+		{BACKEND_LOG, NewLogNotifier(), false},
+		{BACKEND_SMTP, nil, true}, // no host nor sender
+		{"pigeon", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.backend, func(t *testing.T) {
+			n, err := SetNotifier(Config{Backend: tt.backend})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("SetNotifier: got %v, want error %v", err, tt.wantErr)
+			}
+			if err == nil && reflect.TypeOf(n) != reflect.TypeOf(tt.want) {
+				t.Fatalf("SetNotifier: got %T, want %T", n, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,12 @@
 package utils
 
-import "os"
+import (
+	"log/slog"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
 
 func GetEnv(name, fallback string) string {
 	value := os.Getenv(name)
@@ -9,3 +15,45 @@ func GetEnv(name, fallback string) string {
 	}
 	return value
 }
+
+// GetEnvDuration parses a duration such as "10m" or "240h", returning
+// the fallback if the variable is unset or malformed.
+func GetEnvDuration(name string, fallback time.Duration) time.Duration {
+	value := os.Getenv(name)
+	if len(value) == 0 {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		slog.Warn("Invalid duration, using fallback", "name", name, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return d
+}
+
+// GetEnvInt parses an integer, returning the fallback if the variable
+// is unset or malformed.
+func GetEnvInt(name string, fallback int) int {
+	value := os.Getenv(name)
+	if len(value) == 0 {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		slog.Warn("Invalid integer, using fallback", "name", name, "value", value, "fallback", fallback)
+		return fallback
+	}
+	return n
+}
+
+// GetEnvList splits a comma separated list, dropping empty items. It returns
+// nil if the variable is unset.
+func GetEnvList(name string) []string {
+	var items []string
+	for _, item := range strings.Split(os.Getenv(name), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
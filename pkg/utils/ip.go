@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"net"
+	"strings"
+)
+
+// ForwardedIp returns the client address reported by a proxy: the first
+// hop of x-forwarded-for, or else x-real-ip. Only headers set by a trusted
+// proxy should be passed.
+func ForwardedIp(forwardedFor, realIp string) string {
+	if forwardedFor != "" {
+		return strings.TrimSpace(strings.Split(forwardedFor, ",")[0])
+	}
+	return strings.TrimSpace(realIp)
+}
+
+// HostIp strips the port of a peer address, if any.
+func HostIp(addr string) string {
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		return host
+	}
+	return addr
+}